@@ -60,13 +60,13 @@ func Main() cliapp.LifecycleAction {
 			return nil, fmt.Errorf("failed to initialize storage: %w", err)
 		}
 
-		beaconClient, err := beacon.NewBeaconClient(context.Background(), cfg.BeaconConfig)
+		beaconClient, err := beacon.NewBeaconClient(context.Background(), cfg.BeaconConfig, l, beacon.NewMetrics(m.Registry(), metrics.MetricsNamespace))
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize beacon client: %w", err)
 		}
 
 		l.Info("Initializing API Service")
-		api := service.NewAPI(storageClient, beaconClient, m, l)
+		api := service.NewAPI(storageClient, beaconClient, m, l, cfg)
 		return service.NewService(l, api, cfg, m.Registry()), nil
 	}
 }
@@ -2,6 +2,8 @@ package flags
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	common "github.com/base-org/blob-archiver/common/flags"
 	oplog "github.com/ethereum-optimism/optimism/op-service/log"
@@ -14,8 +16,25 @@ type APIConfig struct {
 	MetricsConfig opmetrics.CLIConfig
 	BeaconConfig  common.BeaconConfig
 	StorageConfig common.StorageConfig
+	TLSConfig     common.TLSConfig
 
-	ListenAddr string
+	ListenAddr     string
+	RefuseOrphaned bool
+	CacheTTL       time.Duration
+
+	BeaconFallback      bool
+	BeaconFallbackWrite bool
+
+	PeerURL    string
+	PeerIngest bool
+
+	KeysFile              string
+	RequireKey            bool
+	KeyRequestsPerSecond  float64
+	IPRequestsPerSecond   float64
+	RateLimitBurst        int
+	MaxConcurrentRequests int
+	TrustProxyHeaders     bool
 }
 
 func (c APIConfig) Check() error {
@@ -27,19 +46,79 @@ func (c APIConfig) Check() error {
 		return fmt.Errorf("beacon config check failed: %w", err)
 	}
 
+	if err := c.TLSConfig.Check(); err != nil {
+		return fmt.Errorf("tls config check failed: %w", err)
+	}
+
 	if c.ListenAddr == "" {
 		return fmt.Errorf("listen address must be set")
 	}
 
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("cache TTL must not be negative")
+	}
+
+	if c.BeaconFallbackWrite && !c.BeaconFallback {
+		return fmt.Errorf("beacon fallback write requires the beacon fallback to be enabled")
+	}
+
+	if c.PeerIngest && c.PeerURL == "" {
+		return fmt.Errorf("peer ingest requires a peer url")
+	}
+
+	if c.RequireKey && c.KeysFile == "" {
+		return fmt.Errorf("requiring an API key requires a keys file")
+	}
+
+	if c.KeyRequestsPerSecond < 0 || c.IPRequestsPerSecond < 0 {
+		return fmt.Errorf("requests per second must not be negative")
+	}
+
+	if c.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("max concurrent requests must not be negative")
+	}
+
 	return nil
 }
 
 func ReadConfig(cliCtx *cli.Context) APIConfig {
+	cacheTTL, _ := time.ParseDuration(cliCtx.String(CacheTTLFlag.Name))
 	return APIConfig{
 		LogConfig:     oplog.ReadCLIConfig(cliCtx),
 		MetricsConfig: opmetrics.ReadCLIConfig(cliCtx),
 		BeaconConfig:  common.NewBeaconConfig(cliCtx),
 		StorageConfig: common.NewStorageConfig(cliCtx),
+		TLSConfig:     common.NewTLSConfig(cliCtx),
 		ListenAddr:    cliCtx.String(ListenAddressFlag.Name),
+
+		RefuseOrphaned: cliCtx.Bool(RefuseOrphanedFlag.Name),
+		CacheTTL:       cacheTTL,
+
+		BeaconFallback:      cliCtx.Bool(BeaconFallbackFlag.Name),
+		BeaconFallbackWrite: cliCtx.Bool(BeaconFallbackWriteFlag.Name),
+
+		PeerURL:    cliCtx.String(PeerURLFlag.Name),
+		PeerIngest: cliCtx.Bool(PeerIngestFlag.Name),
+
+		KeysFile:              cliCtx.String(KeysFileFlag.Name),
+		RequireKey:            cliCtx.Bool(RequireKeyFlag.Name),
+		KeyRequestsPerSecond:  cliCtx.Float64(KeyRequestsPerSecondFlag.Name),
+		IPRequestsPerSecond:   cliCtx.Float64(IPRequestsPerSecondFlag.Name),
+		RateLimitBurst:        cliCtx.Int(RateLimitBurstFlag.Name),
+		MaxConcurrentRequests: cliCtx.Int(MaxConcurrentRequestsFlag.Name),
+		TrustProxyHeaders:     cliCtx.Bool(TrustProxyHeadersFlag.Name),
+	}
+}
+
+// PeerURLs returns the URLs of the configured peer blob APIs, in order of preference.
+func (c APIConfig) PeerURLs() []string {
+	if c.PeerURL == "" {
+		return nil
+	}
+
+	var urls []string
+	for _, url := range strings.Split(c.PeerURL, ",") {
+		urls = append(urls, strings.TrimSpace(url))
 	}
+	return urls
 }
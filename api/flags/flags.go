@@ -17,13 +17,91 @@ var (
 		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "LISTEN_ADDRESS"),
 		Value:   "0.0.0.0:8000",
 	}
+	RefuseOrphanedFlag = &cli.BoolFlag{
+		Name:    "api-refuse-orphaned",
+		Usage:   "Whether to respond with 404 for blocks that the archiver marked as orphaned, instead of serving their blobs",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "REFUSE_ORPHANED"),
+		Value:   false,
+	}
+	CacheTTLFlag = &cli.StringFlag{
+		Name:    "api-cache-ttl",
+		Usage:   "How long clients and CDNs may cache responses that can still change, e.g. for slot or head requests. Finalized blocks requested by their root are cached indefinitely",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "CACHE_TTL"),
+		Value:   "12s",
+	}
+	BeaconFallbackFlag = &cli.BoolFlag{
+		Name:    "api-beacon-fallback",
+		Usage:   "Whether to fetch the blobs of blocks that are not in storage from the beacon node, e.g. recent blocks the archiver has not written yet",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BEACON_FALLBACK"),
+		Value:   false,
+	}
+	BeaconFallbackWriteFlag = &cli.BoolFlag{
+		Name:    "api-beacon-fallback-write",
+		Usage:   "Whether to write the blobs fetched from the beacon node back to storage. Requires api-beacon-fallback",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BEACON_FALLBACK_WRITE"),
+		Value:   false,
+	}
+	PeerURLFlag = &cli.StringFlag{
+		Name:    "api-peer-url",
+		Usage:   "Comma-separated URLs of other blob APIs that are asked, in order, for blocks that are not in storage. Their responses are verified before they are served",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "PEER_URL"),
+	}
+	PeerIngestFlag = &cli.BoolFlag{
+		Name:    "api-peer-ingest",
+		Usage:   "Whether to queue the blocks served by a peer for the archiver to store. Requires api-peer-url",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "PEER_INGEST"),
+		Value:   false,
+	}
+	KeysFileFlag = &cli.StringFlag{
+		Name:    "api-keys-file",
+		Usage:   "Path to a file of API keys, one '<name> <key> [requests-per-second]' per line. The file is reloaded when it changes",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "KEYS_FILE"),
+	}
+	RequireKeyFlag = &cli.BoolFlag{
+		Name:    "api-require-key",
+		Usage:   "Whether to reject requests without an API key. Requires api-keys-file",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "REQUIRE_KEY"),
+		Value:   false,
+	}
+	KeyRequestsPerSecondFlag = &cli.Float64Flag{
+		Name:    "api-key-requests-per-second",
+		Usage:   "The maximum number of requests per second of an API key, unless set for the key in the keys file, 0 for no limit",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "KEY_REQUESTS_PER_SECOND"),
+		Value:   0,
+	}
+	IPRequestsPerSecondFlag = &cli.Float64Flag{
+		Name:    "api-ip-requests-per-second",
+		Usage:   "The maximum number of requests per second of a client IP address making requests without an API key, 0 for no limit",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "IP_REQUESTS_PER_SECOND"),
+		Value:   0,
+	}
+	RateLimitBurstFlag = &cli.IntFlag{
+		Name:    "api-rate-limit-burst",
+		Usage:   "The number of requests an API key or IP address can make at once before it is rate limited",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "RATE_LIMIT_BURST"),
+		Value:   10,
+	}
+	MaxConcurrentRequestsFlag = &cli.IntFlag{
+		Name:    "api-max-concurrent-requests",
+		Usage:   "The maximum number of requests processed at once, further requests are rejected with 503. 0 for no limit",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "MAX_CONCURRENT_REQUESTS"),
+		Value:   0,
+	}
+	TrustProxyHeadersFlag = &cli.BoolFlag{
+		Name:    "api-trust-proxy-headers",
+		Usage:   "Whether to take the client IP address from the X-Forwarded-For or X-Real-IP headers, when the API is behind a proxy",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "TRUST_PROXY_HEADERS"),
+		Value:   false,
+	}
 )
 
 func init() {
 	Flags = append(Flags, common.CLIFlags(EnvVarPrefix)...)
+	Flags = append(Flags, common.TLSFlags(EnvVarPrefix)...)
 	Flags = append(Flags, opmetrics.CLIFlags(EnvVarPrefix)...)
 	Flags = append(Flags, oplog.CLIFlags(EnvVarPrefix)...)
-	Flags = append(Flags, ListenAddressFlag)
+	Flags = append(Flags, ListenAddressFlag, RefuseOrphanedFlag, CacheTTLFlag, BeaconFallbackFlag, BeaconFallbackWriteFlag, PeerURLFlag, PeerIngestFlag)
+	Flags = append(Flags, KeysFileFlag, RequireKeyFlag, KeyRequestsPerSecondFlag, IPRequestsPerSecondFlag, RateLimitBurstFlag, MaxConcurrentRequestsFlag, TrustProxyHeadersFlag)
 }
 
 // Flags contains the list of configuration options available to the binary.
@@ -8,24 +8,62 @@ import (
 
 type BlockIdType string
 
+type BeaconFallbackResult string
+
+type PeerFallbackResult string
+
+type RateLimitScope string
+
 var (
 	MetricsNamespace = "blob_api"
 
 	BlockIdTypeHash    BlockIdType = "hash"
 	BlockIdTypeBeacon  BlockIdType = "beacon"
 	BlockIdTypeInvalid BlockIdType = "invalid"
+
+	BeaconFallbackFound    BeaconFallbackResult = "found"
+	BeaconFallbackNotFound BeaconFallbackResult = "not_found"
+	BeaconFallbackError    BeaconFallbackResult = "error"
+
+	PeerFallbackFound    PeerFallbackResult = "found"
+	PeerFallbackNotFound PeerFallbackResult = "not_found"
+	PeerFallbackInvalid  PeerFallbackResult = "invalid"
+	PeerFallbackError    PeerFallbackResult = "error"
+
+	RateLimitScopeKey RateLimitScope = "key"
+	RateLimitScopeIP  RateLimitScope = "ip"
 )
 
 type Metricer interface {
 	Registry() *prometheus.Registry
 	RecordBlockIdType(t BlockIdType)
+	RecordBatchBlocks(count int)
+	RecordBeaconFallback(result BeaconFallbackResult)
+	RecordPeerFallback(peer string, result PeerFallbackResult)
+	RecordKeyRequest(name string)
+	RecordRateLimited(scope RateLimitScope)
+	RecordShedRequest()
 }
 
 type metricsRecorder struct {
 	// blockIdType records the type of block id used to request a block. This could be a hash (BlockIdTypeHash), or a
 	// beacon block identifier (BlockIdTypeBeacon).
 	blockIdType *prometheus.CounterVec
-	registry    *prometheus.Registry
+	// batchBlocks counts the blocks requested through the batch endpoint.
+	batchBlocks prometheus.Counter
+	// beaconFallback counts the blocks that were not in storage and were requested from the beacon node instead, by
+	// result.
+	beaconFallback *prometheus.CounterVec
+	// peerFallback counts the blocks that were not in storage and were requested from a peer blob API instead, by peer
+	// and result.
+	peerFallback *prometheus.CounterVec
+	// keyRequests counts the requests made with an API key, by the name of the key.
+	keyRequests *prometheus.CounterVec
+	// rateLimited counts the requests rejected by the per-key or per-IP rate limit.
+	rateLimited *prometheus.CounterVec
+	// shedRequests counts the requests rejected because the maximum number of concurrent requests was reached.
+	shedRequests prometheus.Counter
+	registry     *prometheus.Registry
 }
 
 func NewMetrics() Metricer {
@@ -38,6 +76,36 @@ func NewMetrics() Metricer {
 			Name:      "block_id_type",
 			Help:      "The type of block id used to request a block",
 		}, []string{"type"}),
+		batchBlocks: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "batch_blocks",
+			Help:      "The number of blocks requested through the batch endpoint",
+		}),
+		beaconFallback: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "beacon_fallback",
+			Help:      "The number of blocks that were not in storage and were requested from the beacon node instead",
+		}, []string{"result"}),
+		peerFallback: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "peer_fallback",
+			Help:      "The number of blocks that were not in storage and were requested from a peer blob API instead",
+		}, []string{"peer", "result"}),
+		keyRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "key_requests",
+			Help:      "The number of requests made with an API key",
+		}, []string{"key"}),
+		rateLimited: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "rate_limited",
+			Help:      "The number of requests rejected by the per-key or per-IP rate limit",
+		}, []string{"scope"}),
+		shedRequests: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "shed_requests",
+			Help:      "The number of requests rejected because the maximum number of concurrent requests was reached",
+		}),
 	}
 }
 
@@ -45,6 +113,30 @@ func (m *metricsRecorder) RecordBlockIdType(t BlockIdType) {
 	m.blockIdType.WithLabelValues(string(t)).Inc()
 }
 
+func (m *metricsRecorder) RecordBatchBlocks(count int) {
+	m.batchBlocks.Add(float64(count))
+}
+
+func (m *metricsRecorder) RecordBeaconFallback(result BeaconFallbackResult) {
+	m.beaconFallback.WithLabelValues(string(result)).Inc()
+}
+
+func (m *metricsRecorder) RecordPeerFallback(peer string, result PeerFallbackResult) {
+	m.peerFallback.WithLabelValues(peer, string(result)).Inc()
+}
+
+func (m *metricsRecorder) RecordKeyRequest(name string) {
+	m.keyRequests.WithLabelValues(name).Inc()
+}
+
+func (m *metricsRecorder) RecordRateLimited(scope RateLimitScope) {
+	m.rateLimited.WithLabelValues(string(scope)).Inc()
+}
+
+func (m *metricsRecorder) RecordShedRequest() {
+	m.shedRequests.Inc()
+}
+
 func (m *metricsRecorder) Registry() *prometheus.Registry {
 	return m.registry
 }
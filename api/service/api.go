@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,14 +11,17 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	client "github.com/attestantio/go-eth2-client"
 	"github.com/attestantio/go-eth2-client/api"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/api/flags"
 	m "github.com/base-org/blob-archiver/api/metrics"
 	"github.com/base-org/blob-archiver/api/version"
 	"github.com/base-org/blob-archiver/common/storage"
+	validator "github.com/base-org/blob-archiver/validator/service"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -43,6 +48,11 @@ const (
 	jsonAcceptType = "application/json"
 	sszAcceptType  = "application/octet-stream"
 	serverTimeout  = 60 * time.Second
+
+	// immutableCacheControl is sent for finalized blocks requested by their root, whose blobs can no longer change.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// finalizedRefreshInterval limits how often the finalized slot is fetched from the beacon node.
+	finalizedRefreshInterval = 12 * time.Second
 )
 
 var (
@@ -50,6 +60,10 @@ var (
 		Code:    http.StatusNotFound,
 		Message: "Block not found",
 	}
+	errOrphanedBlock = &httpError{
+		Code:    http.StatusNotFound,
+		Message: "Block is orphaned",
+	}
 	errServerError = &httpError{
 		Code:    http.StatusInternalServerError,
 		Message: "Internal server error",
@@ -83,35 +97,103 @@ type API struct {
 	router          *chi.Mux
 	logger          log.Logger
 	metrics         m.Metricer
+	cfg             flags.APIConfig
+
+	peers   []peer
+	keys    *keyStore
+	limiter *clientLimiter
+	// inFlight holds a slot for every request being processed, if the number of concurrent requests is limited.
+	inFlight chan struct{}
+
+	finalizedMu   sync.Mutex
+	finalizedSlot uint64
+	finalizedAt   time.Time
+
+	specMu sync.Mutex
+	spec   map[string]string
+	specAt time.Time
+
+	snapshotMu sync.Mutex
+	snapshot   *storage.BeaconSnapshot
+	snapshotAt time.Time
 }
 
-func NewAPI(dataStoreClient storage.DataStoreReader, beaconClient client.BeaconBlockHeadersProvider, metrics m.Metricer, logger log.Logger) *API {
+func NewAPI(dataStoreClient storage.DataStoreReader, beaconClient client.BeaconBlockHeadersProvider, metrics m.Metricer, logger log.Logger, cfg flags.APIConfig) *API {
 	result := &API{
 		dataStoreClient: dataStoreClient,
 		beaconClient:    beaconClient,
 		router:          chi.NewRouter(),
 		logger:          logger,
 		metrics:         metrics,
+		cfg:             cfg,
+		limiter:         newClientLimiter(),
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		result.inFlight = make(chan struct{}, cfg.MaxConcurrentRequests)
+	}
+	if cfg.KeysFile != "" {
+		result.keys = newKeyStore(cfg.KeysFile, logger)
+	}
+	for _, url := range cfg.PeerURLs() {
+		result.peers = append(result.peers, peer{url: url, client: validator.NewBlobSidecarClient(url)})
 	}
 
 	r := result.router
+	if cfg.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
+	r.Use(redactAPIKey)
 	r.Use(middleware.Logger)
-	r.Use(middleware.Timeout(serverTimeout))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Heartbeat("/healthz"))
-	r.Use(middleware.Compress(5, jsonAcceptType, sszAcceptType))
+	r.Use(middleware.Compress(5, jsonAcceptType, sszAcceptType, ndjsonAcceptType))
 
 	recorder := opmetrics.NewPromHTTPRecorder(metrics.Registry(), m.MetricsNamespace)
-	r.Use(func(handler http.Handler) http.Handler {
-		return opmetrics.NewHTTPRecordingMiddleware(recorder, handler)
+	r.Use(recordHTTP(recorder))
+	r.Use(result.limitClients)
+	r.Use(result.shedLoad)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(serverTimeout))
+		r.Get("/eth/v1/beacon/blob_sidecars/{id}", result.blobSidecarHandler)
+		r.Head("/eth/v1/beacon/blob_sidecars/{id}", result.blobSidecarHandler)
+		r.Get("/eth/v1/node/version", result.versionHandler)
+		r.Get("/eth/v1/beacon/genesis", result.genesisHandler)
+		r.Get("/eth/v1/config/spec", result.specHandler)
+		r.Get("/eth/v1/beacon/headers/{id}", result.headerHandler)
 	})
-
-	r.Get("/eth/v1/beacon/blob_sidecars/{id}", result.blobSidecarHandler)
-	r.Get("/eth/v1/node/version", result.versionHandler)
+	// Batch responses are streamed for as long as the client reads them, with a write deadline per block instead
+	r.Get("/blob_sidecars", result.batchHandler)
 
 	return result
 }
 
+// recordHTTP is a middleware that records the metrics of every request, like opmetrics.NewHTTPRecordingMiddleware. Its
+// response writer can be flushed and unwrapped, which streamed responses depend on.
+func recordHTTP(rec opmetrics.HTTPRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			params := &opmetrics.HTTPParams{
+				Method: r.Method,
+			}
+			rec.RecordInflightRequest(params, 1)
+			rec.RecordHTTPRequest(params)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+			params.StatusCode = ww.Status()
+			if params.StatusCode == 0 {
+				params.StatusCode = http.StatusOK
+			}
+			dur := time.Since(start)
+			rec.RecordHTTPResponse(params)
+			rec.RecordHTTPResponseSize(params, ww.BytesWritten())
+			rec.RecordHTTPRequestDuration(params, dur)
+			rec.RecordInflightRequest(params, -1)
+		})
+	}
+}
+
 func isHash(s string) bool {
 	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
 		return false
@@ -142,13 +224,13 @@ func (a *API) versionHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 // toBeaconBlockHash converts a string that can be a slot, hash or identifier to a beacon block hash.
-func (a *API) toBeaconBlockHash(id string) (common.Hash, *httpError) {
+func (a *API) toBeaconBlockHash(ctx context.Context, id string) (common.Hash, *httpError) {
 	if isHash(id) {
 		a.metrics.RecordBlockIdType(m.BlockIdTypeHash)
 		return common.HexToHash(id), nil
 	} else if isSlot(id) || isKnownIdentifier(id) {
 		a.metrics.RecordBlockIdType(m.BlockIdTypeBeacon)
-		result, err := a.beaconClient.BeaconBlockHeader(context.Background(), &api.BeaconBlockHeaderOpts{
+		result, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
 			Common: api.CommonOpts{},
 			Block:  id,
 		})
@@ -169,20 +251,122 @@ func (a *API) toBeaconBlockHash(id string) (common.Hash, *httpError) {
 	}
 }
 
+// blobSource is where the blobs of a block were read from.
+type blobSource int
+
+const (
+	// sourceStorage is the storage of the archive.
+	sourceStorage blobSource = iota
+	// sourceOrphaned is the orphan namespace of the storage of the archive.
+	sourceOrphaned
+	// sourceFallback is the beacon node or a peer, whose responses the archive cannot vouch for.
+	sourceFallback
+)
+
+// readBlob reads the blobs of a block from storage. Blocks that were orphaned are read from the orphan namespace, unless
+// the API is configured to refuse them. Blocks that are not in storage at all are read from the beacon node if the
+// beacon fallback is enabled, and then from the peers if withPeers is set. It also returns where the blobs were read
+// from.
+func (a *API) readBlob(ctx context.Context, beaconBlockHash common.Hash, withPeers bool) (storage.BlobData, blobSource, error) {
+	result, err := a.dataStoreClient.ReadBlob(ctx, beaconBlockHash)
+	if !errors.Is(err, storage.ErrNotFound) {
+		return result, sourceStorage, err
+	}
+
+	orphaned, orphanErr := a.dataStoreClient.ReadOrphanedBlob(ctx, beaconBlockHash)
+	if errors.Is(orphanErr, storage.ErrNotFound) {
+		if a.cfg.BeaconFallback {
+			result, err := a.readFromBeacon(ctx, beaconBlockHash)
+			if !errors.Is(err, storage.ErrNotFound) {
+				return result, sourceFallback, err
+			}
+		}
+		if !withPeers {
+			return storage.BlobData{}, sourceFallback, storage.ErrNotFound
+		}
+		result, err := a.readFromPeers(ctx, beaconBlockHash)
+		return result, sourceFallback, err
+	}
+	if orphanErr != nil {
+		return storage.BlobData{}, sourceOrphaned, orphanErr
+	}
+	if a.cfg.RefuseOrphaned {
+		return storage.BlobData{}, sourceOrphaned, errOrphanedBlock
+	}
+	return orphaned, sourceOrphaned, nil
+}
+
+// isFinalized returns whether the given slot is finalized. The finalized slot is fetched from the beacon node at most
+// once per finalizedRefreshInterval, so a slot may be reported as unfinalized for a short time after it was finalized.
+// If the beacon node is unavailable, the finalized slot last seen by the archiver is used instead.
+func (a *API) isFinalized(ctx context.Context, slot uint64) bool {
+	a.finalizedMu.Lock()
+	defer a.finalizedMu.Unlock()
+
+	if slot > a.finalizedSlot && time.Since(a.finalizedAt) >= finalizedRefreshInterval {
+		a.finalizedAt = time.Now()
+		header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: "finalized"})
+		if err == nil && header.Data != nil && header.Data.Header != nil && header.Data.Header.Message != nil {
+			a.finalizedSlot = max(a.finalizedSlot, uint64(header.Data.Header.Message.Slot))
+		} else if state, stateErr := a.dataStoreClient.ReadChainState(ctx); stateErr == nil {
+			a.logger.Warn("unable to fetch finalized header, using the stored finalized slot", "err", err)
+			a.finalizedSlot = max(a.finalizedSlot, state.FinalizedSlot)
+		} else {
+			a.logger.Warn("unable to fetch finalized header", "err", err, "stateErr", stateErr)
+		}
+	}
+
+	return slot <= a.finalizedSlot
+}
+
+// cacheControl returns the Cache-Control header of a blob sidecars response. Only the blobs of a finalized, canonical
+// block requested by its root and read from the storage of the archive can never change. A slot or named identifier
+// may resolve to another block later, the blobs of an unfinalized block may still be rearchived or orphaned, and blobs
+// served from the beacon node or a peer are not archived yet, so these are only cached for the configured TTL.
+func (a *API) cacheControl(id string, finalized, stored bool) string {
+	if isHash(id) && finalized && stored {
+		return immutableCacheControl
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int(a.cfg.CacheTTL.Seconds()))
+}
+
+// newETag returns a weak entity tag for a response body. The tag is weak because it is derived from the uncompressed
+// body, while the response may be sent compressed, which a strong tag would have to distinguish.
+func newETag(body []byte) string {
+	hash := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(hash[:]) + `"`
+}
+
+// etagMatches returns whether an If-None-Match header matches the given entity tag, using the weak comparison required
+// for If-None-Match.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // blobSidecarHandler implements the /eth/v1/beacon/blob_sidecars/{id} endpoint, using the underlying DataStoreReader
 // to fetch blobs instead of the beacon node. This allows clients to fetch expired blobs.
 func (a *API) blobSidecarHandler(w http.ResponseWriter, r *http.Request) {
 	param := chi.URLParam(r, "id")
-	beaconBlockHash, err := a.toBeaconBlockHash(param)
+	beaconBlockHash, err := a.toBeaconBlockHash(r.Context(), param)
 	if err != nil {
 		err.write(w)
 		return
 	}
 
-	result, storageErr := a.dataStoreClient.ReadBlob(r.Context(), beaconBlockHash)
+	result, source, storageErr := a.readBlob(r.Context(), beaconBlockHash, true)
 	if storageErr != nil {
 		if errors.Is(storageErr, storage.ErrNotFound) {
 			errUnknownBlock.write(w)
+		} else if errors.Is(storageErr, errOrphanedBlock) {
+			errOrphanedBlock.write(w)
 		} else {
 			a.logger.Info("unexpected error fetching blobs", "err", storageErr, "beaconBlockHash", beaconBlockHash.String(), "param", param)
 			errServerError.write(w)
@@ -200,7 +384,12 @@ func (a *API) blobSidecarHandler(w http.ResponseWriter, r *http.Request) {
 
 	blobSidecars.Data = filteredBlobSidecars
 	responseType := r.Header.Get("Accept")
+	metadata := a.blockMetadata(r.Context(), param, beaconBlockHash, result, source == sourceOrphaned)
+	w.Header().Set(consensusVersionHeader, metadata.version.String())
+	w.Header().Set(executionOptimisticHeader, strconv.FormatBool(metadata.executionOptimistic))
+	w.Header().Set(finalizedHeader, strconv.FormatBool(metadata.finalized))
 
+	var body []byte
 	if responseType == sszAcceptType {
 		w.Header().Set("Content-Type", sszAcceptType)
 		res, err := blobSidecars.MarshalSSZ()
@@ -209,22 +398,45 @@ func (a *API) blobSidecarHandler(w http.ResponseWriter, r *http.Request) {
 			errServerError.write(w)
 			return
 		}
-
-		_, err = w.Write(res)
-
-		if err != nil {
-			a.logger.Error("unable to write ssz response", "err", err)
-			errServerError.write(w)
-			return
-		}
+		body = res
 	} else {
 		w.Header().Set("Content-Type", jsonAcceptType)
-		err := json.NewEncoder(w).Encode(blobSidecars)
+		data := blobSidecars.Data
+		if data == nil {
+			data = []*deneb.BlobSidecar{}
+		}
+		res, err := json.Marshal(blobSidecarsResponse{
+			Version:             metadata.version.String(),
+			ExecutionOptimistic: metadata.executionOptimistic,
+			Finalized:           metadata.finalized,
+			Data:                data,
+		})
 		if err != nil {
 			a.logger.Error("unable to encode blob sidecars to JSON", "err", err)
 			errServerError.write(w)
 			return
 		}
+		body = append(res, '\n')
+	}
+
+	etag := newETag(body)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", a.cacheControl(param, metadata.finalized, source == sourceStorage))
+	w.Header().Add("Vary", "Accept")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := w.Write(body); err != nil {
+		a.logger.Error("unable to write response", "err", err)
 	}
 }
 
@@ -8,12 +8,16 @@ import (
 	"io"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/attestantio/go-eth2-client/api"
 	v1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/api/flags"
 	"github.com/base-org/blob-archiver/api/metrics"
 	"github.com/base-org/blob-archiver/common/beacon/beacontest"
 	"github.com/base-org/blob-archiver/common/blobtest"
@@ -61,7 +65,7 @@ func setup(t *testing.T) (*API, *storage.FileStorage, *beacontest.StubBeaconClie
 	fs := storage.NewFileStorage(tempDir, logger)
 	beacon := beacontest.NewEmptyStubBeaconClient()
 	m := metrics.NewMetrics()
-	a := NewAPI(fs, beacon, m, logger)
+	a := NewAPI(fs, beacon, m, logger, flags.APIConfig{})
 	return a, fs, beacon, func() {
 		require.NoError(t, os.RemoveAll(tempDir))
 	}
@@ -100,6 +104,9 @@ func TestAPIService(t *testing.T) {
 
 	beaconClient.Headers["finalized"] = &v1.BeaconBlockHeader{
 		Root: phase0.Root(rootOne),
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{},
+		},
 	}
 
 	beaconClient.Headers["head"] = &v1.BeaconBlockHeader{
@@ -304,6 +311,244 @@ func TestAPIService(t *testing.T) {
 	}
 }
 
+func TestOrphanedBlocks(t *testing.T) {
+	a, fs, _, cleanup := setup(t)
+	defer cleanup()
+
+	root := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890333333")
+	block := storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: root,
+		},
+		BlobSidecars: storage.BlobSidecars{
+			Data: blobtest.NewBlobSidecars(t, 1),
+		},
+	}
+	require.NoError(t, fs.WriteBlob(context.Background(), block))
+	require.NoError(t, fs.OrphanBlob(context.Background(), root))
+
+	request := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/eth/v1/beacon/blob_sidecars/"+root.String(), nil)
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	// Orphaned blocks are served by default
+	response := request()
+	require.Equal(t, 200, response.Code)
+	var data storage.BlobSidecars
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&data))
+	require.Equal(t, block.BlobSidecars, data)
+
+	a.cfg = flags.APIConfig{RefuseOrphaned: true}
+	response = request()
+	require.Equal(t, 404, response.Code)
+	var errResponse httpError
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&errResponse))
+	require.Equal(t, "Block is orphaned", errResponse.Message)
+}
+
+func TestCaching(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+	a.cfg.CacheTTL = 12 * time.Second
+
+	finalizedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890444444")
+	unfinalizedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890555555")
+	for slot, root := range map[phase0.Slot]common.Hash{100: finalizedRoot, 101: unfinalizedRoot} {
+		sidecars := blobtest.NewBlobSidecars(t, 2)
+		for _, sidecar := range sidecars {
+			sidecar.SignedBlockHeader.Message.Slot = slot
+		}
+		require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{
+			Header:       storage.Header{BeaconBlockHash: root},
+			BlobSidecars: storage.BlobSidecars{Data: sidecars},
+		}))
+	}
+
+	finalized := &v1.BeaconBlockHeader{
+		Root: phase0.Root(finalizedRoot),
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{Slot: 100},
+		},
+	}
+	beaconClient.Headers["finalized"] = finalized
+	beaconClient.Headers["100"] = finalized
+	beaconClient.Headers["head"] = &v1.BeaconBlockHeader{Root: phase0.Root(unfinalizedRoot)}
+
+	request := func(method, id, accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/eth/v1/beacon/blob_sidecars/"+id, nil)
+		request.Header.Set("Accept", accept)
+		if ifNoneMatch != "" {
+			request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	tests := []struct {
+		name         string
+		id           string
+		cacheControl string
+	}{
+		{name: "finalized root", id: finalizedRoot.String(), cacheControl: "public, max-age=31536000, immutable"},
+		{name: "unfinalized root", id: unfinalizedRoot.String(), cacheControl: "public, max-age=12"},
+		{name: "finalized slot", id: "100", cacheControl: "public, max-age=12"},
+		{name: "head", id: "head", cacheControl: "public, max-age=12"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := request("GET", test.id, "application/json", "")
+			require.Equal(t, 200, response.Code)
+			require.Equal(t, test.cacheControl, response.Header().Get("Cache-Control"))
+			etag := response.Header().Get("ETag")
+			// The ETag is weak, as responses may be compressed
+			require.Regexp(t, `^W/"[0-9a-f]{64}"$`, etag)
+			body := response.Body.Bytes()
+
+			// The ETag is derived from the content, and differs for every representation
+			require.Equal(t, etag, request("GET", test.id, "application/json", "").Header().Get("ETag"))
+			require.NotEqual(t, etag, request("GET", test.id, "application/octet-stream", "").Header().Get("ETag"))
+
+			response = request("GET", test.id, "application/json", `"other", `+strings.TrimPrefix(etag, "W/"))
+			require.Equal(t, 304, response.Code)
+			require.Empty(t, response.Body.Bytes())
+			require.Equal(t, etag, response.Header().Get("ETag"))
+			require.Equal(t, test.cacheControl, response.Header().Get("Cache-Control"))
+
+			require.Equal(t, 304, request("GET", test.id, "application/json", etag).Code)
+
+			response = request("GET", test.id, "application/json", `"other"`)
+			require.Equal(t, 200, response.Code)
+			require.Equal(t, body, response.Body.Bytes())
+
+			response = request("HEAD", test.id, "application/json", "")
+			require.Equal(t, 200, response.Code)
+			require.Empty(t, response.Body.Bytes())
+			require.Equal(t, etag, response.Header().Get("ETag"))
+			require.Equal(t, strconv.Itoa(len(body)), response.Header().Get("Content-Length"))
+		})
+	}
+
+	// Orphaned blocks can still change, even once finalized
+	require.NoError(t, fs.OrphanBlob(context.Background(), finalizedRoot))
+	response := request("GET", finalizedRoot.String(), "application/json", "")
+	require.Equal(t, 200, response.Code)
+	require.Equal(t, "public, max-age=12", response.Header().Get("Cache-Control"))
+
+	// Errors have no ETag
+	response = request("HEAD", "0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abc111", "application/json", "")
+	require.Equal(t, 404, response.Code)
+	require.Empty(t, response.Header().Get("ETag"))
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+
+	finalizedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890666666")
+	unfinalizedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890777777")
+	capellaRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890888888")
+	for slot, root := range map[phase0.Slot]common.Hash{100: finalizedRoot, 101: unfinalizedRoot, 20: capellaRoot} {
+		sidecars := blobtest.NewBlobSidecars(t, 1)
+		sidecars[0].SignedBlockHeader.Message.Slot = slot
+		require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{
+			Header:       storage.Header{BeaconBlockHash: root},
+			BlobSidecars: storage.BlobSidecars{Data: sidecars},
+		}))
+	}
+
+	beaconClient.Headers["finalized"] = &v1.BeaconBlockHeader{
+		Root: phase0.Root(finalizedRoot),
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{Slot: 100},
+		},
+	}
+	beaconClient.Headers[unfinalizedRoot.String()] = &v1.BeaconBlockHeader{
+		Root: phase0.Root(unfinalizedRoot),
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{Slot: 101},
+		},
+	}
+	beaconClient.ExecutionOptimistic = true
+	beaconClient.Config = map[string]any{
+		"SLOTS_PER_EPOCH":    uint64(4),
+		"CAPELLA_FORK_EPOCH": uint64(2),
+		"DENEB_FORK_EPOCH":   uint64(10),
+	}
+
+	request := func(id, accept string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/eth/v1/beacon/blob_sidecars/"+id, nil)
+		request.Header.Set("Accept", accept)
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	tests := []struct {
+		name                string
+		id                  string
+		version             string
+		executionOptimistic bool
+		finalized           bool
+	}{
+		{name: "finalized", id: finalizedRoot.String(), version: "deneb", finalized: true},
+		{name: "optimistic", id: unfinalizedRoot.String(), version: "deneb", executionOptimistic: true},
+		{name: "fork from slot", id: capellaRoot.String(), version: "capella", finalized: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := request(test.id, "application/json")
+			require.Equal(t, 200, response.Code)
+			require.Equal(t, test.version, response.Header().Get("Eth-Consensus-Version"))
+
+			var envelope blobSidecarsResponse
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &envelope))
+			require.Equal(t, test.version, envelope.Version)
+			require.Equal(t, test.executionOptimistic, envelope.ExecutionOptimistic)
+			require.Equal(t, test.finalized, envelope.Finalized)
+			require.Len(t, envelope.Data, 1)
+
+			response = request(test.id, "application/octet-stream")
+			require.Equal(t, 200, response.Code)
+			require.Equal(t, test.version, response.Header().Get("Eth-Consensus-Version"))
+			require.Equal(t, strconv.FormatBool(test.executionOptimistic), response.Header().Get("Eth-Execution-Optimistic"))
+			require.Equal(t, strconv.FormatBool(test.finalized), response.Header().Get("Eth-Finalized"))
+		})
+	}
+}
+
+func TestResponseEnvelopeStoredFinality(t *testing.T) {
+	a, fs, _, cleanup := setup(t)
+	defer cleanup()
+
+	root := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890999999")
+	sidecars := blobtest.NewBlobSidecars(t, 1)
+	sidecars[0].SignedBlockHeader.Message.Slot = 100
+	require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: root},
+		BlobSidecars: storage.BlobSidecars{Data: sidecars},
+	}))
+	require.NoError(t, fs.WriteChainState(context.Background(), storage.ChainState{FinalizedSlot: 120}))
+
+	// Without a beacon node, finality is read from the chain state of the archiver and Deneb is assumed
+	request := httptest.NewRequest("GET", "/eth/v1/beacon/blob_sidecars/"+root.String(), nil)
+	response := httptest.NewRecorder()
+	a.router.ServeHTTP(response, request)
+	require.Equal(t, 200, response.Code)
+	require.Equal(t, "deneb", response.Header().Get("Eth-Consensus-Version"))
+
+	var envelope blobSidecarsResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &envelope))
+	require.True(t, envelope.Finalized)
+	require.False(t, envelope.ExecutionOptimistic)
+	require.Equal(t, "public, max-age=31536000, immutable", response.Header().Get("Cache-Control"))
+}
+
 func TestVersionHandler(t *testing.T) {
 	a, _, _, cleanup := setup(t)
 	defer cleanup()
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	ndjsonAcceptType = "application/x-ndjson"
+	// maxBatchBlocks is the maximum number of blocks that can be requested at once from the batch endpoint.
+	maxBatchBlocks = 1024
+	// batchConcurrency is the number of blocks of a batch that are resolved and read concurrently.
+	batchConcurrency = 16
+	// batchWriteTimeout is the time the client has to read a block of a batch response.
+	batchWriteTimeout = 30 * time.Second
+)
+
+// batchStatus is the status of a single block in a batch response.
+type batchStatus uint8
+
+const (
+	// batchStatusFound means the block was archived with at least one blob.
+	batchStatusFound batchStatus = iota
+	// batchStatusEmpty means the block was archived and has no blobs.
+	batchStatusEmpty
+	// batchStatusMissed means there is no block at the requested slot.
+	batchStatusMissed
+	// batchStatusNotArchived means the blobs of the block are not in storage.
+	batchStatusNotArchived
+	// batchStatusOrphaned means the block is orphaned, and the API is configured to refuse orphaned blocks.
+	batchStatusOrphaned
+	// batchStatusError means the block could not be resolved or read.
+	batchStatusError
+)
+
+var batchStatusNames = []string{"found", "empty", "missed", "not_archived", "orphaned", "error"}
+
+func (s batchStatus) String() string {
+	return batchStatusNames[s]
+}
+
+func (s batchStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// batchResult is the result of a single block in a batch response.
+type batchResult struct {
+	ID        string               `json:"id"`
+	Slot      string               `json:"slot,omitempty"`
+	BlockRoot string               `json:"block_root,omitempty"`
+	Status    batchStatus          `json:"status"`
+	Data      []*deneb.BlobSidecar `json:"data,omitempty"`
+
+	slot uint64
+	root common.Hash
+}
+
+// parseBatchIds returns the block ids of a batch request, either given as a from and to slot (inclusive), or as a list
+// of block ids in the same formats as the blob sidecars endpoint.
+func parseBatchIds(r *http.Request) ([]string, *httpError) {
+	query := r.URL.Query()
+	from, to, ids := query.Get("from"), query.Get("to"), query["ids"]
+
+	if len(ids) > 0 {
+		if from != "" || to != "" {
+			return nil, newBatchError("either a slot range or block ids must be given, not both")
+		}
+		if len(ids) == 1 {
+			ids = strings.Split(ids[0], ",")
+		}
+		if len(ids) > maxBatchBlocks {
+			return nil, newBatchError(fmt.Sprintf("at most %d blocks can be requested at once", maxBatchBlocks))
+		}
+		for _, id := range ids {
+			if !isHash(id) && !isSlot(id) && !isKnownIdentifier(id) {
+				return nil, newBlockIdError(id)
+			}
+		}
+		return ids, nil
+	}
+
+	fromSlot, err := strconv.ParseUint(from, 10, 64)
+	if err != nil {
+		return nil, newBatchError(fmt.Sprintf("invalid from slot: %s", from))
+	}
+	toSlot, err := strconv.ParseUint(to, 10, 64)
+	if err != nil {
+		return nil, newBatchError(fmt.Sprintf("invalid to slot: %s", to))
+	}
+	if toSlot < fromSlot {
+		return nil, newBatchError("to slot must not be before from slot")
+	}
+	if toSlot-fromSlot >= maxBatchBlocks {
+		return nil, newBatchError(fmt.Sprintf("at most %d blocks can be requested at once", maxBatchBlocks))
+	}
+
+	ids = make([]string, 0, toSlot-fromSlot+1)
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		ids = append(ids, strconv.FormatUint(slot, 10))
+	}
+	return ids, nil
+}
+
+func newBatchError(message string) *httpError {
+	return &httpError{
+		Code:    http.StatusBadRequest,
+		Message: message,
+	}
+}
+
+// resolveBatchBlock resolves a single block id of a batch request and reads its blobs from storage.
+func (a *API) resolveBatchBlock(ctx context.Context, id string) batchResult {
+	result := batchResult{ID: id}
+	if isSlot(id) {
+		result.slot, _ = strconv.ParseUint(id, 10, 64)
+		result.Slot = id
+	}
+
+	root, err := a.toBeaconBlockHash(ctx, id)
+	if err == errUnknownBlock {
+		result.Status = batchStatusMissed
+		return result
+	} else if err != nil {
+		result.Status = batchStatusError
+		return result
+	}
+	result.root = root
+	result.BlockRoot = root.String()
+
+	// Peers are not asked for batch misses, as a single request could otherwise fan out to every peer for every block
+	data, _, storageErr := a.readBlob(ctx, root, false)
+	if errors.Is(storageErr, storage.ErrNotFound) {
+		result.Status = batchStatusNotArchived
+		return result
+	} else if errors.Is(storageErr, errOrphanedBlock) {
+		result.Status = batchStatusOrphaned
+		return result
+	} else if storageErr != nil {
+		a.logger.Info("unexpected error fetching blobs", "err", storageErr, "beaconBlockHash", root.String(), "param", id)
+		result.Status = batchStatusError
+		return result
+	}
+
+	if len(data.BlobSidecars.Data) == 0 {
+		result.Status = batchStatusEmpty
+		return result
+	}
+
+	result.Status = batchStatusFound
+	result.Data = data.BlobSidecars.Data
+	if result.Slot == "" {
+		result.slot = uint64(data.BlobSidecars.Data[0].SignedBlockHeader.Message.Slot)
+		result.Slot = strconv.FormatUint(result.slot, 10)
+	}
+	return result
+}
+
+// batchHandler implements the /blob_sidecars endpoint, which returns the blobs of a slot range (?from=<slot>&to=<slot>)
+// or of a list of block ids (?ids=<id>,<id>) in a single response. Blocks are resolved concurrently, and written in the
+// requested order as soon as they are available, either as one JSON object per line or as SSZ frames, see
+// writeBatchFrame.
+func (a *API) batchHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseBatchIds(r)
+	if err != nil {
+		err.write(w)
+		return
+	}
+	a.metrics.RecordBatchBlocks(len(ids))
+
+	ctx := r.Context()
+	results := make([]chan batchResult, len(ids))
+	for i := range results {
+		results[i] = make(chan batchResult, 1)
+	}
+	go func() {
+		sem := make(chan struct{}, batchConcurrency)
+		// If the number of concurrent requests is limited, every block that is resolved takes a request slot, either
+		// the one of the batch request or a free one, so that a batch is load shed like the requests it replaces
+		own := make(chan struct{}, 1)
+		for i, id := range ids {
+			// Every block after the first is rate limited as a request of its own
+			if i > 0 {
+				if err := a.chargeRequest(ctx); err != nil {
+					return
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			var slot chan struct{}
+			if a.inFlight != nil {
+				select {
+				case own <- struct{}{}:
+					slot = own
+				case a.inFlight <- struct{}{}:
+					slot = a.inFlight
+				case <-ctx.Done():
+					<-sem
+					return
+				}
+			}
+			go func(i int, id string) {
+				defer func() {
+					if slot != nil {
+						<-slot
+					}
+					<-sem
+				}()
+				results[i] <- a.resolveBatchBlock(ctx, id)
+			}(i, id)
+		}
+	}()
+
+	ssz := r.Header.Get("Accept") == sszAcceptType
+	if ssz {
+		w.Header().Set("Content-Type", sszAcceptType)
+	} else {
+		w.Header().Set("Content-Type", ndjsonAcceptType)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// The write deadline of the server is extended for every block, as a batch can take longer than it to stream
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	for i := range ids {
+		var result batchResult
+		select {
+		case result = <-results[i]:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := rc.SetWriteDeadline(time.Now().Add(batchWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			a.logger.Error("unable to set batch write deadline", "err", err)
+			return
+		}
+		var writeErr error
+		if ssz {
+			writeErr = writeBatchFrame(w, result)
+		} else {
+			writeErr = encoder.Encode(result)
+		}
+		if writeErr != nil {
+			a.logger.Error("unable to write batch response", "err", writeErr)
+			return
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			a.logger.Error("unable to flush batch response", "err", err)
+			return
+		}
+	}
+}
+
+// writeBatchFrame writes the SSZ frame of a block in a batch response. A frame consists of the status (1 byte), the slot
+// (8 bytes, little-endian, 0 if unknown), the block root (32 bytes, zero if unknown), the length of the payload
+// (4 bytes, little-endian), and the payload: the SSZ encoded blob sidecars of a found block, empty otherwise.
+func writeBatchFrame(w http.ResponseWriter, result batchResult) error {
+	var payload []byte
+	if result.Status == batchStatusFound {
+		var err error
+		payload, err = (&storage.BlobSidecars{Data: result.Data}).MarshalSSZ()
+		if err != nil {
+			return err
+		}
+	}
+
+	frame := make([]byte, 0, 45+len(payload))
+	frame = append(frame, byte(result.Status))
+	frame = binary.LittleEndian.AppendUint64(frame, result.slot)
+	frame = append(frame, result.root[:]...)
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	_, err := w.Write(frame)
+	return err
+}
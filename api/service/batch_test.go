@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/api/flags"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+type batchLine struct {
+	ID        string               `json:"id"`
+	Slot      string               `json:"slot"`
+	BlockRoot string               `json:"block_root"`
+	Status    string               `json:"status"`
+	Sidecars  []*deneb.BlobSidecar `json:"data"`
+}
+
+func TestBatchHandler(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+
+	found := common.Hash{0xa}
+	empty := common.Hash{0xb}
+	notArchived := common.Hash{0xc}
+	foundSidecars := blobtest.NewBlobSidecars(t, 2)
+	for _, sidecar := range foundSidecars {
+		sidecar.SignedBlockHeader.Message.Slot = 100
+	}
+	require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: found},
+		BlobSidecars: storage.BlobSidecars{Data: foundSidecars},
+	}))
+	require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{
+		Header: storage.Header{BeaconBlockHash: empty},
+	}))
+
+	// Slot 101 is missed
+	beaconClient.Headers["100"] = &v1.BeaconBlockHeader{Root: phase0.Root(found)}
+	beaconClient.Headers["102"] = &v1.BeaconBlockHeader{Root: phase0.Root(empty)}
+	beaconClient.Headers["103"] = &v1.BeaconBlockHeader{Root: phase0.Root(notArchived)}
+	beaconClient.Headers["head"] = &v1.BeaconBlockHeader{Root: phase0.Root(empty)}
+
+	request := func(query string, accept string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/blob_sidecars?"+query, nil)
+		request.Header.Set("Accept", accept)
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	readLines := func(response *httptest.ResponseRecorder) []batchLine {
+		require.Equal(t, 200, response.Code)
+		require.Equal(t, "application/x-ndjson", response.Header().Get("Content-Type"))
+		var lines []batchLine
+		scanner := bufio.NewScanner(response.Body)
+		scanner.Buffer(nil, 1<<24)
+		for scanner.Scan() {
+			var line batchLine
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		require.NoError(t, scanner.Err())
+		return lines
+	}
+
+	t.Run("slot range", func(t *testing.T) {
+		lines := readLines(request("from=100&to=103", "application/x-ndjson"))
+		require.Len(t, lines, 4)
+
+		require.Equal(t, batchLine{ID: "100", Slot: "100", BlockRoot: found.String(), Status: "found"}, withoutSidecars(lines[0]))
+		require.Equal(t, foundSidecars, lines[0].Sidecars)
+		require.Equal(t, batchLine{ID: "101", Slot: "101", Status: "missed"}, lines[1])
+		require.Equal(t, batchLine{ID: "102", Slot: "102", BlockRoot: empty.String(), Status: "empty"}, lines[2])
+		require.Equal(t, batchLine{ID: "103", Slot: "103", BlockRoot: notArchived.String(), Status: "not_archived"}, lines[3])
+	})
+
+	t.Run("block ids", func(t *testing.T) {
+		lines := readLines(request(fmt.Sprintf("ids=%s,head,%s", found, notArchived), "application/json"))
+		require.Len(t, lines, 3)
+
+		// The slot of a block requested by its root is read from its blobs
+		require.Equal(t, batchLine{ID: found.String(), Slot: "100", BlockRoot: found.String(), Status: "found"}, withoutSidecars(lines[0]))
+		require.Equal(t, batchLine{ID: "head", BlockRoot: empty.String(), Status: "empty"}, lines[1])
+		require.Equal(t, batchLine{ID: notArchived.String(), BlockRoot: notArchived.String(), Status: "not_archived"}, lines[2])
+	})
+
+	t.Run("ssz", func(t *testing.T) {
+		response := request("from=100&to=101", "application/octet-stream")
+		require.Equal(t, 200, response.Code)
+		require.Equal(t, "application/octet-stream", response.Header().Get("Content-Type"))
+		body := response.Body.Bytes()
+
+		// Found block
+		require.Equal(t, byte(batchStatusFound), body[0])
+		require.Equal(t, uint64(100), binary.LittleEndian.Uint64(body[1:9]))
+		require.Equal(t, found[:], body[9:41])
+		length := binary.LittleEndian.Uint32(body[41:45])
+		var sidecars api.BlobSidecars
+		require.NoError(t, sidecars.UnmarshalSSZ(body[45:45+length]))
+		require.Equal(t, foundSidecars, sidecars.Sidecars)
+		body = body[45+length:]
+
+		// Missed slot
+		require.Equal(t, byte(batchStatusMissed), body[0])
+		require.Equal(t, uint64(101), binary.LittleEndian.Uint64(body[1:9]))
+		require.Equal(t, make([]byte, 32), body[9:41])
+		require.Equal(t, uint32(0), binary.LittleEndian.Uint32(body[41:45]))
+		require.Len(t, body, 45)
+	})
+
+	errorTests := []struct {
+		name       string
+		query      string
+		errMessage string
+	}{
+		{name: "no parameters", query: "", errMessage: "invalid from slot: "},
+		{name: "invalid to slot", query: "from=1&to=x", errMessage: "invalid to slot: x"},
+		{name: "reversed range", query: "from=2&to=1", errMessage: "to slot must not be before from slot"},
+		{name: "range too large", query: "from=0&to=1024", errMessage: "at most 1024 blocks can be requested at once"},
+		{name: "range and ids", query: "from=0&to=1&ids=1", errMessage: "either a slot range or block ids must be given, not both"},
+		{name: "invalid id", query: "ids=1,foobar", errMessage: "invalid block id: foobar"},
+	}
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			response := request(test.query, "application/json")
+			require.Equal(t, 400, response.Code)
+			var e httpError
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &e))
+			require.Equal(t, test.errMessage, e.Message)
+		})
+	}
+
+	// The largest allowed range succeeds
+	lines := readLines(request("from=0&to=1023", "application/x-ndjson"))
+	require.Len(t, lines, 1024)
+	require.Equal(t, "found", lines[100].Status)
+	require.Equal(t, "missed", lines[0].Status)
+	require.Equal(t, "1023", lines[1023].ID)
+}
+
+func withoutSidecars(line batchLine) batchLine {
+	line.Sidecars = nil
+	return line
+}
+
+func TestBatchOutlivesWriteTimeout(t *testing.T) {
+	// At 5 blocks per second, the batch takes longer than the write timeout of the server
+	a := newLimitedAPI(t, flags.APIConfig{IPRequestsPerSecond: 5, RateLimitBurst: 1})
+	server := httptest.NewUnstartedServer(a.router)
+	server.Config.WriteTimeout = 200 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	ids := make([]string, 4)
+	for i := range ids {
+		ids[i] = common.Hash{byte(i + 1)}.String()
+	}
+	response, err := http.Get(server.URL + "/blob_sidecars?ids=" + strings.Join(ids, ","))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, 200, response.StatusCode)
+
+	var lines []batchLine
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var line batchLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 4)
+	require.Equal(t, ids[3], lines[3].ID)
+}
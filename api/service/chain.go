@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/beacon"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// snapshotRefreshInterval limits how often the beacon snapshot is read from storage.
+	snapshotRefreshInterval = time.Minute
+	// specRefreshInterval limits how often the spec is requested from the beacon node until it was fetched once.
+	specRefreshInterval = time.Minute
+)
+
+var (
+	errUnknownGenesis = &httpError{
+		Code:    http.StatusNotFound,
+		Message: "Chain genesis info is not yet known",
+	}
+	errUnknownSpec = &httpError{
+		Code:    http.StatusNotFound,
+		Message: "Chain spec is not yet known",
+	}
+)
+
+// dataResponse is the response of the beacon API endpoints that only return data.
+type dataResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+// headerResponse is the response of the /eth/v1/beacon/headers/{id} endpoint.
+type headerResponse struct {
+	ExecutionOptimistic bool                  `json:"execution_optimistic"`
+	Finalized           bool                  `json:"finalized"`
+	Data                *v1.BeaconBlockHeader `json:"data"`
+}
+
+// beaconSnapshot returns the chain configuration captured by the archiver, or nil if none was captured yet. The
+// snapshot is read from storage at most once per snapshotRefreshInterval.
+func (a *API) beaconSnapshot(ctx context.Context) *storage.BeaconSnapshot {
+	a.snapshotMu.Lock()
+	defer a.snapshotMu.Unlock()
+
+	if time.Since(a.snapshotAt) >= snapshotRefreshInterval {
+		a.snapshotAt = time.Now()
+		snapshot, err := a.dataStoreClient.ReadBeaconSnapshot(ctx)
+		if err == nil {
+			a.snapshot = &snapshot
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			a.logger.Warn("unable to read beacon snapshot", "err", err)
+		}
+	}
+
+	return a.snapshot
+}
+
+// writeJSON writes a JSON response with the given status.
+func (a *API) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", jsonAcceptType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.logger.Error("unable to encode response to JSON", "err", err)
+	}
+}
+
+// genesisHandler implements the /eth/v1/beacon/genesis endpoint. The genesis is served from the snapshot captured by
+// the archiver, or from the beacon node until the archiver captured one.
+func (a *API) genesisHandler(w http.ResponseWriter, r *http.Request) {
+	if snapshot := a.beaconSnapshot(r.Context()); snapshot != nil && snapshot.Genesis != nil {
+		a.writeJSON(w, http.StatusOK, dataResponse[*v1.Genesis]{Data: snapshot.Genesis})
+		return
+	}
+
+	provider, ok := a.beaconClient.(client.GenesisProvider)
+	if !ok {
+		errUnknownGenesis.write(w)
+		return
+	}
+	res, err := provider.Genesis(r.Context(), &api.GenesisOpts{})
+	if err != nil || res.Data == nil {
+		a.logger.Warn("unable to fetch genesis", "err", err)
+		errUnknownGenesis.write(w)
+		return
+	}
+	a.writeJSON(w, http.StatusOK, dataResponse[*v1.Genesis]{Data: res.Data})
+}
+
+// specHandler implements the /eth/v1/config/spec endpoint, see chainSpec.
+func (a *API) specHandler(w http.ResponseWriter, r *http.Request) {
+	spec := a.chainSpec(r.Context())
+	if spec == nil {
+		errUnknownSpec.write(w)
+		return
+	}
+	a.writeJSON(w, http.StatusOK, dataResponse[map[string]string]{Data: spec})
+}
+
+// chainSpec returns the spec of the chain from the snapshot captured by the archiver, or from the beacon node until
+// the archiver captured one. It returns nil if neither provides the spec. The spec of the beacon node is kept for the
+// lifetime of the API once fetched.
+func (a *API) chainSpec(ctx context.Context) map[string]string {
+	if snapshot := a.beaconSnapshot(ctx); snapshot != nil && snapshot.Spec != nil {
+		return snapshot.Spec
+	}
+
+	a.specMu.Lock()
+	defer a.specMu.Unlock()
+
+	provider, ok := a.beaconClient.(client.SpecProvider)
+	if a.spec != nil || !ok || time.Since(a.specAt) < specRefreshInterval {
+		return a.spec
+	}
+
+	a.specAt = time.Now()
+	res, err := provider.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		a.logger.Warn("unable to fetch spec", "err", err)
+		return nil
+	}
+	a.spec = beacon.FormatSpec(res.Data)
+	return a.spec
+}
+
+// storedHeader returns the header of an archived block, which is read from its blob sidecars. The returned boolean is
+// true if the block is orphaned. It returns storage.ErrNotFound if the block is not archived or has no blobs.
+func (a *API) storedHeader(ctx context.Context, root common.Hash) (*v1.BeaconBlockHeader, storage.BlobData, bool, error) {
+	orphaned := false
+	data, err := a.dataStoreClient.ReadBlob(ctx, root)
+	if errors.Is(err, storage.ErrNotFound) {
+		orphaned = true
+		data, err = a.dataStoreClient.ReadOrphanedBlob(ctx, root)
+	}
+	if err != nil {
+		return nil, data, false, err
+	}
+	if len(data.BlobSidecars.Data) == 0 {
+		return nil, data, false, storage.ErrNotFound
+	}
+
+	return &v1.BeaconBlockHeader{
+		Root:      phase0.Root(root),
+		Canonical: !orphaned,
+		Header:    data.BlobSidecars.Data[0].SignedBlockHeader,
+	}, data, orphaned, nil
+}
+
+// headerHandler implements the /eth/v1/beacon/headers/{id} endpoint. Blocks requested by their root are served from
+// the headers in their archived blob sidecars. Other identifiers, and blocks without archived blobs, are forwarded to
+// the beacon node.
+func (a *API) headerHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isHash(id) && !isSlot(id) && !isKnownIdentifier(id) {
+		newBlockIdError(id).write(w)
+		return
+	}
+
+	if isHash(id) {
+		root := common.HexToHash(id)
+		header, data, orphaned, err := a.storedHeader(r.Context(), root)
+		if err == nil {
+			metadata := a.blockMetadata(r.Context(), id, root, data, orphaned)
+			w.Header().Set("Cache-Control", a.cacheControl(id, metadata.finalized, true))
+			a.writeJSON(w, http.StatusOK, headerResponse{
+				ExecutionOptimistic: metadata.executionOptimistic,
+				Finalized:           metadata.finalized,
+				Data:                header,
+			})
+			return
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			a.logger.Info("unexpected error reading header", "err", err, "param", id)
+			errServerError.write(w)
+			return
+		}
+	}
+
+	res, err := a.beaconClient.BeaconBlockHeader(r.Context(), &api.BeaconBlockHeaderOpts{Block: id})
+	if err != nil {
+		var apiErr *api.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			errUnknownBlock.write(w)
+		} else {
+			a.logger.Info("unexpected error fetching header", "err", err, "param", id)
+			errServerError.write(w)
+		}
+		return
+	}
+
+	executionOptimistic, _ := res.Metadata["execution_optimistic"].(bool)
+	finalized, _ := res.Metadata["finalized"].(bool)
+	w.Header().Set("Cache-Control", a.cacheControl(id, finalized, false))
+	a.writeJSON(w, http.StatusOK, headerResponse{
+		ExecutionOptimistic: executionOptimistic,
+		Finalized:           finalized,
+		Data:                res.Data,
+	})
+}
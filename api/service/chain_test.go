@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenesisAndSpec(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+
+	request := func(path string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, httptest.NewRequest("GET", path, nil))
+		return response
+	}
+
+	// Neither the archiver nor the beacon node provide the chain configuration
+	require.Equal(t, 404, request("/eth/v1/beacon/genesis").Code)
+	require.Equal(t, 404, request("/eth/v1/config/spec").Code)
+
+	// Until a snapshot is captured, the beacon node is used
+	beaconClient.GenesisData = &v1.Genesis{
+		GenesisTime:           time.Unix(1606824023, 0),
+		GenesisValidatorsRoot: phase0.Root{1},
+		GenesisForkVersion:    phase0.Version{0, 0, 0, 0},
+	}
+	beaconClient.Config = map[string]any{"SECONDS_PER_SLOT": 12 * time.Second}
+	a.specAt = time.Time{}
+
+	response := request("/eth/v1/beacon/genesis")
+	require.Equal(t, 200, response.Code)
+	require.JSONEq(t, `{"data":{"genesis_time":"1606824023","genesis_validators_root":"0x0100000000000000000000000000000000000000000000000000000000000000","genesis_fork_version":"0x00000000"}}`, response.Body.String())
+
+	response = request("/eth/v1/config/spec")
+	require.Equal(t, 200, response.Code)
+	require.JSONEq(t, `{"data":{"SECONDS_PER_SLOT":"12"}}`, response.Body.String())
+
+	// Once captured, the snapshot is served without the beacon node
+	require.NoError(t, fs.WriteBeaconSnapshot(context.Background(), storage.BeaconSnapshot{
+		Genesis: &v1.Genesis{
+			GenesisTime:           time.Unix(1695902400, 0),
+			GenesisValidatorsRoot: phase0.Root{2},
+			GenesisForkVersion:    phase0.Version{0x01, 0x01, 0x70, 0x00},
+		},
+		Spec: map[string]string{"SECONDS_PER_SLOT": "12", "SLOTS_PER_EPOCH": "32"},
+	}))
+	beaconClient.GenesisData = nil
+	beaconClient.Config = nil
+	a.snapshotAt = time.Time{}
+
+	response = request("/eth/v1/beacon/genesis")
+	require.Equal(t, 200, response.Code)
+	require.JSONEq(t, `{"data":{"genesis_time":"1695902400","genesis_validators_root":"0x0200000000000000000000000000000000000000000000000000000000000000","genesis_fork_version":"0x01017000"}}`, response.Body.String())
+
+	response = request("/eth/v1/config/spec")
+	require.Equal(t, 200, response.Code)
+	require.JSONEq(t, `{"data":{"SECONDS_PER_SLOT":"12","SLOTS_PER_EPOCH":"32"}}`, response.Body.String())
+}
+
+func TestHeaderHandler(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+
+	archivedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890aaaaaa")
+	orphanedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890bbbbbb")
+	for slot, root := range map[phase0.Slot]common.Hash{100: archivedRoot, 101: orphanedRoot} {
+		sidecars := blobtest.NewBlobSidecars(t, 1)
+		sidecars[0].SignedBlockHeader.Message.Slot = slot
+		require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{
+			Header:       storage.Header{BeaconBlockHash: root},
+			BlobSidecars: storage.BlobSidecars{Data: sidecars},
+		}))
+	}
+	require.NoError(t, fs.OrphanBlob(context.Background(), orphanedRoot))
+
+	finalized := &v1.BeaconBlockHeader{
+		Root:      phase0.Root(archivedRoot),
+		Canonical: true,
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{Slot: 100},
+		},
+	}
+	beaconClient.Headers["finalized"] = finalized
+	beaconClient.Headers["100"] = finalized
+
+	request := func(id string) (int, headerResponse) {
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, httptest.NewRequest("GET", "/eth/v1/beacon/headers/"+id, nil))
+
+		var body headerResponse
+		if response.Code == 200 {
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		}
+		return response.Code, body
+	}
+
+	// Archived blocks are served from their blob sidecars
+	code, body := request(archivedRoot.String())
+	require.Equal(t, 200, code)
+	require.True(t, body.Finalized)
+	require.True(t, body.Data.Canonical)
+	require.Equal(t, phase0.Root(archivedRoot), body.Data.Root)
+	require.Equal(t, phase0.Slot(100), body.Data.Header.Message.Slot)
+
+	code, body = request(orphanedRoot.String())
+	require.Equal(t, 200, code)
+	require.False(t, body.Finalized)
+	require.False(t, body.Data.Canonical)
+	require.Equal(t, phase0.Slot(101), body.Data.Header.Message.Slot)
+
+	// Other identifiers are forwarded to the beacon node
+	code, body = request("100")
+	require.Equal(t, 200, code)
+	require.Equal(t, phase0.Root(archivedRoot), body.Data.Root)
+
+	code, _ = request("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890cccccc")
+	require.Equal(t, 404, code)
+	code, _ = request("101")
+	require.Equal(t, 404, code)
+	code, _ = request("invalid")
+	require.Equal(t, 400, code)
+}
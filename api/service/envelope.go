@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	consensusVersionHeader    = "Eth-Consensus-Version"
+	executionOptimisticHeader = "Eth-Execution-Optimistic"
+	finalizedHeader           = "Eth-Finalized"
+)
+
+// forkEpochKeys lists the consensus versions with the spec keys of their fork epochs, latest first.
+var forkEpochKeys = []struct {
+	version spec.DataVersion
+	key     string
+}{
+	{version: spec.DataVersionDeneb, key: "DENEB_FORK_EPOCH"},
+	{version: spec.DataVersionCapella, key: "CAPELLA_FORK_EPOCH"},
+	{version: spec.DataVersionBellatrix, key: "BELLATRIX_FORK_EPOCH"},
+	{version: spec.DataVersionAltair, key: "ALTAIR_FORK_EPOCH"},
+}
+
+// blobSidecarsResponse is the JSON response of the blob sidecars endpoint, see getBlobSidecars in the beacon API spec.
+type blobSidecarsResponse struct {
+	Version             string               `json:"version"`
+	ExecutionOptimistic bool                 `json:"execution_optimistic"`
+	Finalized           bool                 `json:"finalized"`
+	Data                []*deneb.BlobSidecar `json:"data"`
+}
+
+// blockMetadata holds the metadata of a block that the beacon API returns along with its blob sidecars.
+type blockMetadata struct {
+	version             spec.DataVersion
+	executionOptimistic bool
+	finalized           bool
+}
+
+// consensusVersion returns the fork of the given slot. Without a spec, every slot is assumed to be in Deneb, the first
+// fork with blobs.
+func (a *API) consensusVersion(ctx context.Context, slot uint64) spec.DataVersion {
+	config := a.chainSpec(ctx)
+	slotsPerEpoch, err := strconv.ParseUint(config["SLOTS_PER_EPOCH"], 10, 64)
+	if err != nil || slotsPerEpoch == 0 {
+		return spec.DataVersionDeneb
+	}
+
+	epoch := slot / slotsPerEpoch
+	for _, fork := range forkEpochKeys {
+		if forkEpoch, err := strconv.ParseUint(config[fork.key], 10, 64); err == nil && epoch >= forkEpoch {
+			return fork.version
+		}
+	}
+	return spec.DataVersionPhase0
+}
+
+// blockMetadata returns the metadata of a block. The slot of the block is read from its blobs, or from the id it was
+// requested by, or else from the beacon node. Orphaned blocks are never finalized, and only unfinalized blocks can be
+// optimistic, which is reported by the beacon node.
+func (a *API) blockMetadata(ctx context.Context, id string, root common.Hash, data storage.BlobData, orphaned bool) blockMetadata {
+	var slot uint64
+	slotKnown := true
+	if len(data.BlobSidecars.Data) > 0 {
+		slot = uint64(data.BlobSidecars.Data[0].SignedBlockHeader.Message.Slot)
+	} else if isSlot(id) {
+		slot, _ = strconv.ParseUint(id, 10, 64)
+	} else {
+		slotKnown = false
+	}
+
+	var header *api.Response[*v1.BeaconBlockHeader]
+	if !slotKnown || (!orphaned && !a.isFinalized(ctx, slot)) {
+		res, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: root.String()})
+		if err == nil && res.Data != nil && res.Data.Header != nil && res.Data.Header.Message != nil {
+			header = res
+			if !slotKnown {
+				slot = uint64(res.Data.Header.Message.Slot)
+				slotKnown = true
+			}
+		}
+	}
+
+	metadata := blockMetadata{version: spec.DataVersionDeneb}
+	if slotKnown {
+		metadata.version = a.consensusVersion(ctx, slot)
+		metadata.finalized = !orphaned && a.isFinalized(ctx, slot)
+	}
+	if !metadata.finalized && header != nil {
+		metadata.executionOptimistic, _ = header.Metadata["execution_optimistic"].(bool)
+	}
+	return metadata
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	m "github.com/base-org/blob-archiver/api/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/verify"
+	validator "github.com/base-org/blob-archiver/validator/service"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// readFromBeacon reads the blob sidecars of a block that is not in storage from the beacon node, e.g. because the
+// archiver has not written it yet. It returns storage.ErrNotFound if the beacon node does not know the block, cannot
+// be reached or returns no sidecars: a beacon node also returns no sidecars for blocks outside of its retention period,
+// so the block is left to the peers. If configured, the sidecars are written back to storage. Only finalized,
+// canonical blocks are written back, as the API does not track whether the blocks it stores are orphaned later.
+func (a *API) readFromBeacon(ctx context.Context, beaconBlockHash common.Hash) (storage.BlobData, error) {
+	sidecarsProvider, ok := a.beaconClient.(client.BlobSidecarsProvider)
+	if !ok {
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+
+	resp, err := sidecarsProvider.BlobSidecars(ctx, &api.BlobSidecarsOpts{Block: beaconBlockHash.String()})
+	if err != nil {
+		var apiErr *api.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+			a.metrics.RecordBeaconFallback(m.BeaconFallbackNotFound)
+			return storage.BlobData{}, storage.ErrNotFound
+		}
+
+		a.metrics.RecordBeaconFallback(m.BeaconFallbackError)
+		a.logger.Warn("unable to fetch blob sidecars from beacon node", "err", err, "beaconBlockHash", beaconBlockHash.String())
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+	if len(resp.Data) == 0 {
+		a.metrics.RecordBeaconFallback(m.BeaconFallbackNotFound)
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+	a.metrics.RecordBeaconFallback(m.BeaconFallbackFound)
+
+	result := storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: beaconBlockHash},
+		BlobSidecars: storage.BlobSidecars{Data: resp.Data},
+	}
+
+	if writer, ok := a.dataStoreClient.(storage.DataStoreWriter); ok && a.cfg.BeaconFallbackWrite &&
+		a.isFinalizedCanonical(ctx, beaconBlockHash) {
+		if err := writer.WriteBlob(ctx, result); err != nil {
+			a.logger.Warn("unable to write blob sidecars from beacon node to storage", "err", err, "beaconBlockHash", beaconBlockHash.String())
+		}
+	}
+
+	return result, nil
+}
+
+// isFinalizedCanonical returns whether the beacon node considers a block canonical and finalized, so that it can never be
+// orphaned.
+func (a *API) isFinalizedCanonical(ctx context.Context, beaconBlockHash common.Hash) bool {
+	header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: beaconBlockHash.String()})
+	if err != nil || header.Data == nil || header.Data.Header == nil || header.Data.Header.Message == nil {
+		return false
+	}
+	return header.Data.Canonical && a.isFinalized(ctx, uint64(header.Data.Header.Message.Slot))
+}
+
+// peer is another blob API that blocks missing from storage are requested from.
+type peer struct {
+	url    string
+	client validator.BlobSidecarClient
+}
+
+// readFromPeers reads the blob sidecars of a block that is not in storage from the configured peers, in order. Peers
+// are not trusted, so their sidecars are only returned if they are the complete set of blobs of the block, as listed
+// in the block from the beacon node, and pass KZG verification. Peers that return no sidecars are skipped. If
+// configured, a block found on a peer is queued for the archiver to ingest. It returns storage.ErrNotFound if no peer
+// has the block, or if the block cannot be fetched from the beacon node to verify the sidecars against.
+func (a *API) readFromPeers(ctx context.Context, beaconBlockHash common.Hash) (storage.BlobData, error) {
+	if len(a.peers) == 0 {
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+
+	blocks, ok := a.beaconClient.(client.SignedBeaconBlockProvider)
+	if !ok {
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+	commitments, err := verify.BlockCommitments(ctx, blocks, beaconBlockHash)
+	if err != nil {
+		a.logger.Warn("unable to fetch block to verify peer sidecars", "err", err, "beaconBlockHash", beaconBlockHash.String())
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+	if len(commitments) == 0 {
+		return storage.BlobData{}, storage.ErrNotFound
+	}
+
+	for _, p := range a.peers {
+		status, sidecars, err := p.client.FetchSidecars(ctx, beaconBlockHash.String(), validator.FormatSSZ)
+		if err != nil || (status != http.StatusOK && status != http.StatusNotFound) {
+			a.metrics.RecordPeerFallback(p.url, m.PeerFallbackError)
+			a.logger.Warn("unable to fetch blob sidecars from peer", "peer", p.url, "status", status, "err", err, "beaconBlockHash", beaconBlockHash.String())
+			continue
+		}
+		if status == http.StatusNotFound || len(sidecars.Data) == 0 {
+			a.metrics.RecordPeerFallback(p.url, m.PeerFallbackNotFound)
+			continue
+		}
+
+		if err := verify.CompleteBlobSidecars(beaconBlockHash, commitments, sidecars.Data); err != nil {
+			a.metrics.RecordPeerFallback(p.url, m.PeerFallbackInvalid)
+			a.logger.Error("peer returned invalid blob sidecars", "peer", p.url, "beaconBlockHash", beaconBlockHash.String(), "err", err)
+			continue
+		}
+
+		a.metrics.RecordPeerFallback(p.url, m.PeerFallbackFound)
+		if a.cfg.PeerIngest {
+			a.queueIngest(ctx, beaconBlockHash, p.url)
+		}
+		return storage.BlobData{
+			Header:       storage.Header{BeaconBlockHash: beaconBlockHash},
+			BlobSidecars: sidecars,
+		}, nil
+	}
+
+	return storage.BlobData{}, storage.ErrNotFound
+}
+
+// queueIngest adds a block served by a peer to the ingest queue, for the archiver to fetch it from the same peer and
+// store it. Every queued block is an object of its own, so that concurrent requests and the archiver never overwrite
+// each other's changes. Failures are only logged, as the block is queued again the next time it is served by a peer.
+func (a *API) queueIngest(ctx context.Context, beaconBlockHash common.Hash, peerURL string) {
+	store, ok := a.dataStoreClient.(storage.DataStore)
+	if !ok {
+		return
+	}
+
+	queue, err := store.ReadIngestQueue(ctx)
+	if err != nil {
+		a.logger.Warn("unable to read ingest queue", "err", err)
+		return
+	}
+	if _, queued := queue[beaconBlockHash]; queued {
+		return
+	}
+
+	err = store.WriteIngestBlock(ctx, beaconBlockHash, storage.IngestBlock{Peer: peerURL, QueuedAt: time.Now().Unix()})
+	if err != nil {
+		a.logger.Warn("unable to queue block for ingest", "err", err, "beaconBlockHash", beaconBlockHash.String())
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	validator "github.com/base-org/blob-archiver/validator/service"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeaconFallback(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+
+	root := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890666666")
+	emptyRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890777777")
+	sidecars := blobtest.NewBlobSidecars(t, 2)
+	beaconClient.Blobs[root.String()] = sidecars
+	beaconClient.Blobs[emptyRoot.String()] = nil
+
+	request := func(root common.Hash) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/eth/v1/beacon/blob_sidecars/"+root.String(), nil)
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	// Without the fallback, blocks that are not in storage are not found
+	require.Equal(t, 404, request(root).Code)
+
+	a.cfg.BeaconFallback = true
+	response := request(root)
+	require.Equal(t, 200, response.Code)
+	var data storage.BlobSidecars
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&data))
+	require.Equal(t, sidecars, data.Data)
+	exists, err := fs.Exists(context.Background(), root)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Blocks the beacon node does not know are still not found
+	require.Equal(t, 404, request(common.Hash{1}).Code)
+
+	// Sidecars are only written back to storage once their block is finalized and canonical
+	a.cfg.BeaconFallbackWrite = true
+	header := &v1.BeaconBlockHeader{
+		Root:   phase0.Root(root),
+		Header: &phase0.SignedBeaconBlockHeader{Message: &phase0.BeaconBlockHeader{Slot: phase0.Slot(blobtest.StartSlot + 5)}},
+	}
+	beaconClient.Headers[root.String()] = header
+	beaconClient.Headers["finalized"] = &v1.BeaconBlockHeader{
+		Header: &phase0.SignedBeaconBlockHeader{Message: &phase0.BeaconBlockHeader{Slot: phase0.Slot(blobtest.StartSlot + 3)}},
+	}
+	require.Equal(t, 200, request(root).Code)
+	exists, err = fs.Exists(context.Background(), root)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	header.Header.Message.Slot = phase0.Slot(blobtest.StartSlot + 2)
+	require.Equal(t, 200, request(root).Code)
+	exists, err = fs.Exists(context.Background(), root)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	header.Canonical = true
+	response = request(root)
+	require.Equal(t, 200, response.Code)
+	stored, err := fs.ReadBlob(context.Background(), root)
+	require.NoError(t, err)
+	require.Equal(t, sidecars, stored.BlobSidecars.Data)
+
+	// Only blobs read from storage are immutable
+	require.NotContains(t, response.Header().Get("Cache-Control"), "immutable")
+	require.Equal(t, "public, max-age=31536000, immutable", request(root).Header().Get("Cache-Control"))
+
+	// Empty responses are not found, e.g. for blocks outside of the retention period of the beacon node, and are not
+	// written back to storage
+	require.Equal(t, 404, request(emptyRoot).Code)
+	exists, err = fs.Exists(context.Background(), emptyRoot)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Refused orphaned blocks are not read from the beacon node
+	orphan := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890888888")
+	beaconClient.Blobs[orphan.String()] = sidecars
+	require.NoError(t, fs.WriteBlob(context.Background(), storage.BlobData{Header: storage.Header{BeaconBlockHash: orphan}}))
+	require.NoError(t, fs.OrphanBlob(context.Background(), orphan))
+	a.cfg.RefuseOrphaned = true
+	require.Equal(t, 404, request(orphan).Code)
+}
+
+// stubPeer is a blob API that serves the given sidecars by block root.
+type stubPeer struct {
+	sidecars map[string][]*deneb.BlobSidecar
+}
+
+func (p *stubPeer) FetchSidecars(_ context.Context, id string, _ validator.Format) (int, storage.BlobSidecars, error) {
+	sidecars, ok := p.sidecars[id]
+	if !ok {
+		return http.StatusNotFound, storage.BlobSidecars{}, nil
+	}
+	return http.StatusOK, storage.BlobSidecars{Data: sidecars}, nil
+}
+
+func TestPeerFallback(t *testing.T) {
+	a, fs, beaconClient, cleanup := setup(t)
+	defer cleanup()
+
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 2)
+	_, otherSidecars := blobtest.NewVerifiableBlobSidecars(t, 1)
+	beaconClient.Commitments[root.String()] = blobtest.Commitments(sidecars)
+	invalid := &stubPeer{sidecars: map[string][]*deneb.BlobSidecar{root.String(): otherSidecars}}
+	truncated := &stubPeer{sidecars: map[string][]*deneb.BlobSidecar{root.String(): sidecars[:1]}}
+	valid := &stubPeer{sidecars: map[string][]*deneb.BlobSidecar{root.String(): sidecars}}
+
+	request := func(root common.Hash) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/eth/v1/beacon/blob_sidecars/"+root.String(), nil)
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	// Without peers, blocks that are not in storage are not found
+	require.Equal(t, 404, request(root).Code)
+
+	// Peers are asked in order, and invalid or incomplete sidecars are skipped
+	a.peers = []peer{{url: "http://invalid", client: invalid}, {url: "http://truncated", client: truncated}, {url: "http://valid", client: valid}}
+	response := request(root)
+	require.Equal(t, 200, response.Code)
+	var data storage.BlobSidecars
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&data))
+	require.Equal(t, sidecars, data.Data)
+
+	queue, err := fs.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, queue)
+
+	// Peers are not asked for the blocks of a batch
+	response = httptest.NewRecorder()
+	a.router.ServeHTTP(response, httptest.NewRequest("GET", "/blob_sidecars?ids="+root.String(), nil))
+	require.Equal(t, 200, response.Code)
+	require.Contains(t, response.Body.String(), `"status":"not_archived"`)
+
+	// Blocks no peer has are still not found
+	require.Equal(t, 404, request(common.Hash{1}).Code)
+
+	// Without the block from the beacon node, sidecars cannot be checked for completeness
+	delete(beaconClient.Commitments, root.String())
+	require.Equal(t, 404, request(root).Code)
+	beaconClient.Commitments[root.String()] = blobtest.Commitments(sidecars)
+
+	// Blocks served by a peer can be queued for the archiver
+	a.cfg.PeerIngest = true
+	require.Equal(t, 200, request(root).Code)
+	queue, err = fs.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	require.Equal(t, "http://valid", queue[root].Peer)
+	exists, err := fs.Exists(context.Background(), root)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Blocks queued concurrently are all kept
+	var wg sync.WaitGroup
+	for i := byte(1); i <= 8; i++ {
+		wg.Add(1)
+		go func(root common.Hash) {
+			defer wg.Done()
+			a.queueIngest(context.Background(), root, "http://valid")
+		}(common.Hash{i})
+	}
+	wg.Wait()
+	queue, err = fs.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Len(t, queue, 9)
+
+	// Peers are asked for blocks the beacon node returns no sidecars for, e.g. because it pruned them
+	a.cfg.BeaconFallback = true
+	beaconClient.Blobs[root.String()] = nil
+	response = request(root)
+	require.Equal(t, 200, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&data))
+	require.Equal(t, sidecars, data.Data)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	m "github.com/base-org/blob-archiver/api/metrics"
+	"github.com/base-org/blob-archiver/common/ratelimit"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	apiKeyHeader     = "X-API-Key"
+	apiKeyQueryParam = "api_key"
+	// keysReloadInterval limits how often the API keys file is checked for changes.
+	keysReloadInterval = 10 * time.Second
+	// limiterIdleTimeout is how long the rate limit of a client is kept after its last request.
+	limiterIdleTimeout = 10 * time.Minute
+)
+
+var (
+	errMissingAPIKey = &httpError{
+		Code:    http.StatusUnauthorized,
+		Message: "Missing API key",
+	}
+	errInvalidAPIKey = &httpError{
+		Code:    http.StatusUnauthorized,
+		Message: "Invalid API key",
+	}
+	errRateLimited = &httpError{
+		Code:    http.StatusTooManyRequests,
+		Message: "Too many requests",
+	}
+	errOverloaded = &httpError{
+		Code:    http.StatusServiceUnavailable,
+		Message: "Server overloaded",
+	}
+)
+
+// apiKey is a client of the API, identified by its key.
+type apiKey struct {
+	name string
+	// requestsPerSecond is the rate limit of the key, or 0 to use the configured default.
+	requestsPerSecond float64
+}
+
+// keyStore holds the API keys loaded from a file. The file is checked for changes at most once per
+// keysReloadInterval, and reloaded when it changed. If the file cannot be loaded, the previous keys are kept.
+type keyStore struct {
+	path string
+	log  log.Logger
+
+	mu        sync.Mutex
+	keys      map[string]apiKey
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+func newKeyStore(path string, l log.Logger) *keyStore {
+	s := &keyStore{path: path, log: l, checkedAt: time.Now()}
+	if err := s.reload(); err != nil {
+		l.Error("unable to load API keys, all keys are rejected until the file is fixed", "path", path, "err", err)
+	}
+	return s
+}
+
+// lookup returns the API key with the given value, reloading the keys file if it changed.
+func (s *keyStore) lookup(key string) (apiKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checkedAt) >= keysReloadInterval {
+		s.checkedAt = time.Now()
+		if err := s.reload(); err != nil {
+			s.log.Error("unable to reload API keys, keeping the previous keys", "path", s.path, "err", err)
+		}
+	}
+
+	k, ok := s.keys[key]
+	return k, ok
+}
+
+// reload loads the keys file if it changed since it was last loaded. The caller must hold the lock, if any.
+func (s *keyStore) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if s.keys != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	keys, err := parseKeys(f)
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	s.log.Info("loaded API keys", "path", s.path, "keys", len(keys))
+	return nil
+}
+
+// parseKeys parses a keys file, holding one '<name> <key> [requests-per-second]' per line. Empty lines and lines
+// starting with # are ignored.
+func parseKeys(r io.Reader) (map[string]apiKey, error) {
+	keys := make(map[string]apiKey)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected '<name> <key> [requests-per-second]'", line)
+		}
+
+		k := apiKey{name: fields[0]}
+		if len(fields) == 3 {
+			rate, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil || rate <= 0 {
+				return nil, fmt.Errorf("line %d: invalid requests per second %q", line, fields[2])
+			}
+			k.requestsPerSecond = rate
+		}
+		if _, ok := keys[fields[1]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key", line)
+		}
+		keys[fields[1]] = k
+	}
+	return keys, scanner.Err()
+}
+
+// clientLimiter rate limits clients with a token bucket per client, which is created on the first request of the
+// client and removed once the client was idle for limiterIdleTimeout.
+type clientLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*clientBucket
+	prunedAt time.Time
+}
+
+type clientBucket struct {
+	bucket   *ratelimit.TokenBucket
+	lastSeen time.Time
+}
+
+func newClientLimiter() *clientLimiter {
+	return &clientLimiter{
+		buckets:  make(map[string]*clientBucket),
+		prunedAt: time.Now(),
+	}
+}
+
+// allow returns true if the client may make a request at the given rate. Otherwise, it returns how long the client
+// has to wait.
+func (l *clientLimiter) allow(client string, rate float64, burst int) (bool, time.Duration) {
+	b := l.bucket(client, rate, burst)
+	if b.Allow() {
+		return true, 0
+	}
+	return false, b.Delay()
+}
+
+// wait blocks until the client may make a request at the given rate, or the context is done.
+func (l *clientLimiter) wait(ctx context.Context, client string, rate float64, burst int) error {
+	return l.bucket(client, rate, burst).Wait(ctx)
+}
+
+// bucket returns the token bucket of the client, creating it if needed, and removes the buckets of idle clients.
+func (l *clientLimiter) bucket(client string, rate float64, burst int) *ratelimit.TokenBucket {
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.prunedAt) >= limiterIdleTimeout {
+		l.prunedAt = now
+		for c, b := range l.buckets {
+			if now.Sub(b.lastSeen) >= limiterIdleTimeout {
+				delete(l.buckets, c)
+			}
+		}
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &clientBucket{bucket: ratelimit.NewTokenBucket(rate, burst)}
+		l.buckets[client] = b
+	}
+	b.lastSeen = now
+	l.mu.Unlock()
+
+	if b.bucket.Rate() != rate {
+		b.bucket.SetRate(rate)
+	}
+	return b.bucket
+}
+
+// clientLimit is the rate limit of the client of a request, as determined by limitClients.
+type clientLimit struct {
+	client string
+	rate   float64
+}
+
+type clientLimitKey struct{}
+
+// clientIP returns the IP address of the client of a request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// redactAPIKey is a middleware that moves an API key given in the api_key query parameter to the X-API-Key header, unless
+// that is set, and removes it from the URL, so that keys are not logged with the URL of the request.
+func redactAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has(apiKeyQueryParam) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		if r.Header.Get(apiKeyHeader) == "" {
+			r.Header.Set(apiKeyHeader, query.Get(apiKeyQueryParam))
+		}
+		query.Del(apiKeyQueryParam)
+		r.URL.RawQuery = query.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitClients is a middleware that authenticates API keys, given in the X-API-Key header or the api_key query
+// parameter (see redactAPIKey), and rate limits clients. Requests with a key are limited per key, requests without a key
+// per IP address. Requests without a key are rejected if keys are required.
+func (a *API) limitClients(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(apiKeyHeader)
+
+		client, scope, rate := "ip:"+clientIP(r), m.RateLimitScopeIP, a.cfg.IPRequestsPerSecond
+		if a.keys != nil && key != "" {
+			k, ok := a.keys.lookup(key)
+			if !ok {
+				errInvalidAPIKey.write(w)
+				return
+			}
+			a.metrics.RecordKeyRequest(k.name)
+
+			client, scope, rate = "key:"+k.name, m.RateLimitScopeKey, a.cfg.KeyRequestsPerSecond
+			if k.requestsPerSecond > 0 {
+				rate = k.requestsPerSecond
+			}
+		} else if a.keys != nil && a.cfg.RequireKey {
+			errMissingAPIKey.write(w)
+			return
+		}
+
+		if rate > 0 {
+			if ok, delay := a.limiter.allow(client, rate, a.cfg.RateLimitBurst); !ok {
+				a.metrics.RecordRateLimited(scope)
+				w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(delay.Seconds())))))
+				errRateLimited.write(w)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), clientLimitKey{}, clientLimit{client: client, rate: rate}))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// shedLoad is a middleware that rejects requests while the configured maximum number of requests is being processed,
+// to protect the storage backend.
+func (a *API) shedLoad(next http.Handler) http.Handler {
+	if a.inFlight == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case a.inFlight <- struct{}{}:
+			defer func() { <-a.inFlight }()
+			next.ServeHTTP(w, r)
+		default:
+			a.metrics.RecordShedRequest()
+			w.Header().Set("Retry-After", "1")
+			errOverloaded.write(w)
+		}
+	})
+}
+
+// chargeRequest charges the client of a request for an additional request, for handlers that do the work of several
+// requests at once. It waits until the rate limit of the client allows the request instead of rejecting it, as the
+// client was already admitted.
+func (a *API) chargeRequest(ctx context.Context) error {
+	limit, ok := ctx.Value(clientLimitKey{}).(clientLimit)
+	if !ok {
+		return nil
+	}
+	return a.limiter.wait(ctx, limit.client, limit.rate, a.cfg.RateLimitBurst)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/api/flags"
+	"github.com/base-org/blob-archiver/api/metrics"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitedAPI(t *testing.T, cfg flags.APIConfig) *API {
+	logger := testlog.Logger(t, log.LvlInfo)
+	fs := storage.NewFileStorage(t.TempDir(), logger)
+	return NewAPI(fs, beacontest.NewEmptyStubBeaconClient(), metrics.NewMetrics(), logger, cfg)
+}
+
+func writeKeys(t *testing.T, file string, keys string) {
+	require.NoError(t, os.WriteFile(file, []byte(keys), 0600))
+}
+
+func versionRequest(a *API, remoteAddr string, header http.Header, query string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", "/eth/v1/node/version"+query, nil)
+	request.RemoteAddr = remoteAddr
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	response := httptest.NewRecorder()
+	a.router.ServeHTTP(response, request)
+	return response
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := parseKeys(strings.NewReader("# comment\n\nalice key-a\nbob  key-b 2.5\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]apiKey{
+		"key-a": {name: "alice"},
+		"key-b": {name: "bob", requestsPerSecond: 2.5},
+	}, keys)
+
+	_, err = parseKeys(strings.NewReader("alice\n"))
+	require.ErrorContains(t, err, "line 1")
+	_, err = parseKeys(strings.NewReader("alice key-a x\n"))
+	require.ErrorContains(t, err, "invalid requests per second")
+	_, err = parseKeys(strings.NewReader("alice key-a\nbob key-a\n"))
+	require.ErrorContains(t, err, "line 2: duplicate key")
+}
+
+func TestAPIKeys(t *testing.T) {
+	file := path.Join(t.TempDir(), "keys")
+	writeKeys(t, file, "alice key-a\n")
+	a := newLimitedAPI(t, flags.APIConfig{KeysFile: file, RequireKey: true})
+
+	response := versionRequest(a, "1.2.3.4:1000", nil, "")
+	require.Equal(t, 401, response.Code)
+	require.Contains(t, response.Body.String(), "Missing API key")
+
+	response = versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-b"}}, "")
+	require.Equal(t, 401, response.Code)
+	require.Contains(t, response.Body.String(), "Invalid API key")
+
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-a"}}, "").Code)
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", nil, "?api_key=key-a").Code)
+
+	// Keys in the query are removed from the URL, so they are not logged
+	var requestURI string
+	a.router.Get("/uri", func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+	})
+	request := httptest.NewRequest("GET", "/uri?api_key=key-a&from=1", nil)
+	response = httptest.NewRecorder()
+	a.router.ServeHTTP(response, request)
+	require.Equal(t, 200, response.Code)
+	require.Equal(t, "/uri?from=1", requestURI)
+
+	// The health check needs no key
+	request = httptest.NewRequest("GET", "/healthz", nil)
+	response = httptest.NewRecorder()
+	a.router.ServeHTTP(response, request)
+	require.Equal(t, 200, response.Code)
+
+	// The keys file is reloaded when it changes, and invalid files are ignored
+	writeKeys(t, file, "bob key-b\n")
+	a.keys.checkedAt = time.Time{}
+	require.Equal(t, 401, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-a"}}, "").Code)
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-b"}}, "").Code)
+
+	writeKeys(t, file, "invalid\n")
+	a.keys.checkedAt = time.Time{}
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-b"}}, "").Code)
+}
+
+func TestRateLimits(t *testing.T) {
+	file := path.Join(t.TempDir(), "keys")
+	writeKeys(t, file, "alice key-a\nbob key-b 0.001\n")
+	a := newLimitedAPI(t, flags.APIConfig{
+		KeysFile:             file,
+		KeyRequestsPerSecond: 1000,
+		IPRequestsPerSecond:  0.001,
+		RateLimitBurst:       2,
+	})
+
+	// Requests without a key are limited per IP address
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", nil, "").Code)
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1001", nil, "").Code)
+	response := versionRequest(a, "1.2.3.4:1000", nil, "")
+	require.Equal(t, 429, response.Code)
+	require.Contains(t, response.Body.String(), "Too many requests")
+	require.NotEmpty(t, response.Header().Get("Retry-After"))
+	require.Equal(t, 200, versionRequest(a, "5.6.7.8:1000", nil, "").Code)
+
+	// Requests with a key are limited per key, with the rate of the key if set
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-a"}}, "").Code)
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-a"}}, "").Code)
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", http.Header{"X-Api-Key": {"key-b"}}, "").Code)
+	require.Equal(t, 200, versionRequest(a, "5.6.7.8:1000", http.Header{"X-Api-Key": {"key-b"}}, "").Code)
+	require.Equal(t, 429, versionRequest(a, "9.9.9.9:1000", http.Header{"X-Api-Key": {"key-b"}}, "").Code)
+}
+
+func TestShedLoad(t *testing.T) {
+	a := newLimitedAPI(t, flags.APIConfig{MaxConcurrentRequests: 1})
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	a.router.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	done := make(chan int)
+	go func() {
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, httptest.NewRequest("GET", "/slow", nil))
+		done <- response.Code
+	}()
+	<-entered
+
+	response := versionRequest(a, "1.2.3.4:1000", nil, "")
+	require.Equal(t, 503, response.Code)
+	require.Equal(t, "1", response.Header().Get("Retry-After"))
+
+	close(release)
+	require.Equal(t, 200, <-done)
+	require.Equal(t, 200, versionRequest(a, "1.2.3.4:1000", nil, "").Code)
+}
+
+func TestBatchRateLimits(t *testing.T) {
+	a := newLimitedAPI(t, flags.APIConfig{IPRequestsPerSecond: 0.001, RateLimitBurst: 3, MaxConcurrentRequests: 1})
+	batchRequest := func(ctx context.Context, blocks int) []string {
+		ids := make([]string, blocks)
+		for i := range ids {
+			ids[i] = common.Hash{byte(i + 1)}.String()
+		}
+		request := httptest.NewRequest("GET", "/blob_sidecars?ids="+strings.Join(ids, ","), nil).WithContext(ctx)
+		request.RemoteAddr = "1.2.3.4:1000"
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		require.Equal(t, 200, response.Code)
+		return strings.Split(strings.TrimSpace(response.Body.String()), "\n")
+	}
+
+	// Every block of a batch is charged, and blocks are resolved although the batch takes the only request slot
+	require.Len(t, batchRequest(context.Background(), 3), 3)
+	require.Equal(t, 429, versionRequest(a, "1.2.3.4:1000", nil, "").Code)
+
+	// Once the client is out of tokens, a batch only proceeds at its rate limit
+	a.limiter = newClientLimiter()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.Len(t, batchRequest(ctx, 5), 3)
+}
@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 
 	"github.com/base-org/blob-archiver/api/flags"
+	"github.com/base-org/blob-archiver/common/tlsutil"
 	"github.com/ethereum-optimism/optimism/op-service/httputil"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
 	"github.com/ethereum/go-ethereum/log"
@@ -30,7 +31,7 @@ type APIService struct {
 	cfg           flags.APIConfig
 	registry      *prometheus.Registry
 	metricsServer *httputil.HTTPServer
-	apiServer     *httputil.HTTPServer
+	apiServer     *tlsutil.HTTPServer
 	api           *API
 }
 
@@ -48,12 +49,17 @@ func (a *APIService) Start(ctx context.Context) error {
 
 	a.log.Debug("starting API server", "address", a.cfg.ListenAddr)
 
-	srv, err := httputil.StartHTTPServer(a.cfg.ListenAddr, a.api.router)
+	tlsConfig, err := tlsutil.NewServerConfig(a.cfg.TLSConfig, a.log)
+	if err != nil {
+		return fmt.Errorf("failed to load API server TLS config: %w", err)
+	}
+
+	srv, err := tlsutil.StartHTTPServer(a.cfg.ListenAddr, a.api.router, tlsConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start API server: %w", err)
 	}
 
-	a.log.Info("API server started", "address", srv.Addr().String())
+	a.log.Info("API server started", "address", srv.Addr().String(), "tls", tlsConfig != nil)
 	a.apiServer = srv
 	return nil
 }
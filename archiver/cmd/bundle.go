@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/archiver/flags"
+	"github.com/base-org/blob-archiver/common/beacon"
+	"github.com/base-org/blob-archiver/common/bundle"
+	"github.com/base-org/blob-archiver/common/storage"
+	oplog "github.com/ethereum-optimism/optimism/op-service/log"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+var exportCommand = &cli.Command{
+	Name:        "export",
+	Usage:       "Export the blobs of a slot range to a bundle file",
+	Description: "Writes the blobs of the canonical blocks of a slot range from the data store to a bundle file, which can be loaded into another data store with the import command",
+	Flags:       []cli.Flag{flags.ExportFromSlotFlag, flags.ExportToSlotFlag, flags.ExportOutputFlag},
+	Action:      exportAction,
+}
+
+var importCommand = &cli.Command{
+	Name:        "import",
+	Usage:       "Import the blobs of a bundle file",
+	Description: "Verifies that a bundle file is of the chain of the beacon node, verifies its checksums and that its blobs are the complete, valid blobs of their blocks, and writes its blobs to the data store, skipping blobs that already exist",
+	Flags:       []cli.Flag{flags.ImportInputFlag, flags.ImportGenesisValidatorsRootFlag},
+	Action:      importAction,
+}
+
+func exportAction(cliCtx *cli.Context) error {
+	cfg := flags.ReadExportConfig(cliCtx)
+	if err := cfg.Check(); err != nil {
+		return fmt.Errorf("invalid CLI flags: %w", err)
+	}
+	l := oplog.NewLogger(oplog.AppOut(cliCtx), cfg.LogConfig)
+
+	ctx, cancel := context.WithCancel(cliCtx.Context)
+	defer cancel()
+
+	beaconClient, err := beacon.NewBeaconClient(ctx, cfg.BeaconConfig, l, beacon.NoopMetrics)
+	if err != nil {
+		return err
+	}
+	exportClient, ok := beaconClient.(bundle.BeaconClient)
+	if !ok {
+		return fmt.Errorf("beacon client does not provide genesis")
+	}
+
+	storageClient, err := storage.NewStorage(cfg.StorageConfig, l)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(cfg.Output)
+	if err != nil {
+		return err
+	}
+	result, err := bundle.Export(ctx, exportClient, storageClient, cfg.FromSlot, cfg.ToSlot, f, l)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(cfg.Output)
+		return fmt.Errorf("failed to export bundle: %w", err)
+	}
+
+	l.Info("exported bundle", "output", cfg.Output, "fromSlot", cfg.FromSlot, "toSlot", cfg.ToSlot, "exported", result.Exported, "missing", result.Missing)
+	return nil
+}
+
+func importAction(cliCtx *cli.Context) error {
+	cfg := flags.ReadImportConfig(cliCtx)
+	if err := cfg.Check(); err != nil {
+		return fmt.Errorf("invalid CLI flags: %w", err)
+	}
+	l := oplog.NewLogger(oplog.AppOut(cliCtx), cfg.LogConfig)
+
+	beaconClient, err := beacon.NewBeaconClient(cliCtx.Context, cfg.BeaconConfig, l, beacon.NoopMetrics)
+	if err != nil {
+		return err
+	}
+	blocks, ok := beaconClient.(client.SignedBeaconBlockProvider)
+	if !ok {
+		return fmt.Errorf("beacon client does not provide blocks")
+	}
+	genesisValidatorsRoot, err := importGenesisValidatorsRoot(cliCtx.Context, cfg, beaconClient)
+	if err != nil {
+		return err
+	}
+
+	storageClient, err := storage.NewStorage(cfg.StorageConfig, l)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(cfg.Input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := bundle.Import(cliCtx.Context, f, storageClient, blocks, genesisValidatorsRoot, l)
+	if err != nil {
+		return fmt.Errorf("failed to import bundle after %d blocks: %w", result.Imported, err)
+	}
+
+	l.Info("imported bundle", "input", cfg.Input, "imported", result.Imported, "skipped", result.Skipped)
+	return nil
+}
+
+// importGenesisValidatorsRoot returns the genesis validators root of the chain to import into, either as configured or
+// as fetched from the beacon node.
+func importGenesisValidatorsRoot(ctx context.Context, cfg flags.ImportConfig, beaconClient beacon.Client) (common.Hash, error) {
+	if cfg.GenesisValidatorsRoot != "" {
+		return common.HexToHash(cfg.GenesisValidatorsRoot), nil
+	}
+
+	genesisProvider, ok := beaconClient.(client.GenesisProvider)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("beacon client does not provide genesis")
+	}
+	genesis, err := genesisProvider.Genesis(ctx, &api.GenesisOpts{})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to fetch genesis: %w", err)
+	}
+	return common.Hash(genesis.Data.GenesisValidatorsRoot), nil
+}
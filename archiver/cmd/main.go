@@ -33,6 +33,7 @@ func main() {
 	app.Usage = "Archiver service for Ethereum blobs"
 	app.Description = "Service for fetching blobs and archiving them to a datastore"
 	app.Action = cliapp.LifecycleCmd(Main())
+	app.Commands = []*cli.Command{exportCommand, importCommand}
 
 	err := app.Run(os.Args)
 	if err != nil {
@@ -56,7 +57,7 @@ func Main() cliapp.LifecycleAction {
 
 		m := metrics.NewMetrics()
 
-		beaconClient, err := beacon.NewBeaconClient(context.Background(), cfg.BeaconConfig)
+		beaconClient, err := beacon.NewBeaconClient(context.Background(), cfg.BeaconConfig, l, beacon.NewMetrics(m.Registry(), metrics.MetricsNamespace))
 		if err != nil {
 			return nil, err
 		}
@@ -72,7 +73,10 @@ func Main() cliapp.LifecycleAction {
 			return nil, fmt.Errorf("failed to initialize archiver: %w", err)
 		}
 
-		api := service.NewAPI(m, l, archiver)
+		api, err := service.NewAPI(m, l, archiver)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize archiver API: %w", err)
+		}
 
 		return service.NewService(l, cfg, api, archiver, m)
 	}
@@ -17,9 +17,33 @@ type ArchiverConfig struct {
 	MetricsConfig opmetrics.CLIConfig
 	BeaconConfig  common.BeaconConfig
 	StorageConfig common.StorageConfig
+	TLSConfig     common.TLSConfig
 	PollInterval  time.Duration
 	OriginBlock   geth.Hash
 	ListenAddr    string
+	Standby       bool
+	EventStream   bool
+	FinalizedOnly bool
+	// ConsensusQuorum is the number of beacon nodes that must agree on blob sidecars, or 0 to trust a single node.
+	ConsensusQuorum int
+	// OrphanRetention is how long orphaned blobs are kept, or forever if it is 0.
+	OrphanRetention time.Duration
+	// CompactionInterval is how often finalized blocks are packed into segment files, see StorageConfig.SegmentSlots.
+	CompactionInterval time.Duration
+
+	BeaconRequestsPerSecond float64
+	BeaconMaxInFlight       int
+
+	BackfillWorkers   int
+	BackfillChunkSize uint64
+	BackfillPeerURL   string
+
+	// AdminTokensFile holds the bearer tokens that authenticate clients of the admin endpoints.
+	AdminTokensFile string
+	// AdminRequireClientCert restricts the admin endpoints to clients with a verified certificate, optionally only to
+	// the common names in AdminClientNames (comma-separated).
+	AdminRequireClientCert bool
+	AdminClientNames       string
 }
 
 func (c ArchiverConfig) Check() error {
@@ -31,6 +55,10 @@ func (c ArchiverConfig) Check() error {
 		return err
 	}
 
+	if err := c.TLSConfig.Check(); err != nil {
+		return err
+	}
+
 	if c.PollInterval == 0 {
 		return fmt.Errorf("archiver poll interval must be set")
 	}
@@ -43,18 +71,179 @@ func (c ArchiverConfig) Check() error {
 		return fmt.Errorf("archiver listen address must be set")
 	}
 
+	if c.ConsensusQuorum < 0 {
+		return fmt.Errorf("archiver consensus quorum must not be negative")
+	}
+
+	if c.ConsensusQuorum > len(c.BeaconConfig.Endpoints()) {
+		return fmt.Errorf("archiver consensus quorum of %d requires at least as many beacon nodes", c.ConsensusQuorum)
+	}
+
+	if c.StorageConfig.SegmentSlots > 0 && c.CompactionInterval <= 0 {
+		return fmt.Errorf("archiver compaction interval must be set when segment files are enabled")
+	}
+
+	if c.OrphanRetention < 0 {
+		return fmt.Errorf("archiver orphan retention must not be negative")
+	}
+
+	if c.BeaconRequestsPerSecond < 0 {
+		return fmt.Errorf("archiver beacon requests per second must not be negative")
+	}
+
+	if c.BeaconMaxInFlight < 0 {
+		return fmt.Errorf("archiver beacon max in flight must not be negative")
+	}
+
+	if c.BackfillWorkers < 1 {
+		return fmt.Errorf("archiver backfill workers must be at least 1")
+	}
+
+	if c.BackfillChunkSize == 0 {
+		return fmt.Errorf("archiver backfill chunk size must be set")
+	}
+
+	if c.AdminRequireClientCert && c.TLSConfig.ClientCAFile == "" {
+		return fmt.Errorf("archiver admin client certs require a tls client ca file")
+	}
+
+	if c.AdminClientNames != "" && !c.AdminRequireClientCert {
+		return fmt.Errorf("archiver admin client names require admin client certs to be required")
+	}
+
 	return nil
 }
 
+// AdminClients returns the common names of the client certificates allowed to use the admin endpoints, or nil if any
+// verified client certificate is allowed.
+func (c ArchiverConfig) AdminClients() []string {
+	if c.AdminClientNames == "" {
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(c.AdminClientNames, ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
 func ReadConfig(cliCtx *cli.Context) ArchiverConfig {
 	pollInterval, _ := time.ParseDuration(cliCtx.String(ArchiverPollIntervalFlag.Name))
+	orphanRetention, _ := time.ParseDuration(cliCtx.String(ArchiverOrphanRetentionFlag.Name))
+	compactionInterval, _ := time.ParseDuration(cliCtx.String(ArchiverCompactionIntervalFlag.Name))
 	return ArchiverConfig{
 		LogConfig:     oplog.ReadCLIConfig(cliCtx),
 		MetricsConfig: opmetrics.ReadCLIConfig(cliCtx),
 		BeaconConfig:  common.NewBeaconConfig(cliCtx),
 		StorageConfig: common.NewStorageConfig(cliCtx),
+		TLSConfig:     common.NewTLSConfig(cliCtx),
 		PollInterval:  pollInterval,
 		OriginBlock:   geth.HexToHash(strings.Trim(cliCtx.String(ArchiverOriginBlock.Name), "\"")),
 		ListenAddr:    cliCtx.String(ArchiverListenAddrFlag.Name),
+		Standby:       cliCtx.Bool(ArchiverStandbyFlag.Name),
+		EventStream:   cliCtx.Bool(ArchiverEventStreamFlag.Name),
+		FinalizedOnly: cliCtx.Bool(ArchiverFinalizedOnlyFlag.Name),
+
+		ConsensusQuorum: cliCtx.Int(ArchiverConsensusQuorumFlag.Name),
+
+		OrphanRetention:    orphanRetention,
+		CompactionInterval: compactionInterval,
+
+		BeaconRequestsPerSecond: cliCtx.Float64(ArchiverBeaconRequestsPerSecondFlag.Name),
+		BeaconMaxInFlight:       cliCtx.Int(ArchiverBeaconMaxInFlightFlag.Name),
+
+		BackfillWorkers:   cliCtx.Int(ArchiverBackfillWorkersFlag.Name),
+		BackfillChunkSize: cliCtx.Uint64(ArchiverBackfillChunkSizeFlag.Name),
+		BackfillPeerURL:   strings.TrimSuffix(cliCtx.String(ArchiverBackfillPeerURLFlag.Name), "/"),
+
+		AdminTokensFile:        cliCtx.String(ArchiverAdminTokensFileFlag.Name),
+		AdminRequireClientCert: cliCtx.Bool(ArchiverAdminRequireClientCertFlag.Name),
+		AdminClientNames:       cliCtx.String(ArchiverAdminClientNamesFlag.Name),
+	}
+}
+
+// ExportConfig configures the export of a slot range to a bundle.
+type ExportConfig struct {
+	LogConfig     oplog.CLIConfig
+	BeaconConfig  common.BeaconConfig
+	StorageConfig common.StorageConfig
+	FromSlot      uint64
+	ToSlot        uint64
+	Output        string
+}
+
+func (c ExportConfig) Check() error {
+	if err := c.StorageConfig.Check(); err != nil {
+		return err
+	}
+
+	if err := c.BeaconConfig.Check(); err != nil {
+		return err
+	}
+
+	if c.FromSlot > c.ToSlot {
+		return fmt.Errorf("from slot %d is after to slot %d", c.FromSlot, c.ToSlot)
+	}
+
+	if c.Output == "" {
+		return fmt.Errorf("output must be set")
+	}
+
+	return nil
+}
+
+func ReadExportConfig(cliCtx *cli.Context) ExportConfig {
+	return ExportConfig{
+		LogConfig:     oplog.ReadCLIConfig(cliCtx),
+		BeaconConfig:  common.NewBeaconConfig(cliCtx),
+		StorageConfig: common.NewStorageConfig(cliCtx),
+		FromSlot:      cliCtx.Uint64(ExportFromSlotFlag.Name),
+		ToSlot:        cliCtx.Uint64(ExportToSlotFlag.Name),
+		Output:        cliCtx.String(ExportOutputFlag.Name),
+	}
+}
+
+// ImportConfig configures the import of a bundle.
+type ImportConfig struct {
+	LogConfig     oplog.CLIConfig
+	BeaconConfig  common.BeaconConfig
+	StorageConfig common.StorageConfig
+	Input         string
+	// GenesisValidatorsRoot identifies the chain of the data store. If it is not set, it is fetched from the beacon node.
+	GenesisValidatorsRoot string
+}
+
+func (c ImportConfig) Check() error {
+	if err := c.StorageConfig.Check(); err != nil {
+		return err
+	}
+
+	// The beacon node is required even with a genesis validators root, as the blobs are checked against their blocks
+	if err := c.BeaconConfig.Check(); err != nil {
+		return err
+	}
+
+	if c.GenesisValidatorsRoot != "" {
+		var root geth.Hash
+		if err := root.UnmarshalText([]byte(c.GenesisValidatorsRoot)); err != nil {
+			return fmt.Errorf("invalid genesis validators root %q: %w", c.GenesisValidatorsRoot, err)
+		}
+	}
+
+	if c.Input == "" {
+		return fmt.Errorf("input must be set")
+	}
+
+	return nil
+}
+
+func ReadImportConfig(cliCtx *cli.Context) ImportConfig {
+	return ImportConfig{
+		LogConfig:             oplog.ReadCLIConfig(cliCtx),
+		BeaconConfig:          common.NewBeaconConfig(cliCtx),
+		StorageConfig:         common.NewStorageConfig(cliCtx),
+		Input:                 cliCtx.String(ImportInputFlag.Name),
+		GenesisValidatorsRoot: cliCtx.String(ImportGenesisValidatorsRootFlag.Name),
 	}
 }
@@ -17,11 +17,12 @@ var (
 		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ARCHIVER_POLL_INTERVAL"),
 		Value:   "6s",
 	}
+	// ArchiverOriginBlock is not marked as required, as the export and import commands do not need it. The archiver
+	// config checks it instead.
 	ArchiverOriginBlock = &cli.StringFlag{
-		Name:     "archiver-origin-block",
-		Usage:    "The latest block hash that the archiver will walk back to",
-		Required: true,
-		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "ORIGIN_BLOCK"),
+		Name:    "archiver-origin-block",
+		Usage:   "The latest block hash that the archiver will walk back to",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ORIGIN_BLOCK"),
 	}
 	ArchiverListenAddrFlag = &cli.StringFlag{
 		Name:    "archiver-listen-address",
@@ -29,13 +30,126 @@ var (
 		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "LISTEN_ADDRESS"),
 		Value:   "0.0.0.0:8000",
 	}
+	ArchiverStandbyFlag = &cli.BoolFlag{
+		Name:    "archiver-standby",
+		Usage:   "Whether to track the head without writing while another archiver holds the storage lock",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "STANDBY"),
+		Value:   false,
+	}
+	ArchiverEventStreamFlag = &cli.BoolFlag{
+		Name:    "archiver-event-stream",
+		Usage:   "Whether to subscribe to the beacon node event stream to archive new blocks as soon as they are imported. The archiver polls while the stream is unavailable",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "EVENT_STREAM"),
+		Value:   true,
+	}
+	ArchiverFinalizedOnlyFlag = &cli.BoolFlag{
+		Name:    "archiver-finalized-only",
+		Usage:   "Whether to only archive finalized blocks, following the finalized checkpoint instead of the head",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "FINALIZED_ONLY"),
+		Value:   false,
+	}
+	ArchiverConsensusQuorumFlag = &cli.IntFlag{
+		Name:    "archiver-consensus-quorum",
+		Usage:   "The number of beacon nodes that must return byte-identical blob sidecars before they are stored, 0 to trust the healthiest beacon node. Blob sidecars are fetched from every configured beacon node",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "CONSENSUS_QUORUM"),
+		Value:   0,
+	}
+	ArchiverOrphanRetentionFlag = &cli.StringFlag{
+		Name:    "archiver-orphan-retention",
+		Usage:   "How long the blobs of orphaned blocks are kept in storage, 0 to keep them forever",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ORPHAN_RETENTION"),
+		Value:   "0",
+	}
+	ArchiverCompactionIntervalFlag = &cli.StringFlag{
+		Name:    "archiver-compaction-interval",
+		Usage:   "The interval at which finalized blocks are packed into segment files, when segment files are enabled",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "COMPACTION_INTERVAL"),
+		Value:   "10m",
+	}
+	ArchiverBeaconRequestsPerSecondFlag = &cli.Float64Flag{
+		Name:    "archiver-beacon-requests-per-second",
+		Usage:   "The maximum number of beacon node requests per second, shared by live tracking, rearchive jobs and the backfill in that order of priority, 0 for no limit. The limit is lowered temporarily when the beacon node responds with 429 or 503",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BEACON_REQUESTS_PER_SECOND"),
+		Value:   0,
+	}
+	ArchiverBeaconMaxInFlightFlag = &cli.IntFlag{
+		Name:    "archiver-beacon-max-in-flight",
+		Usage:   "The maximum number of concurrent beacon node requests, 0 for no limit",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BEACON_MAX_IN_FLIGHT"),
+		Value:   0,
+	}
+	ArchiverBackfillWorkersFlag = &cli.IntFlag{
+		Name:    "archiver-backfill-workers",
+		Usage:   "The number of workers that backfill concurrently. With more than one worker the slot range of each backfill is split into chunks that are filled in parallel",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BACKFILL_WORKERS"),
+		Value:   1,
+	}
+	ArchiverBackfillChunkSizeFlag = &cli.Uint64Flag{
+		Name:    "archiver-backfill-chunk-size",
+		Usage:   "The number of slots in each chunk of a parallel backfill",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BACKFILL_CHUNK_SIZE"),
+		Value:   1024,
+	}
+	ArchiverBackfillPeerURLFlag = &cli.StringFlag{
+		Name:    "archiver-backfill-peer-url",
+		Usage:   "The URL of another blob API to backfill blob sidecars from when the beacon node no longer has them. The sidecars are verified against the block and their KZG commitments before they are stored",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BACKFILL_PEER_URL"),
+	}
+	ArchiverAdminTokensFileFlag = &cli.StringFlag{
+		Name:    "archiver-admin-tokens-file",
+		Usage:   "Path to a file of bearer tokens for the admin endpoints, one '<name> <token>' per line. If set, the admin endpoints require a token or, with archiver-admin-require-client-cert, a verified client certificate",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADMIN_TOKENS_FILE"),
+	}
+	ArchiverAdminRequireClientCertFlag = &cli.BoolFlag{
+		Name:    "archiver-admin-require-client-cert",
+		Usage:   "Whether the admin endpoints, both those that change the archiver, e.g. /rearchive, and those that report its state, e.g. /status, require a verified client certificate. Requires tls-client-ca-file",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADMIN_REQUIRE_CLIENT_CERT"),
+		Value:   false,
+	}
+	ArchiverAdminClientNamesFlag = &cli.StringFlag{
+		Name:    "archiver-admin-client-names",
+		Usage:   "Comma-separated common names of the client certificates allowed to use the admin endpoints, or empty to allow any verified client certificate",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADMIN_CLIENT_NAMES"),
+	}
+)
+
+// Flags of the export and import commands. The beacon node and storage are configured by the flags of the archiver.
+var (
+	ExportFromSlotFlag = &cli.Uint64Flag{
+		Name:     "from-slot",
+		Usage:    "The first slot to export",
+		Required: true,
+	}
+	ExportToSlotFlag = &cli.Uint64Flag{
+		Name:     "to-slot",
+		Usage:    "The last slot to export",
+		Required: true,
+	}
+	ExportOutputFlag = &cli.StringFlag{
+		Name:     "output",
+		Usage:    "The path of the bundle file to write",
+		Required: true,
+	}
+	ImportInputFlag = &cli.StringFlag{
+		Name:     "input",
+		Usage:    "The path of the bundle file to import",
+		Required: true,
+	}
+	ImportGenesisValidatorsRootFlag = &cli.StringFlag{
+		Name:  "genesis-validators-root",
+		Usage: "The genesis validators root of the chain of the data store. Fetched from the beacon node if not set",
+	}
 )
 
 func init() {
 	Flags = append(Flags, common.CLIFlags(EnvVarPrefix)...)
+	Flags = append(Flags, common.TLSFlags(EnvVarPrefix)...)
 	Flags = append(Flags, opmetrics.CLIFlags(EnvVarPrefix)...)
 	Flags = append(Flags, oplog.CLIFlags(EnvVarPrefix)...)
-	Flags = append(Flags, ArchiverPollIntervalFlag, ArchiverOriginBlock, ArchiverListenAddrFlag)
+	Flags = append(Flags, ArchiverPollIntervalFlag, ArchiverOriginBlock, ArchiverListenAddrFlag, ArchiverStandbyFlag, ArchiverEventStreamFlag, ArchiverFinalizedOnlyFlag, ArchiverConsensusQuorumFlag, ArchiverOrphanRetentionFlag, ArchiverCompactionIntervalFlag)
+	Flags = append(Flags, ArchiverBeaconRequestsPerSecondFlag, ArchiverBeaconMaxInFlightFlag)
+	Flags = append(Flags, ArchiverBackfillWorkersFlag, ArchiverBackfillChunkSizeFlag, ArchiverBackfillPeerURLFlag)
+	Flags = append(Flags, ArchiverAdminTokensFileFlag, ArchiverAdminRequireClientCertFlag, ArchiverAdminClientNamesFlag)
 }
 
 // Flags contains the list of configuration options available to the binary.
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/ethereum-optimism/optimism/op-service/metrics"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,11 +22,45 @@ type Metricer interface {
 	Registry() *prometheus.Registry
 	RecordProcessedBlock(source BlockSource)
 	RecordStoredBlobs(count int)
+	RecordStandby(standby bool)
+	RecordStandbyLag(slots uint64)
+	RecordBackfillProcesses(count int)
+	RecordBackfillProgress(start string, remaining uint64, blocksPerSecond float64, lastProgress time.Time)
+	RemoveBackfillProgress(start string)
+	RecordEventStreamConnected(connected bool)
+	RecordOrphanedBlock()
+	RecordStoredOrphans(count int)
+	RecordFinalityLag(slots uint64)
+	RecordConsensusDisagreement(node string)
+	RecordParkedBlocks(count int)
+	RecordBeaconRequestsInFlight(count int)
+	RecordBeaconRequestsQueued(priority string, count int)
+	RecordBeaconThrottled(requestsPerSecond float64)
+	RecordPeerSidecars(valid bool)
+	RecordSealedSegments(count int)
 }
 
 type metricsRecorder struct {
 	blockProcessedCounter *prometheus.CounterVec
 	blobsStored           prometheus.Counter
+	standby               prometheus.Gauge
+	standbyLag            prometheus.Gauge
+	backfillProcesses     prometheus.Gauge
+	backfillRemaining     *prometheus.GaugeVec
+	backfillThroughput    *prometheus.GaugeVec
+	backfillLastProgress  *prometheus.GaugeVec
+	eventStreamConnected  prometheus.Gauge
+	blocksOrphaned        prometheus.Counter
+	storedOrphans         prometheus.Gauge
+	finalityLag           prometheus.Gauge
+	consensusDisagreement *prometheus.CounterVec
+	parkedBlocks          prometheus.Gauge
+	beaconInFlight        prometheus.Gauge
+	beaconQueued          *prometheus.GaugeVec
+	beaconThrottled       prometheus.Counter
+	beaconRateLimit       prometheus.Gauge
+	peerSidecars          *prometheus.CounterVec
+	sealedSegments        prometheus.Gauge
 	registry              *prometheus.Registry
 }
 
@@ -43,6 +79,96 @@ func NewMetrics() Metricer {
 			Name:      "blobs_stored",
 			Help:      "number of blobs stored",
 		}),
+		standby: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "standby",
+			Help:      "1 if the archiver is in standby waiting for the storage lock, 0 otherwise",
+		}),
+		standbyLag: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "standby_lag_slots",
+			Help:      "number of slots the head archived by the leader is behind the head seen by the standby",
+		}),
+		backfillProcesses: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "backfill_processes",
+			Help:      "number of backfill processes that have not reached the origin block",
+		}),
+		backfillRemaining: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "backfill_remaining_blocks",
+			Help:      "number of slots a backfill process still has to walk to reach the origin block",
+		}, []string{"start_hash"}),
+		backfillThroughput: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "backfill_blocks_per_second",
+			Help:      "average number of slots per second a backfill process has walked since it was started",
+		}, []string{"start_hash"}),
+		backfillLastProgress: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "backfill_last_progress_timestamp",
+			Help:      "unix timestamp of the last time a backfill process moved closer to the origin block",
+		}, []string{"start_hash"}),
+		eventStreamConnected: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "event_stream_connected",
+			Help:      "1 if new blocks are received from the beacon node event stream, 0 if the archiver is polling",
+		}),
+		blocksOrphaned: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "blocks_orphaned",
+			Help:      "number of archived blocks that were moved to the orphan namespace",
+		}),
+		storedOrphans: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "stored_orphans",
+			Help:      "number of orphaned blocks kept in storage until their retention expires",
+		}),
+		finalityLag: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "finality_lag_slots",
+			Help:      "number of slots the latest finalized block archived in finalized-only mode is behind the head",
+		}),
+		consensusDisagreement: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "consensus_disagreements",
+			Help:      "number of times a beacon node returned blob sidecars that differ from the ones returned by most beacon nodes",
+		}, []string{"node"}),
+		parkedBlocks: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "parked_blocks",
+			Help:      "number of blocks not stored because the beacon nodes disagree on their blob sidecars",
+		}),
+		beaconInFlight: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "beacon_requests_in_flight",
+			Help:      "number of beacon node requests admitted by the scheduler that have not completed",
+		}),
+		beaconQueued: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "beacon_requests_queued",
+			Help:      "number of beacon node requests waiting to be admitted by the scheduler",
+		}, []string{"priority"}),
+		beaconThrottled: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "beacon_throttled",
+			Help:      "number of times the scheduler backed off because the beacon node responded with 429 or 503",
+		}),
+		beaconRateLimit: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "beacon_rate_limit",
+			Help:      "beacon node requests per second allowed by the scheduler after its latest back off, 0 without a rate limit",
+		}),
+		peerSidecars: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "peer_sidecars",
+			Help:      "number of blocks whose blob sidecars were fetched from the peer archiver, by verification result",
+		}, []string{"result"}),
+		sealedSegments: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "sealed_segments",
+			Help:      "number of segment files that finalized blocks are packed into",
+		}),
 	}
 }
 
@@ -57,3 +183,84 @@ func (m *metricsRecorder) RecordStoredBlobs(count int) {
 func (m *metricsRecorder) RecordProcessedBlock(source BlockSource) {
 	m.blockProcessedCounter.WithLabelValues(string(source)).Inc()
 }
+
+func (m *metricsRecorder) RecordStandby(standby bool) {
+	if standby {
+		m.standby.Set(1)
+	} else {
+		m.standby.Set(0)
+	}
+}
+
+func (m *metricsRecorder) RecordStandbyLag(slots uint64) {
+	m.standbyLag.Set(float64(slots))
+}
+
+func (m *metricsRecorder) RecordBackfillProcesses(count int) {
+	m.backfillProcesses.Set(float64(count))
+}
+
+func (m *metricsRecorder) RecordBackfillProgress(start string, remaining uint64, blocksPerSecond float64, lastProgress time.Time) {
+	m.backfillRemaining.WithLabelValues(start).Set(float64(remaining))
+	m.backfillThroughput.WithLabelValues(start).Set(blocksPerSecond)
+	m.backfillLastProgress.WithLabelValues(start).Set(float64(lastProgress.Unix()))
+}
+
+func (m *metricsRecorder) RemoveBackfillProgress(start string) {
+	m.backfillRemaining.DeleteLabelValues(start)
+	m.backfillThroughput.DeleteLabelValues(start)
+	m.backfillLastProgress.DeleteLabelValues(start)
+}
+
+func (m *metricsRecorder) RecordEventStreamConnected(connected bool) {
+	if connected {
+		m.eventStreamConnected.Set(1)
+	} else {
+		m.eventStreamConnected.Set(0)
+	}
+}
+
+func (m *metricsRecorder) RecordOrphanedBlock() {
+	m.blocksOrphaned.Inc()
+}
+
+func (m *metricsRecorder) RecordStoredOrphans(count int) {
+	m.storedOrphans.Set(float64(count))
+}
+
+func (m *metricsRecorder) RecordFinalityLag(slots uint64) {
+	m.finalityLag.Set(float64(slots))
+}
+
+func (m *metricsRecorder) RecordConsensusDisagreement(node string) {
+	m.consensusDisagreement.WithLabelValues(node).Inc()
+}
+
+func (m *metricsRecorder) RecordParkedBlocks(count int) {
+	m.parkedBlocks.Set(float64(count))
+}
+
+func (m *metricsRecorder) RecordBeaconRequestsInFlight(count int) {
+	m.beaconInFlight.Set(float64(count))
+}
+
+func (m *metricsRecorder) RecordBeaconRequestsQueued(priority string, count int) {
+	m.beaconQueued.WithLabelValues(priority).Set(float64(count))
+}
+
+func (m *metricsRecorder) RecordBeaconThrottled(requestsPerSecond float64) {
+	m.beaconThrottled.Inc()
+	m.beaconRateLimit.Set(requestsPerSecond)
+}
+
+func (m *metricsRecorder) RecordPeerSidecars(valid bool) {
+	result := "valid"
+	if !valid {
+		result = "invalid"
+	}
+	m.peerSidecars.WithLabelValues(result).Inc()
+}
+
+func (m *metricsRecorder) RecordSealedSegments(count int) {
+	m.sealedSegments.Set(float64(count))
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/tlsutil"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// auditDayFormat is the format of the days that audit records are stored by.
+	auditDayFormat = "2006-01-02"
+	// maxAuditDays is the maximum number of days of audit records that can be requested at once.
+	maxAuditDays = 31
+
+	adminAuthNone       = "none"
+	adminAuthToken      = "token"
+	adminAuthClientCert = "client_cert"
+)
+
+// adminToken is a bearer token that authenticates a client of the admin endpoints.
+type adminToken struct {
+	name  string
+	token string
+}
+
+// adminIdentity is the authenticated client of an admin request.
+type adminIdentity struct {
+	name string
+	auth string
+}
+
+type adminIdentityKey struct{}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type auditResponse struct {
+	Error   string                `json:"error,omitempty"`
+	Records []storage.AuditRecord `json:"records"`
+}
+
+// loadAdminTokens reads a tokens file, holding one '<name> <token>' per line. Empty lines and lines starting with # are
+// ignored.
+func loadAdminTokens(file string) ([]adminToken, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tokens []adminToken
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected '<name> <token>'", line)
+		}
+		for _, t := range tokens {
+			if t.token == fields[1] {
+				return nil, fmt.Errorf("line %d: duplicate token", line)
+			}
+		}
+		tokens = append(tokens, adminToken{name: fields[0], token: fields[1]})
+	}
+	return tokens, scanner.Err()
+}
+
+// lookupToken returns the name of the given bearer token. All tokens are compared in constant time.
+func (a *API) lookupToken(token string) (string, bool) {
+	var name string
+	for _, t := range a.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(t.token), []byte(token)) == 1 {
+			name = t.name
+		}
+	}
+	return name, name != ""
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: message})
+}
+
+// authenticateAdmin is a middleware that authenticates the clients of the admin endpoints, by a bearer token in the
+// Authorization header or by a verified client certificate. Clients that are not authenticated are rejected if a tokens
+// file is configured or client certificates are required, and are recorded as anonymous otherwise.
+func (a *API) authenticateAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := adminIdentity{auth: adminAuthNone}
+		certName, hasCert := tlsutil.ClientName(r)
+
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && a.requireAdminToken {
+			name, ok := a.lookupToken(token)
+			if !ok {
+				writeErrorResponse(w, http.StatusUnauthorized, "invalid bearer token")
+				return
+			}
+			identity = adminIdentity{name: name, auth: adminAuthToken}
+		} else if hasCert {
+			if len(a.adminClients) > 0 && !slices.Contains(a.adminClients, certName) {
+				writeErrorResponse(w, http.StatusForbidden, fmt.Sprintf("client %q is not allowed", certName))
+				return
+			}
+			identity = adminIdentity{name: certName, auth: adminAuthClientCert}
+		} else if a.requireAdminToken && a.requireAdminCert {
+			writeErrorResponse(w, http.StatusUnauthorized, "a bearer token or a verified client certificate is required")
+			return
+		} else if a.requireAdminToken {
+			writeErrorResponse(w, http.StatusUnauthorized, "a bearer token is required")
+			return
+		} else if a.requireAdminCert {
+			writeErrorResponse(w, http.StatusUnauthorized, "a verified client certificate is required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminIdentityKey{}, identity)))
+	})
+}
+
+// auditAdmin is a middleware that records every request with the client that made it, its parameters and its result in
+// the audit log. It must run after authenticateAdmin.
+func (a *API) auditAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := r.Context().Value(adminIdentityKey{}).(adminIdentity)
+
+		var body []byte
+		if r.Body != nil {
+			var err error
+			body, err = io.ReadAll(r.Body)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		var response bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&response)
+		next.ServeHTTP(ww, r)
+
+		rctx := chi.RouteContext(r.Context())
+		record := storage.AuditRecord{
+			Time:   time.Now().Unix(),
+			Actor:  identity.name,
+			Auth:   identity.auth,
+			Action: r.Method + " " + rctx.RoutePattern(),
+			Status: ww.Status(),
+		}
+		params := make(map[string]string)
+		for i, key := range rctx.URLParams.Keys {
+			params[key] = rctx.URLParams.Values[i]
+		}
+		for key, values := range r.URL.Query() {
+			params[key] = values[0]
+		}
+		if len(params) > 0 {
+			record.Params = params
+		}
+		if body = bytes.TrimSpace(body); len(body) > 0 && json.Valid(body) {
+			record.Body = body
+		}
+		if res := bytes.TrimSpace(response.Bytes()); len(res) > 0 && json.Valid(res) {
+			record.Response = res
+		}
+
+		a.recordAudit(context.WithoutCancel(r.Context()), record)
+	})
+}
+
+// recordAudit adds the record to the audit log of its day. Every record is written as an object of its own, so that
+// records of archivers sharing the storage are not lost. Failures are logged, as the action was already taken.
+func (a *API) recordAudit(ctx context.Context, record storage.AuditRecord) {
+	a.logger.Info("admin action", "actor", record.Actor, "auth", record.Auth, "action", record.Action, "params", record.Params, "status", record.Status)
+
+	day := time.Unix(record.Time, 0).UTC().Format(auditDayFormat)
+	if err := a.archiver.dataStoreClient.WriteAuditRecord(ctx, day, record); err != nil {
+		a.logger.Error("Failed to write audit log", "err", err, "day", day)
+	}
+}
+
+// auditHandler returns the audit records between the days given by the from and to params (inclusive, formatted as
+// YYYY-MM-DD, today by default), optionally only those of the client given by the actor param.
+func (a *API) auditHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	today := time.Now().UTC().Format(auditDayFormat)
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" {
+		from = today
+	}
+	if to == "" {
+		to = today
+	}
+
+	fromDay, err := time.Parse(auditDayFormat, from)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid from param: \"%s\"", from))
+		return
+	}
+	toDay, err := time.Parse(auditDayFormat, to)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid to param: \"%s\"", to))
+		return
+	}
+	if toDay.Before(fromDay) {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid range: from %s to %s", from, to))
+		return
+	}
+	if toDay.Sub(fromDay) >= maxAuditDays*24*time.Hour {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("at most %d days can be requested at once", maxAuditDays))
+		return
+	}
+
+	records := []storage.AuditRecord{}
+	for day := fromDay; !day.After(toDay); day = day.AddDate(0, 0, 1) {
+		auditLog, err := a.archiver.dataStoreClient.ReadAuditLog(r.Context(), day.Format(auditDayFormat))
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			a.logger.Error("Failed to read audit log", "err", err, "day", day.Format(auditDayFormat))
+			writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		for _, record := range auditLog {
+			if actor := query.Get("actor"); actor == "" || record.Actor == actor {
+				records = append(records, record)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(auditResponse{Records: records})
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
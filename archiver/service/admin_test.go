@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/archiver/flags"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/storage/storagetest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAdminAPI(t *testing.T, cfg flags.ArchiverConfig) (*API, *storagetest.TestFileStorage) {
+	logger := testlog.Logger(t, log.LvlInfo)
+	m := metrics.NewMetrics()
+	fs := storagetest.NewTestFileStorage(t, logger)
+	cfg.PollInterval = 10 * time.Second
+	archiver, err := NewArchiver(logger, cfg, fs, nil, m)
+	require.NoError(t, err)
+	a, err := NewAPI(m, logger, archiver)
+	require.NoError(t, err)
+	return a, fs
+}
+
+func writeAdminTokens(t *testing.T, tokens string) string {
+	file := path.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(file, []byte(tokens), 0600))
+	return file
+}
+
+// adminRequest makes a request with the given bearer token and client certificate common name, if they are set.
+func adminRequest(a *API, method string, target string, token string, clientName string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	if clientName != "" {
+		request.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: clientName}}}},
+		}
+	}
+	response := httptest.NewRecorder()
+	a.router.ServeHTTP(response, request)
+	return response
+}
+
+func TestLoadAdminTokens(t *testing.T) {
+	tokens, err := loadAdminTokens(writeAdminTokens(t, "# comment\n\nalice token-a\nbob token-b\n"))
+	require.NoError(t, err)
+	require.Equal(t, []adminToken{{name: "alice", token: "token-a"}, {name: "bob", token: "token-b"}}, tokens)
+
+	_, err = loadAdminTokens(writeAdminTokens(t, "alice token-a 1\n"))
+	require.ErrorContains(t, err, "line 1: expected '<name> <token>'")
+	_, err = loadAdminTokens(writeAdminTokens(t, "alice token-a\nbob token-a\n"))
+	require.ErrorContains(t, err, "line 2: duplicate token")
+	_, err = loadAdminTokens(path.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+func TestAdminAuth(t *testing.T) {
+	a, _ := setupAdminAPI(t, flags.ArchiverConfig{
+		AdminTokensFile:        writeAdminTokens(t, "alice token-a\n"),
+		AdminRequireClientCert: true,
+		AdminClientNames:       "operator",
+	})
+
+	tests := []struct {
+		name           string
+		token          string
+		clientName     string
+		expectedStatus int
+		error          string
+	}{
+		{
+			name:           "should reject requests without credentials",
+			expectedStatus: 401,
+			error:          "a bearer token or a verified client certificate is required",
+		},
+		{
+			name:           "should reject an invalid token",
+			token:          "token-b",
+			clientName:     "operator",
+			expectedStatus: 401,
+			error:          "invalid bearer token",
+		},
+		{
+			name:           "should reject a client that is not allowed",
+			clientName:     "intruder",
+			expectedStatus: 403,
+			error:          "client \"intruder\" is not allowed",
+		},
+		{
+			name:           "should accept a valid token",
+			token:          "token-a",
+			expectedStatus: 200,
+		},
+		{
+			name:           "should accept an allowed client",
+			clientName:     "operator",
+			expectedStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			for _, target := range []string{"/control/live/pause", "/audit"} {
+				method := "POST"
+				if target == "/audit" {
+					method = "GET"
+				}
+				response := adminRequest(a, method, target, test.token, test.clientName)
+				require.Equal(t, test.expectedStatus, response.Code)
+
+				var res errorResponse
+				require.NoError(t, json.NewDecoder(response.Body).Decode(&res))
+				require.Equal(t, test.error, res.Error)
+			}
+		})
+	}
+
+	// Read-only endpoints are restricted as well
+	for _, target := range []string{"/status", "/backfill", "/control", "/jobs", "/jobs/unknown"} {
+		require.Equal(t, 401, adminRequest(a, "GET", target, "", "").Code, target)
+	}
+	require.Equal(t, 200, adminRequest(a, "GET", "/control", "token-a", "").Code)
+
+	// The API cannot be created if the tokens file cannot be loaded
+	logger := testlog.Logger(t, log.LvlInfo)
+	archiver, err := NewArchiver(logger, flags.ArchiverConfig{
+		PollInterval:    10 * time.Second,
+		AdminTokensFile: path.Join(t.TempDir(), "missing"),
+	}, storagetest.NewTestFileStorage(t, logger), nil, metrics.NewMetrics())
+	require.NoError(t, err)
+	_, err = NewAPI(metrics.NewMetrics(), logger, archiver)
+	require.ErrorContains(t, err, "failed to load admin tokens")
+}
+
+func TestAuditLog(t *testing.T) {
+	a, fs := setupAdminAPI(t, flags.ArchiverConfig{AdminTokensFile: writeAdminTokens(t, "alice token-a\nbob token-b\n")})
+
+	require.Equal(t, 200, adminRequest(a, "POST", "/control/live/pause", "token-a", "").Code)
+	require.Equal(t, 503, adminRequest(a, "POST", "/rearchive?from=1&to=2&dry_run=true", "token-b", "").Code)
+	require.Equal(t, 404, adminRequest(a, "DELETE", "/jobs/unknown", "token-a", "").Code)
+	// Rejected requests are not recorded
+	require.Equal(t, 401, adminRequest(a, "POST", "/control/live/resume", "", "").Code)
+
+	readRecords := func(query string) []storage.AuditRecord {
+		response := adminRequest(a, "GET", "/audit"+query, "token-a", "")
+		require.Equal(t, 200, response.Code)
+		var res auditResponse
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&res))
+		for i := range res.Records {
+			require.NotZero(t, res.Records[i].Time)
+			res.Records[i].Time = 0
+		}
+		return res.Records
+	}
+
+	records := readRecords("")
+	require.Len(t, records, 3)
+	require.Equal(t, storage.AuditRecord{
+		Actor:    "alice",
+		Auth:     "token",
+		Action:   "POST /control/live/pause",
+		Status:   200,
+		Response: json.RawMessage(`{"live_tracking_paused":true,"paused_backfills":null}`),
+	}, records[0])
+	require.Equal(t, storage.AuditRecord{
+		Actor:    "bob",
+		Auth:     "token",
+		Action:   "POST /rearchive",
+		Params:   map[string]string{"from": "1", "to": "2", "dry_run": "true"},
+		Status:   503,
+		Response: json.RawMessage(`{"error":"` + ErrNotLeader.Error() + `","dryRun":false,"blockStart":1,"blockEnd":2}`),
+	}, records[1])
+	require.Equal(t, "DELETE /jobs/{id}", records[2].Action)
+	require.Equal(t, map[string]string{"id": "unknown"}, records[2].Params)
+
+	// The records are persisted by day, and can be filtered by actor
+	today := time.Now().UTC().Format(auditDayFormat)
+	stored, err := fs.ReadAuditLog(context.Background(), today)
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+
+	records = readRecords("?actor=bob")
+	require.Len(t, records, 1)
+	require.Equal(t, "POST /rearchive", records[0].Action)
+
+	require.Empty(t, readRecords("?from=2024-01-01&to=2024-01-31"))
+
+	errorTests := []struct {
+		query string
+		error string
+	}{
+		{query: "?from=yesterday", error: "invalid from param: \"yesterday\""},
+		{query: "?from=2024-01-02&to=2024-01-01", error: "invalid range: from 2024-01-02 to 2024-01-01"},
+		{query: "?from=2024-01-01&to=2024-02-01", error: "at most 31 days can be requested at once"},
+	}
+	for _, test := range errorTests {
+		response := adminRequest(a, "GET", "/audit"+test.query, "token-a", "")
+		require.Equal(t, 400, response.Code)
+		var res errorResponse
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&res))
+		require.Equal(t, test.error, res.Error)
+	}
+}
@@ -1,14 +1,20 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	m "github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -16,6 +22,8 @@ import (
 
 const (
 	serverTimeout = 60 * time.Second
+	// maxRearchiveItems is the maximum number of blocks that can be listed in a single rearchive request.
+	maxRearchiveItems = 1000
 )
 
 type API struct {
@@ -23,15 +31,32 @@ type API struct {
 	logger   log.Logger
 	metrics  m.Metricer
 	archiver *Archiver
+
+	adminTokens       []adminToken
+	adminClients      []string
+	requireAdminToken bool
+	requireAdminCert  bool
 }
 
-// NewAPI creates a new Archiver API instance. This API exposes an admin interface to control the archiver.
-func NewAPI(metrics m.Metricer, logger log.Logger, archiver *Archiver) *API {
+// NewAPI creates a new Archiver API instance. This API exposes an admin interface to control the archiver. It returns an
+// error if the admin tokens file is configured but cannot be loaded.
+func NewAPI(metrics m.Metricer, logger log.Logger, archiver *Archiver) (*API, error) {
 	result := &API{
 		router:   chi.NewRouter(),
 		archiver: archiver,
 		logger:   logger,
 		metrics:  metrics,
+
+		adminClients:      archiver.cfg.AdminClients(),
+		requireAdminToken: archiver.cfg.AdminTokensFile != "",
+		requireAdminCert:  archiver.cfg.AdminRequireClientCert,
+	}
+	if result.requireAdminToken {
+		tokens, err := loadAdminTokens(archiver.cfg.AdminTokensFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin tokens from %s: %w", archiver.cfg.AdminTokensFile, err)
+		}
+		result.adminTokens = tokens
 	}
 
 	r := result.router
@@ -46,15 +71,257 @@ func NewAPI(metrics m.Metricer, logger log.Logger, archiver *Archiver) *API {
 	})
 
 	r.Get("/", http.NotFound)
-	r.Post("/rearchive", result.rearchiveBlocks)
 
-	return result
+	// Admin endpoints require authentication if configured, including those that only report the state of the archiver,
+	// as it reveals the backfills, jobs and audited actions. Every request that changes the archiver is audited.
+	r.Group(func(r chi.Router) {
+		r.Use(result.authenticateAdmin)
+		r.Get("/status", result.statusHandler)
+		r.Get("/backfill", result.backfillHandler)
+		r.Get("/control", result.controlHandler)
+		r.Get("/jobs", result.jobsHandler)
+		r.Get("/jobs/{id}", result.jobHandler)
+		r.Get("/audit", result.auditHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(result.auditAdmin)
+			r.Post("/backfill", result.startBackfillHandler)
+			r.Post("/backfill/{start}/pause", result.backfillControlHandler(archiver.PauseBackfill))
+			r.Post("/backfill/{start}/resume", result.backfillControlHandler(archiver.ResumeBackfill))
+			r.Delete("/backfill/{start}", result.backfillControlHandler(archiver.CancelBackfill))
+			r.Post("/control/live/pause", result.liveTrackingHandler(true))
+			r.Post("/control/live/resume", result.liveTrackingHandler(false))
+			r.Post("/rearchive", result.rearchiveBlocks)
+			r.Delete("/jobs/{id}", result.cancelJobHandler)
+		})
+	})
+
+	return result, nil
+}
+
+// statusHandler reports whether the archiver is the leader or in standby, and how far the leader is behind the head.
+func (a *API) statusHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(a.archiver.Status())
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+type backfillResponse struct {
+	Error     string             `json:"error,omitempty"`
+	Processes []BackfillProgress `json:"processes"`
+}
+
+// backfillHandler reports the progress of every backfill process that has not reached the origin block yet.
+func (a *API) backfillHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	processes, err := a.archiver.BackfillProgress(r.Context())
+	if err != nil {
+		a.logger.Error("Failed to read backfill processes", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(backfillResponse{
+			Error:     err.Error(),
+			Processes: []BackfillProgress{},
+		})
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(backfillResponse{Processes: processes})
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+type controlResponse struct {
+	Error string `json:"error,omitempty"`
+	storage.ControlState
+}
+
+type startBackfillResponse struct {
+	Error     string      `json:"error,omitempty"`
+	StartHash common.Hash `json:"start_hash"`
+	StartSlot uint64      `json:"start_slot"`
+}
+
+// writeControlResponse writes the control state, or the error that prevented changing it.
+func (a *API) writeControlResponse(w http.ResponseWriter, state storage.ControlState, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := controlResponse{ControlState: state}
+	if err != nil {
+		response.Error = err.Error()
+		w.WriteHeader(controlErrorStatus(err))
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func controlErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBackfillNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBackfillExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrBackfillNotStarted):
+		return http.StatusServiceUnavailable
+	case isBlockNotFound(err):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// controlHandler reports the runtime controls that are currently in effect.
+func (a *API) controlHandler(w http.ResponseWriter, _ *http.Request) {
+	a.writeControlResponse(w, a.archiver.ControlState(), nil)
+}
+
+// liveTrackingHandler pauses or resumes polling the beacon node for new blocks.
+func (a *API) liveTrackingHandler(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := a.archiver.SetLiveTrackingPaused(r.Context(), paused)
+		if err != nil {
+			a.logger.Error("Failed to update live tracking", "err", err)
+		}
+		a.writeControlResponse(w, state, err)
+	}
+}
+
+// backfillControlHandler applies the given action to the backfill process identified by its start block hash.
+func (a *API) backfillControlHandler(action func(context.Context, common.Hash) (storage.ControlState, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := chi.URLParam(r, "start")
+		var start common.Hash
+		if err := start.UnmarshalText([]byte(param)); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(controlResponse{
+				Error:        fmt.Sprintf("invalid start block hash: \"%s\"", param),
+				ControlState: a.archiver.ControlState(),
+			})
+			return
+		}
+
+		state, err := action(r.Context(), start)
+		if err != nil {
+			a.logger.Error("Failed to update backfill process", "err", err, "startHash", start.String())
+		}
+		a.writeControlResponse(w, state, err)
+	}
+}
+
+// startBackfillHandler starts a backfill process from the block given by the block param, which is either a block root
+// or a slot.
+func (a *API) startBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	blockId, err := toBlockId(r.URL.Query().Get("block"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(startBackfillResponse{
+			Error: fmt.Sprintf("invalid block param: %v", err),
+		})
+		return
+	}
+
+	header, err := a.archiver.StartBackfill(r.Context(), blockId)
+	if err != nil {
+		a.logger.Error("Failed to start backfill", "err", err, "block", blockId)
+		w.WriteHeader(controlErrorStatus(err))
+		_ = json.NewEncoder(w).Encode(startBackfillResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(startBackfillResponse{
+		StartHash: common.Hash(header.Root),
+		StartSlot: uint64(header.Header.Message.Slot),
+	})
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// toBlockId validates that the input is either a block root or a slot.
+func toBlockId(input string) (string, error) {
+	if strings.HasPrefix(input, "0x") {
+		var root common.Hash
+		if err := root.UnmarshalText([]byte(input)); err != nil {
+			return "", fmt.Errorf("invalid block root: \"%s\"", input)
+		}
+		return root.String(), nil
+	}
+
+	slot, err := toSlot(input)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(slot, 10), nil
 }
 
 type rearchiveResponse struct {
 	Error      string `json:"error,omitempty"`
+	JobId      string `json:"jobId,omitempty"`
+	DryRun     bool   `json:"dryRun"`
 	BlockStart uint64 `json:"blockStart"`
 	BlockEnd   uint64 `json:"blockEnd"`
+	Items      int    `json:"items,omitempty"`
+}
+
+// rearchiveRequest lists the blocks to rearchive by their root, their slot or the versioned hash of one of their blobs.
+// Blocks of versioned hashes are searched for between the slots search_from and search_to.
+type rearchiveRequest struct {
+	BlockRoots      []common.Hash `json:"block_roots"`
+	Slots           []uint64      `json:"slots"`
+	VersionedHashes []common.Hash `json:"versioned_hashes"`
+	SearchFrom      *uint64       `json:"search_from"`
+	SearchTo        *uint64       `json:"search_to"`
+	DryRun          bool          `json:"dry_run"`
+}
+
+// toItems validates the request and returns the blocks to rearchive.
+func (req rearchiveRequest) toItems() ([]storage.RearchiveItem, error) {
+	count := len(req.BlockRoots) + len(req.Slots) + len(req.VersionedHashes)
+	if count == 0 {
+		return nil, fmt.Errorf("must provide block_roots, slots or versioned_hashes")
+	}
+	if count > maxRearchiveItems {
+		return nil, fmt.Errorf("too many items: %d, maximum is %d", count, maxRearchiveItems)
+	}
+
+	if len(req.VersionedHashes) > 0 {
+		if req.SearchFrom == nil || req.SearchTo == nil {
+			return nil, fmt.Errorf("must provide search_from and search_to with versioned_hashes")
+		}
+		if *req.SearchFrom > *req.SearchTo {
+			return nil, fmt.Errorf("invalid search range: from %d to %d", *req.SearchFrom, *req.SearchTo)
+		}
+	}
+
+	items := make([]storage.RearchiveItem, 0, count)
+	for i := range req.BlockRoots {
+		items = append(items, storage.RearchiveItem{BlockRoot: &req.BlockRoots[i]})
+	}
+	for i := range req.Slots {
+		items = append(items, storage.RearchiveItem{Slot: &req.Slots[i]})
+	}
+	for i, hash := range req.VersionedHashes {
+		if !kzg4844.IsValidVersionedHash(hash[:]) {
+			return nil, fmt.Errorf("invalid versioned hash: \"%s\"", hash.String())
+		}
+		items = append(items, storage.RearchiveItem{VersionedHash: &req.VersionedHashes[i]})
+	}
+	return items, nil
 }
 
 func toSlot(input string) (uint64, error) {
@@ -68,9 +335,19 @@ func toSlot(input string) (uint64, error) {
 	return res, nil
 }
 
-// rearchiveBlocks rearchives blobs from blocks between the given from and to slots.
-// If any blocks are already archived, they will be overwritten with data from the beacon node.
+// rearchiveBlocks creates a job that rearchives blobs from blocks between the given from and to slots. The job runs in
+// the background, and its progress can be followed with GET /jobs/{id}. If any blocks are already archived, they will
+// be overwritten with data from the beacon node, unless the dry_run param is set. Either way, the job reports which
+// stored blocks are identical to, different from or missing compared to the beacon node.
+//
+// Instead of a range, a JSON body can list the blocks to rearchive, see rearchiveRequest. The job then reports the
+// result of every block, including blocks that are no longer canonical but still known to the beacon node.
 func (a *API) rearchiveBlocks(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		a.rearchiveItems(w, r)
+		return
+	}
+
 	from, err := toSlot(r.URL.Query().Get("from"))
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -97,23 +374,92 @@ func (a *API) rearchiveBlocks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blockStart, blockEnd, err := a.archiver.rearchiveRange(from, to)
+	dryRun := false
+	if param := r.URL.Query().Get("dry_run"); param != "" {
+		dryRun, err = strconv.ParseBool(param)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(rearchiveResponse{
+				Error: fmt.Sprintf("invalid dry_run param: \"%s\"", param),
+			})
+			return
+		}
+	}
+
+	job, err := a.archiver.CreateRearchiveJob(r.Context(), from, to, dryRun)
 	if err != nil {
-		a.logger.Error("Failed to rearchive blocks", "err", err)
+		a.logger.Error("Failed to create rearchive job", "err", err)
 
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(jobErrorStatus(err))
 		err = json.NewEncoder(w).Encode(rearchiveResponse{
 			Error:      err.Error(),
-			BlockStart: blockStart,
-			BlockEnd:   blockEnd,
+			BlockStart: from,
+			BlockEnd:   to,
+		})
+	} else {
+		a.logger.Info("Rearchive job created", "id", job.Id)
+		w.WriteHeader(http.StatusAccepted)
+
+		err = json.NewEncoder(w).Encode(rearchiveResponse{
+			JobId:      job.Id,
+			DryRun:     dryRun,
+			BlockStart: from,
+			BlockEnd:   to,
+		})
+	}
+
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// rearchiveItems creates a job that rearchives the blocks listed in the request body.
+func (a *API) rearchiveItems(w http.ResponseWriter, r *http.Request) {
+	var req rearchiveRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(rearchiveResponse{
+			Error: fmt.Sprintf("invalid request body: %v", err),
+		})
+		return
+	}
+
+	items, err := req.toItems()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(rearchiveResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	var searchFrom, searchTo uint64
+	if req.SearchFrom != nil && req.SearchTo != nil {
+		searchFrom, searchTo = *req.SearchFrom, *req.SearchTo
+	}
+
+	job, err := a.archiver.CreateRearchiveItemsJob(r.Context(), items, searchFrom, searchTo, req.DryRun)
+	if err != nil {
+		a.logger.Error("Failed to create rearchive job", "err", err)
+
+		w.WriteHeader(jobErrorStatus(err))
+		err = json.NewEncoder(w).Encode(rearchiveResponse{
+			Error: err.Error(),
+			Items: len(items),
 		})
 	} else {
-		a.logger.Info("Rearchiving blocks complete")
-		w.WriteHeader(http.StatusOK)
+		a.logger.Info("Rearchive job created", "id", job.Id)
+		w.WriteHeader(http.StatusAccepted)
 
 		err = json.NewEncoder(w).Encode(rearchiveResponse{
-			BlockStart: blockStart,
-			BlockEnd:   blockEnd,
+			JobId:      job.Id,
+			DryRun:     req.DryRun,
+			BlockStart: searchFrom,
+			BlockEnd:   searchTo,
+			Items:      len(items),
 		})
 	}
 
@@ -122,3 +468,83 @@ func (a *API) rearchiveBlocks(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+type jobResponse struct {
+	Error string `json:"error,omitempty"`
+	*storage.RearchiveJob
+}
+
+type jobsResponse struct {
+	Error string                 `json:"error,omitempty"`
+	Jobs  []storage.RearchiveJob `json:"jobs"`
+}
+
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrNotLeader):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJobResponse writes the job, or the error that occurred while reading or changing it.
+func (a *API) writeJobResponse(w http.ResponseWriter, job storage.RearchiveJob, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := jobResponse{}
+	if job.Id != "" {
+		response.RearchiveJob = &job
+	}
+	if err != nil {
+		response.Error = err.Error()
+		w.WriteHeader(jobErrorStatus(err))
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// jobsHandler lists all rearchive jobs, the most recently created first.
+func (a *API) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	jobs, err := a.archiver.RearchiveJobs(r.Context())
+	if err != nil {
+		a.logger.Error("Failed to read rearchive jobs", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(jobsResponse{
+			Error: err.Error(),
+			Jobs:  []storage.RearchiveJob{},
+		})
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(jobsResponse{Jobs: jobs})
+	if err != nil {
+		a.logger.Error("Failed to write response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// jobHandler reports the progress and the failed slots of a rearchive job.
+func (a *API) jobHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := a.archiver.RearchiveJob(r.Context(), chi.URLParam(r, "id"))
+	a.writeJobResponse(w, job, err)
+}
+
+// cancelJobHandler stops a rearchive job.
+func (a *API) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := a.archiver.CancelRearchiveJob(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		a.logger.Error("Failed to cancel rearchive job", "err", err)
+	}
+	a.writeJobResponse(w, job, err)
+}
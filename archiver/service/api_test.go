@@ -1,13 +1,21 @@
 package service
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/base-org/blob-archiver/archiver/flags"
 	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
 	"github.com/base-org/blob-archiver/common/storage/storagetest"
 	"github.com/ethereum-optimism/optimism/op-service/testlog"
 	"github.com/ethereum/go-ethereum/log"
@@ -22,7 +30,9 @@ func setupAPI(t *testing.T) (*API, *storagetest.TestFileStorage) {
 		PollInterval: 10 * time.Second,
 	}, fs, nil, m)
 	require.NoError(t, err)
-	return NewAPI(m, logger, archiver), fs
+	a, err := NewAPI(m, logger, archiver)
+	require.NoError(t, err)
+	return a, fs
 }
 
 func TestHealthHandler(t *testing.T) {
@@ -36,6 +46,266 @@ func TestHealthHandler(t *testing.T) {
 	require.Equal(t, 200, response.Code)
 }
 
+func TestStatusHandler(t *testing.T) {
+	a, _ := setupAPI(t)
+
+	request := httptest.NewRequest("GET", "/status", nil)
+	response := httptest.NewRecorder()
+
+	a.router.ServeHTTP(response, request)
+
+	require.Equal(t, 200, response.Code)
+
+	var status ArchiverStatus
+	err := json.NewDecoder(response.Body).Decode(&status)
+	require.NoError(t, err)
+	require.Equal(t, a.archiver.id, status.ArchiverId)
+	require.False(t, status.Leader)
+	require.False(t, status.Standby)
+}
+
+func TestBackfillHandler(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 1, 1)
+	a, err := NewAPI(svc.metrics, svc.log, svc)
+	require.NoError(t, err)
+
+	five := *beacon.Headers[blobtest.Five.String()]
+	three := *beacon.Headers[blobtest.Three.String()]
+	err = fs.WriteBackfillProcesses(context.Background(), storage.BackfillProcesses{
+		blobtest.Three: storage.BackfillProcess{Start: three, Current: three},
+		blobtest.Five:  storage.BackfillProcess{Start: five, Current: *beacon.Headers[blobtest.Four.String()]},
+	})
+	require.NoError(t, err)
+
+	request := httptest.NewRequest("GET", "/backfill", nil)
+	response := httptest.NewRecorder()
+
+	a.router.ServeHTTP(response, request)
+
+	require.Equal(t, 200, response.Code)
+
+	var result backfillResponse
+	err = json.NewDecoder(response.Body).Decode(&result)
+	require.NoError(t, err)
+	require.Empty(t, result.Error)
+	require.Len(t, result.Processes, 2)
+
+	// The most recent process comes first
+	require.Equal(t, blobtest.Five, result.Processes[0].StartHash)
+	require.Equal(t, blobtest.StartSlot+4, result.Processes[0].CurrentSlot)
+	require.Equal(t, uint64(4), *result.Processes[0].RemainingBlocks)
+	require.Equal(t, blobtest.Three, result.Processes[1].StartHash)
+	require.Equal(t, uint64(3), *result.Processes[1].RemainingBlocks)
+}
+
+func TestControlHandlers(t *testing.T) {
+	a, fs := setupAPI(t)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		error          string
+		paused         bool
+	}{
+		{
+			name:           "should pause live tracking",
+			method:         "POST",
+			path:           "/control/live/pause",
+			expectedStatus: 200,
+			paused:         true,
+		},
+		{
+			name:           "should report the control state",
+			method:         "GET",
+			path:           "/control",
+			expectedStatus: 200,
+			paused:         true,
+		},
+		{
+			name:           "should fail with invalid start hash",
+			method:         "POST",
+			path:           "/backfill/0x1234/pause",
+			expectedStatus: 400,
+			error:          "invalid start block hash: \"0x1234\"",
+			paused:         true,
+		},
+		{
+			name:           "should fail before the backfill is started",
+			method:         "DELETE",
+			path:           "/backfill/" + blobtest.Three.String(),
+			expectedStatus: 503,
+			error:          ErrBackfillNotStarted.Error(),
+			paused:         true,
+		},
+		{
+			name:           "should resume live tracking",
+			method:         "POST",
+			path:           "/control/live/resume",
+			expectedStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			response := httptest.NewRecorder()
+
+			a.router.ServeHTTP(response, request)
+
+			require.Equal(t, test.expectedStatus, response.Code)
+
+			var res controlResponse
+			err := json.NewDecoder(response.Body).Decode(&res)
+			require.NoError(t, err)
+			require.Equal(t, test.error, res.Error)
+			require.Equal(t, test.paused, res.LiveTrackingPaused)
+
+			stored, err := fs.ReadControlState(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, test.paused, stored.LiveTrackingPaused)
+		})
+	}
+}
+
+func TestStartBackfillHandler(t *testing.T) {
+	a, _ := setupAPI(t)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		error          string
+	}{
+		{
+			name:           "should fail with no params",
+			path:           "/backfill",
+			expectedStatus: 400,
+			error:          "invalid block param: must provide param",
+		},
+		{
+			name:           "should fail with invalid root",
+			path:           "/backfill?block=0x1234",
+			expectedStatus: 400,
+			error:          "invalid block param: invalid block root: \"0x1234\"",
+		},
+		{
+			name:           "should fail before the backfill is started",
+			path:           "/backfill?block=13",
+			expectedStatus: 503,
+			error:          ErrBackfillNotStarted.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", test.path, nil)
+			response := httptest.NewRecorder()
+
+			a.router.ServeHTTP(response, request)
+
+			require.Equal(t, test.expectedStatus, response.Code)
+
+			var res startBackfillResponse
+			err := json.NewDecoder(response.Body).Decode(&res)
+			require.NoError(t, err)
+			require.Equal(t, test.error, res.Error)
+		})
+	}
+}
+
+func TestJobHandlers(t *testing.T) {
+	a, fs := setupAPI(t)
+
+	require.NoError(t, fs.WriteRearchiveJobs(context.Background(), storage.RearchiveJobs{
+		"job": storage.RearchiveJob{
+			Id:          "job",
+			Status:      storage.JobStatusRunning,
+			From:        1,
+			To:          5,
+			NextSlot:    3,
+			Processed:   2,
+			FailedSlots: []uint64{2},
+		},
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		error          string
+		status         storage.JobStatus
+	}{
+		{
+			name:           "should report a job",
+			method:         "GET",
+			path:           "/jobs/job",
+			expectedStatus: 200,
+			status:         storage.JobStatusRunning,
+		},
+		{
+			name:           "should fail with unknown job",
+			method:         "GET",
+			path:           "/jobs/unknown",
+			expectedStatus: 404,
+			error:          ErrJobNotFound.Error(),
+		},
+		{
+			name:           "should cancel a job",
+			method:         "DELETE",
+			path:           "/jobs/job",
+			expectedStatus: 200,
+			status:         storage.JobStatusCanceled,
+		},
+		{
+			name:           "should fail to cancel a finished job",
+			method:         "DELETE",
+			path:           "/jobs/job",
+			expectedStatus: 409,
+			error:          ErrJobFinished.Error(),
+			status:         storage.JobStatusCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			response := httptest.NewRecorder()
+
+			a.router.ServeHTTP(response, request)
+
+			require.Equal(t, test.expectedStatus, response.Code)
+
+			var res jobResponse
+			err := json.NewDecoder(response.Body).Decode(&res)
+			require.NoError(t, err)
+			require.Equal(t, test.error, res.Error)
+
+			if test.status != "" {
+				require.NotNil(t, res.RearchiveJob)
+				require.Equal(t, test.status, res.Status)
+				require.Equal(t, []uint64{2}, res.FailedSlots)
+			}
+		})
+	}
+
+	request := httptest.NewRequest("GET", "/jobs", nil)
+	response := httptest.NewRecorder()
+	a.router.ServeHTTP(response, request)
+	require.Equal(t, 200, response.Code)
+
+	var res jobsResponse
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&res))
+	require.Len(t, res.Jobs, 1)
+	require.Equal(t, "job", res.Jobs[0].Id)
+}
+
 func TestRearchiveHandler(t *testing.T) {
 	a, _ := setupAPI(t)
 
@@ -81,6 +351,18 @@ func TestRearchiveHandler(t *testing.T) {
 			expectedStatus: 400,
 			error:          "invalid range: from 2 to 1",
 		},
+		{
+			name:           "should fail with invalid dry_run param",
+			path:           "/rearchive?from=1&to=2&dry_run=maybe",
+			expectedStatus: 400,
+			error:          "invalid dry_run param: \"maybe\"",
+		},
+		{
+			name:           "should fail without the storage lock",
+			path:           "/rearchive?from=1&to=2",
+			expectedStatus: 503,
+			error:          ErrNotLeader.Error(),
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,3 +385,122 @@ func TestRearchiveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRearchiveItemsHandler(t *testing.T) {
+	a, _ := setupAPI(t)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		error          string
+	}{
+		{
+			name:           "should fail with invalid json",
+			body:           `{"block_roots": [`,
+			expectedStatus: 400,
+			error:          "invalid request body: unexpected EOF",
+		},
+		{
+			name:           "should fail with unknown fields",
+			body:           `{"roots": []}`,
+			expectedStatus: 400,
+			error:          "invalid request body: json: unknown field \"roots\"",
+		},
+		{
+			name:           "should fail without items",
+			body:           `{"dry_run": true}`,
+			expectedStatus: 400,
+			error:          "must provide block_roots, slots or versioned_hashes",
+		},
+		{
+			name:           "should fail with versioned hashes but no search range",
+			body:           `{"versioned_hashes": ["0x01000000000000000000000000000000000000000000000000000000000000aa"]}`,
+			expectedStatus: 400,
+			error:          "must provide search_from and search_to with versioned_hashes",
+		},
+		{
+			name:           "should fail with an inverted search range",
+			body:           `{"versioned_hashes": ["0x01000000000000000000000000000000000000000000000000000000000000aa"], "search_from": 2, "search_to": 1}`,
+			expectedStatus: 400,
+			error:          "invalid search range: from 2 to 1",
+		},
+		{
+			name:           "should fail with an invalid versioned hash",
+			body:           `{"versioned_hashes": ["0x02000000000000000000000000000000000000000000000000000000000000aa"], "search_from": 1, "search_to": 2}`,
+			expectedStatus: 400,
+			error:          "invalid versioned hash: \"0x02000000000000000000000000000000000000000000000000000000000000aa\"",
+		},
+		{
+			name:           "should fail without the storage lock",
+			body:           `{"block_roots": ["0x0300000000000000000000000000000000000000000000000000000000000000"], "slots": [11]}`,
+			expectedStatus: 503,
+			error:          ErrNotLeader.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/rearchive", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", "application/json")
+			response := httptest.NewRecorder()
+
+			a.router.ServeHTTP(response, request)
+
+			require.Equal(t, test.expectedStatus, response.Code)
+
+			var errResponse rearchiveResponse
+			err := json.NewDecoder(response.Body).Decode(&errResponse)
+			require.NoError(t, err)
+			require.Equal(t, test.error, errResponse.Error)
+		})
+	}
+}
+
+func TestAdminClientCert(t *testing.T) {
+	logger := testlog.Logger(t, log.LvlInfo)
+	m := metrics.NewMetrics()
+	fs := storagetest.NewTestFileStorage(t, logger)
+	archiver, err := NewArchiver(logger, flags.ArchiverConfig{
+		PollInterval:           10 * time.Second,
+		AdminRequireClientCert: true,
+		AdminClientNames:       "operator, deployer",
+	}, fs, nil, m)
+	require.NoError(t, err)
+	a, err := NewAPI(m, logger, archiver)
+	require.NoError(t, err)
+
+	request := func(method string, path string, clientName string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		if clientName != "" {
+			request.TLS = &tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: clientName}}}},
+			}
+		}
+		response := httptest.NewRecorder()
+		a.router.ServeHTTP(response, request)
+		return response
+	}
+
+	response := request("POST", "/rearchive?from=1&to=2", "")
+	require.Equal(t, 401, response.Code)
+	var res errorResponse
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&res))
+	require.Equal(t, "a verified client certificate is required", res.Error)
+
+	response = request("POST", "/control/live/pause", "intruder")
+	require.Equal(t, 403, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&res))
+	require.Equal(t, "client \"intruder\" is not allowed", res.Error)
+
+	require.Equal(t, 200, request("POST", "/control/live/pause", "deployer").Code)
+
+	// Read-only endpoints are restricted as well
+	require.Equal(t, 401, request("GET", "/control", "").Code)
+	require.Equal(t, 401, request("GET", "/jobs", "").Code)
+	require.Equal(t, 200, request("GET", "/jobs", "operator").Code)
+
+	// The health check is not
+	require.Equal(t, 200, request("GET", "/healthz", "").Code)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	client "github.com/attestantio/go-eth2-client"
@@ -11,7 +13,9 @@ import (
 	v1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/base-org/blob-archiver/archiver/flags"
 	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon"
 	"github.com/base-org/blob-archiver/common/storage"
+	validator "github.com/base-org/blob-archiver/validator/service"
 	"github.com/ethereum-optimism/optimism/op-service/retry"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
@@ -31,34 +35,139 @@ type BeaconClient interface {
 }
 
 func NewArchiver(l log.Logger, cfg flags.ArchiverConfig, dataStoreClient storage.DataStore, client BeaconClient, m metrics.Metricer) (*Archiver, error) {
+	id := uuid.New().String()
+	events, _ := client.(beacon.EventSubscriber)
+	chainConfig, _ := client.(chainConfigProvider)
+	blocks, _ := client.(blockProvider)
+
+	if cfg.ConsensusQuorum > 0 {
+		consensusClient, err := newConsensusBeaconClient(client, cfg.ConsensusQuorum, l, m)
+		if err != nil {
+			return nil, err
+		}
+		client = consensusClient
+	}
+
+	// Live tracking, rearchive jobs and the backfill share the beacon node through a scheduler, in that order of priority
+	scheduler := beacon.NewScheduler(beacon.SchedulerConfig{
+		RequestsPerSecond: cfg.BeaconRequestsPerSecond,
+		MaxInFlight:       cfg.BeaconMaxInFlight,
+	}, m)
+	liveClient := scheduler.Client(client, beacon.PriorityLive)
+	rearchiveClient := scheduler.Client(client, beacon.PriorityRearchive)
+
+	backfillClient := scheduler.Client(client, beacon.PriorityBackfill)
+	if cfg.BackfillPeerURL != "" {
+		if blocks == nil {
+			return nil, errors.New("the backfill peer requires a beacon client that returns signed blocks")
+		}
+		backfillClient = &peerBeaconClient{
+			BeaconClient: backfillClient,
+			blocks:       blocks,
+			peer:         validator.NewBlobSidecarClient(cfg.BackfillPeerURL),
+			log:          l,
+			metrics:      m,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Archiver{
+		ctx:             ctx,
+		cancel:          cancel,
 		log:             l,
 		cfg:             cfg,
 		dataStoreClient: dataStoreClient,
 		metrics:         m,
-		beaconClient:    client,
+		beaconClient:    liveClient,
+		rearchiveClient: rearchiveClient,
+		backfillClient:  backfillClient,
+		events:          events,
+		chainConfig:     chainConfig,
+		blocks:          blocks,
 		stopCh:          make(chan struct{}),
-		id:              uuid.New().String(),
+		id:              id,
+		status:          ArchiverStatus{ArchiverId: id},
+		backfillRates:   make(map[common.Hash]*backfillRate),
+		backfillRuns:    make(map[common.Hash]*backfillRun),
+		jobRuns:         make(map[string]context.CancelFunc),
+		newPeerClient:   validator.NewBlobSidecarClient,
 	}, nil
 }
 
 type Archiver struct {
-	log             log.Logger
-	cfg             flags.ArchiverConfig
-	dataStoreClient storage.DataStore
-	beaconClient    BeaconClient
-	metrics         metrics.Metricer
-	stopCh          chan struct{}
-	id              string
+	ctx               context.Context
+	cancel            context.CancelFunc
+	log               log.Logger
+	cfg               flags.ArchiverConfig
+	dataStoreClient   storage.DataStore
+	beaconClient      BeaconClient
+	rearchiveClient   BeaconClient
+	backfillClient    BeaconClient
+	events            beacon.EventSubscriber
+	chainConfig       chainConfigProvider
+	blocks            blockProvider
+	metrics           metrics.Metricer
+	stopCh            chan struct{}
+	id                string
+	statusMu          sync.Mutex
+	status            ArchiverStatus
+	backfillMu        sync.Mutex
+	backfillCtx       context.Context
+	backfillProcesses storage.BackfillProcesses
+	backfillRuns      map[common.Hash]*backfillRun
+	controlMu         sync.Mutex
+	control           storage.ControlState
+	progressMu        sync.Mutex
+	backfillRates     map[common.Hash]*backfillRate
+	originMu          sync.Mutex
+	originSlotKnown   bool
+	originSlotValue   uint64
+	jobsMu            sync.Mutex
+	jobs              storage.RearchiveJobs
+	jobRuns           map[string]context.CancelFunc
+	streaming         atomic.Bool
+	chainMu           sync.Mutex
+	chain             storage.ChainState
+	unfinalized       map[uint64]storage.UnfinalizedEpoch
+	newPeerClient     func(url string) validator.BlobSidecarClient
 }
 
 // Start starts archiving blobs. It begins polling the beacon node for the latest blocks and persisting blobs for
 // them. Concurrently it'll also begin a backfill process (see backfillBlobs) to store all blobs from the current head
 // to the previously stored blocks. This ensures that during restarts or outages of an archiver, any gaps will be
 // filled in.
+//
+// In standby mode nothing is written until the storage lock is obtained. While waiting, the archiver tracks the head
+// without writing, and once it takes over it immediately catches up from the last head archived by the previous leader.
+//
+// In finalized-only mode the archiver follows the finalized checkpoint instead of the head (see processFinalizedBlocks).
 func (a *Archiver) Start(ctx context.Context) error {
+	if err := a.loadControlState(ctx); err != nil {
+		a.log.Error("failed to read control state", "err", err)
+		return err
+	}
+
+	if a.cfg.Standby {
+		leader := a.waitObtainStorageLock(ctx)
+		if leader.HeadRoot != (common.Hash{}) {
+			a.log.Info("took over storage lock, catching up from leader head", "leader", leader.ArchiverId, "leaderHeadHash", leader.HeadRoot, "leaderHeadSlot", leader.HeadSlot)
+			if a.cfg.FinalizedOnly {
+				// The finalized cursor already records how far the leader got
+				a.processFinalizedBlocks(ctx)
+			} else {
+				a.catchUpToLeaderHead(ctx, leader)
+			}
+		}
+	}
+
 	currentBlock, _, err := retry.Do2(ctx, startupFetchBlobMaximumRetries, retry.Exponential(), func() (*v1.BeaconBlockHeader, bool, error) {
-		return a.persistBlobsForBlockToS3(ctx, "head", false)
+		// A parked block still seeds the archiver, its blobs are stored once the beacon nodes agree
+		header, exists, err := a.persistBlobsForBlockToS3(ctx, a.latestBlockId(), false)
+		if errors.Is(err, errBlockParked) {
+			return header, exists, nil
+		}
+		return header, exists, err
 	})
 
 	if err != nil {
@@ -66,26 +175,49 @@ func (a *Archiver) Start(ctx context.Context) error {
 		return err
 	}
 
-	a.waitObtainStorageLock(ctx)
+	if !a.cfg.Standby {
+		a.waitObtainStorageLock(ctx)
+	}
+
+	a.setLatestHead(currentBlock)
+	if a.cfg.FinalizedOnly {
+		// The blocks between a previous cursor and the initial block are left to the backfill
+		a.advanceFinalizedCursor(ctx, currentBlock)
+	}
+
+	if err := a.resumeRearchiveJobs(ctx); err != nil {
+		a.log.Error("failed to resume rearchive jobs", "err", err)
+		return err
+	}
 
 	go a.backfillBlobs(ctx, currentBlock)
+	go a.runCompactor(ctx)
+	go a.runIngest(ctx)
+	go a.runSnapshots(ctx)
 
 	return a.trackLatestBlocks(ctx)
 }
 
 // Stops the archiver service.
 func (a *Archiver) Stop(ctx context.Context) error {
+	a.cancel()
 	close(a.stopCh)
 	return nil
 }
 
 // persistBlobsForBlockToS3 fetches the blobs for a given block and persists them to S3. It returns the block header
-// and a boolean indicating whether the blobs already existed in S3 and any errors that occur.
+// and a boolean indicating whether the blobs already existed in S3 and any errors that occur. If the blobs of the block
+// are disputed, it returns the block header with errBlockParked.
 // If the blobs are already stored, it will not overwrite the data. Currently, the archiver does not
 // perform any validation of the blobs, it assumes a trusted beacon node. See:
 // https://github.com/base-org/blob-archiver/issues/4.
 func (a *Archiver) persistBlobsForBlockToS3(ctx context.Context, blockIdentifier string, overwrite bool) (*v1.BeaconBlockHeader, bool, error) {
-	currentHeader, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+	return a.persistBlobsForBlockWithClient(ctx, a.beaconClient, blockIdentifier, overwrite)
+}
+
+// persistBlobsForBlockWithClient is persistBlobsForBlockToS3 using the given beacon client.
+func (a *Archiver) persistBlobsForBlockWithClient(ctx context.Context, beaconClient BeaconClient, blockIdentifier string, overwrite bool) (*v1.BeaconBlockHeader, bool, error) {
+	currentHeader, err := beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
 		Block: blockIdentifier,
 	})
 
@@ -105,10 +237,17 @@ func (a *Archiver) persistBlobsForBlockToS3(ctx context.Context, blockIdentifier
 		return currentHeader.Data, true, nil
 	}
 
-	blobSidecars, err := a.beaconClient.BlobSidecars(ctx, &api.BlobSidecarsOpts{
+	blobSidecars, err := beaconClient.BlobSidecars(ctx, &api.BlobSidecarsOpts{
 		Block: currentHeader.Data.Root.String(),
 	})
 
+	if errors.Is(err, errBlobSidecarsDisputed) {
+		// Disputed data is never stored. The block is retried later (see retryParkedBlocks), so that callers carry on
+		// with its parent in the meantime.
+		a.parkBlock(ctx, currentHeader.Data)
+		return currentHeader.Data, false, errBlockParked
+	}
+
 	if err != nil {
 		a.log.Error("failed to fetch blob sidecars", "err", err)
 		return nil, false, err
@@ -132,6 +271,7 @@ func (a *Archiver) persistBlobsForBlockToS3(ctx context.Context, blockIdentifier
 	}
 
 	a.metrics.RecordStoredBlobs(len(blobSidecars.Data))
+	a.trackStoredBlock(ctx, currentHeader.Data)
 
 	return currentHeader.Data, exists, nil
 }
@@ -140,12 +280,20 @@ const LockUpdateInterval = 10 * time.Second
 const LockTimeout = int64(20) // 20 seconds
 var ObtainLockRetryInterval = 10 * time.Second
 
-func (a *Archiver) waitObtainStorageLock(ctx context.Context) {
+// waitObtainStorageLock blocks until the storage lock is obtained, and keeps refreshing it in the background. It returns
+// the last lockfile written by another archiver, which is empty if no other archiver held the lock. In standby mode the
+// head is tracked while waiting.
+func (a *Archiver) waitObtainStorageLock(ctx context.Context) storage.Lockfile {
 	lockfile, err := a.dataStoreClient.ReadLockfile(ctx)
 	if err != nil {
 		a.log.Crit("failed to read lockfile", "err", err)
 	}
 
+	retryInterval := ObtainLockRetryInterval
+	if a.cfg.Standby {
+		retryInterval = a.cfg.PollInterval
+	}
+
 	currentTime := time.Now().Unix()
 	emptyLockfile := storage.Lockfile{}
 	if lockfile != emptyLockfile {
@@ -155,7 +303,10 @@ func (a *Archiver) waitObtainStorageLock(ctx context.Context) {
 				"timestamp", strconv.FormatInt(lockfile.Timestamp, 10),
 				"currentTime", strconv.FormatInt(currentTime, 10),
 			)
-			time.Sleep(ObtainLockRetryInterval)
+			if a.cfg.Standby {
+				a.trackHeadInStandby(ctx, lockfile)
+			}
+			time.Sleep(retryInterval)
 			lockfile, err = a.dataStoreClient.ReadLockfile(ctx)
 			if err != nil {
 				a.log.Crit("failed to read lockfile", "err", err)
@@ -164,10 +315,24 @@ func (a *Archiver) waitObtainStorageLock(ctx context.Context) {
 		}
 	}
 
-	err = a.dataStoreClient.WriteLockfile(ctx, storage.Lockfile{ArchiverId: a.id, Timestamp: currentTime})
+	previous := emptyLockfile
+	if lockfile.ArchiverId != a.id {
+		previous = lockfile
+	}
+
+	if previous.HeadRoot != (common.Hash{}) {
+		// Until this archiver archives a new head, the head of the previous leader is the latest archived head
+		a.statusMu.Lock()
+		a.status.LeaderHeadRoot = previous.HeadRoot
+		a.status.LeaderHeadSlot = previous.HeadSlot
+		a.statusMu.Unlock()
+	}
+
+	err = a.dataStoreClient.WriteLockfile(ctx, a.lockfile(currentTime))
 	if err != nil {
 		a.log.Crit("failed to write to lockfile: %v", err)
 	}
+	a.setLeader()
 	a.log.Info("obtained storage lock")
 
 	go func() {
@@ -177,7 +342,7 @@ func (a *Archiver) waitObtainStorageLock(ctx context.Context) {
 			select {
 			case <-ticker.C:
 				currentTime := time.Now().Unix()
-				err := a.dataStoreClient.WriteLockfile(ctx, storage.Lockfile{ArchiverId: a.id, Timestamp: currentTime})
+				err := a.dataStoreClient.WriteLockfile(ctx, a.lockfile(currentTime))
 				if err != nil {
 					a.log.Error("failed to update lockfile timestamp", "err", err)
 				}
@@ -187,79 +352,174 @@ func (a *Archiver) waitObtainStorageLock(ctx context.Context) {
 			}
 		}
 	}()
+
+	return previous
 }
 
 // backfillBlobs will persist all blobs from the provided beacon block header, to either the last block that was persisted
 // to the archivers storage or the origin block in the configuration. This is used to ensure that any gaps can be filled.
-// If an error is encountered persisting a block, it will retry after waiting for a period of time.
+// If an error is encountered persisting a block, it will retry after waiting for a period of time. With more than one
+// backfill worker configured, the processes are filled in parallel (see backfillParallel). Paused processes are kept
+// in storage but not filled until they are resumed.
 func (a *Archiver) backfillBlobs(ctx context.Context, latest *v1.BeaconBlockHeader) {
 	// Add backfill process that starts at latest slot, then loop through all backfill processes
 	backfillProcesses, err := a.dataStoreClient.ReadBackfillProcesses(ctx)
 	if err != nil {
 		a.log.Crit("failed to read backfill_processes", "err", err)
 	}
-	backfillProcesses[common.Hash(latest.Root)] = storage.BackfillProcess{Start: *latest, Current: *latest}
-	_ = a.dataStoreClient.WriteBackfillProcesses(ctx, backfillProcesses)
-
-	backfillLoop := func(start *v1.BeaconBlockHeader, current *v1.BeaconBlockHeader) {
-		curr, alreadyExists, err := current, false, error(nil)
-		count := 0
-		a.log.Info("backfill process initiated",
-			"currHash", curr.Root.String(),
-			"currSlot", curr.Header.Message.Slot,
-			"startHash", start.Root.String(),
-			"startSlot", start.Header.Message.Slot,
-		)
-
-		defer func() {
-			a.log.Info("backfill process complete",
-				"endHash", curr.Root.String(),
-				"endSlot", curr.Header.Message.Slot,
+
+	a.backfillMu.Lock()
+	a.backfillCtx = ctx
+	a.backfillProcesses = backfillProcesses
+	a.backfillProcesses[common.Hash(latest.Root)] = storage.BackfillProcess{Start: *latest, Current: *latest}
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+
+	var starts []common.Hash
+	for start := range a.backfillProcesses {
+		if a.backfillPaused(start) {
+			a.log.Info("backfill process paused", "startHash", start.String())
+			continue
+		}
+		starts = append(starts, start)
+	}
+	a.backfillMu.Unlock()
+
+	a.runBackfill(ctx, starts...)
+}
+
+// runBackfill fills the given backfill processes, either in parallel or one after the other.
+func (a *Archiver) runBackfill(ctx context.Context, starts ...common.Hash) {
+	if a.cfg.BackfillWorkers > 1 {
+		a.backfillParallel(ctx, starts)
+		return
+	}
+
+	for _, start := range starts {
+		a.backfillSequential(ctx, start)
+	}
+}
+
+// backfillSequential walks a backfill process back block by block, until it reaches a block that is already archived
+// or the origin block. If the process is paused or canceled, it stops and keeps the progress made so far.
+func (a *Archiver) backfillSequential(ctx context.Context, startHash common.Hash) {
+	runCtx, done, ok := a.startBackfillRun(ctx, startHash)
+	if !ok {
+		return
+	}
+	defer done()
+
+	a.backfillMu.Lock()
+	process, ok := a.backfillProcesses[startHash]
+	a.backfillMu.Unlock()
+	if !ok {
+		return
+	}
+
+	start := &process.Start
+	curr, alreadyExists, err := &process.Current, false, error(nil)
+	count := 0
+	a.log.Info("backfill process initiated",
+		"currHash", curr.Root.String(),
+		"currSlot", curr.Header.Message.Slot,
+		"startHash", start.Root.String(),
+		"startSlot", start.Header.Message.Slot,
+	)
+
+	for !alreadyExists {
+		if runCtx.Err() != nil {
+			a.log.Info("backfill process stopped",
+				"currHash", curr.Root.String(),
+				"currSlot", curr.Header.Message.Slot,
 				"startHash", start.Root.String(),
 				"startSlot", start.Header.Message.Slot,
 			)
-			delete(backfillProcesses, common.Hash(start.Root))
-			_ = a.dataStoreClient.WriteBackfillProcesses(ctx, backfillProcesses)
-		}()
+			a.updateBackfillProcess(ctx, startHash, *curr)
+			return
+		}
 
-		for !alreadyExists {
-			previous := curr
+		previous := curr
 
-			if common.Hash(curr.Root) == a.cfg.OriginBlock {
-				a.log.Info("reached origin block", "hash", curr.Root.String())
-				return
-			}
+		if common.Hash(curr.Root) == a.cfg.OriginBlock {
+			a.log.Info("reached origin block", "hash", curr.Root.String())
+			break
+		}
 
-			curr, alreadyExists, err = a.persistBlobsForBlockToS3(ctx, previous.Header.Message.ParentRoot.String(), false)
-			if err != nil {
-				a.log.Error("failed to persist blobs for block, will retry", "err", err, "hash", previous.Header.Message.ParentRoot.String())
-				// Revert back to block we failed to fetch
+		curr, alreadyExists, err = a.persistBlobsForBlockWithClient(runCtx, a.backfillClient, previous.Header.Message.ParentRoot.String(), false)
+		parked := errors.Is(err, errBlockParked)
+		if err != nil && !parked {
+			if runCtx.Err() != nil {
 				curr = previous
-				time.Sleep(backfillErrorRetryInterval)
 				continue
 			}
-
-			if !alreadyExists {
-				a.metrics.RecordProcessedBlock(metrics.BlockSourceBackfill)
+			a.log.Error("failed to persist blobs for block, will retry", "err", err, "hash", previous.Header.Message.ParentRoot.String())
+			// Revert back to block we failed to fetch
+			curr = previous
+			select {
+			case <-runCtx.Done():
+			case <-time.After(backfillErrorRetryInterval):
 			}
+			continue
+		}
 
-			count++
-			if count%10 == 0 {
-				backfillProcesses[common.Hash(start.Root)] = storage.BackfillProcess{Start: *start, Current: *curr}
-				_ = a.dataStoreClient.WriteBackfillProcesses(ctx, backfillProcesses)
-			}
+		if !alreadyExists && !parked {
+			a.metrics.RecordProcessedBlock(metrics.BlockSourceBackfill)
+		}
+
+		count++
+		if count%10 == 0 {
+			a.updateBackfillProcess(ctx, startHash, *curr)
 		}
 	}
 
-	for _, process := range backfillProcesses {
-		backfillLoop(&process.Start, &process.Current)
+	a.log.Info("backfill process complete",
+		"endHash", curr.Root.String(),
+		"endSlot", curr.Header.Message.Slot,
+		"startHash", start.Root.String(),
+		"startSlot", start.Header.Message.Slot,
+	)
+
+	a.backfillMu.Lock()
+	delete(a.backfillProcesses, startHash)
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+	a.backfillMu.Unlock()
+	a.completeBackfillProgress(startHash)
+}
+
+// updateBackfillProcess records the current block of a sequential backfill process and persists the backfill processes.
+// It does nothing if the process was canceled in the meantime.
+func (a *Archiver) updateBackfillProcess(ctx context.Context, start common.Hash, current v1.BeaconBlockHeader) {
+	a.backfillMu.Lock()
+	process, ok := a.backfillProcesses[start]
+	if !ok {
+		a.backfillMu.Unlock()
+		return
 	}
+	process.Current = current
+	a.backfillProcesses[start] = process
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+	a.backfillMu.Unlock()
+
+	a.recordBackfillProgress(ctx, start, process)
 }
 
-// trackLatestBlocks will poll the beacon node for the latest blocks and persist blobs for them.
+// trackLatestBlocks will follow the latest blocks and persist blobs for them. If the event stream is enabled and the
+// beacon client supports it, new blocks are archived as soon as they are imported. Otherwise, or while the stream is
+// unavailable, the beacon node is polled.
 func (a *Archiver) trackLatestBlocks(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	newBlocks := make(chan struct{}, 1)
+	chain := newChainEvents()
+	go a.trackChain(ctx, chain)
+	if a.events != nil && a.cfg.EventStream {
+		go a.subscribeEvents(ctx, a.events, newBlocks, chain)
+	}
+
 	t := time.NewTicker(a.cfg.PollInterval)
 	defer t.Stop()
+	parked := time.NewTicker(parkedRetryInterval)
+	defer parked.Stop()
 
 	for {
 		select {
@@ -268,11 +528,27 @@ func (a *Archiver) trackLatestBlocks(ctx context.Context) error {
 		case <-a.stopCh:
 			return nil
 		case <-t.C:
-			a.processBlocksUntilKnownBlock(ctx)
+			if a.streaming.Load() {
+				continue
+			}
+			a.refreshLiveData(ctx)
+		case <-newBlocks:
+			a.refreshLiveData(ctx)
+		case <-parked.C:
+			a.retryParkedBlocks(ctx)
 		}
 	}
 }
 
+// refreshLiveData archives the latest blocks, unless live tracking is paused.
+func (a *Archiver) refreshLiveData(ctx context.Context) {
+	if a.liveTrackingPaused() {
+		a.log.Debug("live tracking paused")
+		return
+	}
+	a.processLatestBlocks(ctx)
+}
+
 // processBlocksUntilKnownBlock will fetch and persist blobs for blocks until it finds a block that has been stored before.
 // In the case of a reorg, it will fetch the new head and then walk back the chain, storing all blobs until it finds a
 // known block -- that already exists in the archivers' storage.
@@ -283,8 +559,13 @@ func (a *Archiver) processBlocksUntilKnownBlock(ctx context.Context) {
 	currentBlockId := "head"
 
 	for {
+		parked := false
 		current, alreadyExisted, err := retry.Do2(ctx, liveFetchBlobMaximumRetries, retry.Exponential(), func() (*v1.BeaconBlockHeader, bool, error) {
-			return a.persistBlobsForBlockToS3(ctx, currentBlockId, false)
+			header, exists, err := a.persistBlobsForBlockToS3(ctx, currentBlockId, false)
+			if parked = errors.Is(err, errBlockParked); parked {
+				return header, exists, nil
+			}
+			return header, exists, err
 		})
 
 		if err != nil {
@@ -294,9 +575,12 @@ func (a *Archiver) processBlocksUntilKnownBlock(ctx context.Context) {
 
 		if start == nil {
 			start = current
+			a.setLatestHead(current)
 		}
 
-		if !alreadyExisted {
+		if parked {
+			a.log.Debug("skipping parked block", "hash", current.Root.String())
+		} else if !alreadyExisted {
 			a.metrics.RecordProcessedBlock(metrics.BlockSourceLive)
 		} else {
 			a.log.Debug("blob already exists", "hash", current.Root.String())
@@ -308,44 +592,3 @@ func (a *Archiver) processBlocksUntilKnownBlock(ctx context.Context) {
 
 	a.log.Info("live data refreshed", "startHash", start.Root.String(), "endHash", currentBlockId)
 }
-
-// rearchiveRange will rearchive all blocks in the range from the given start to end. It returns the start and end of the
-// range that was successfully rearchived. On any persistent errors, it will halt archiving and return the range of blocks
-// that were rearchived and the error that halted the process.
-func (a *Archiver) rearchiveRange(from uint64, to uint64) (uint64, uint64, error) {
-	for i := from; i <= to; i++ {
-		id := strconv.FormatUint(i, 10)
-
-		l := a.log.New("slot", id)
-
-		l.Info("rearchiving block")
-
-		rewritten, err := retry.Do(context.Background(), rearchiveMaximumRetries, retry.Exponential(), func() (bool, error) {
-			_, _, e := a.persistBlobsForBlockToS3(context.Background(), id, true)
-
-			// If the block is not found, we can assume that the slot has been skipped
-			if e != nil {
-				var apiErr *api.Error
-				if errors.As(e, &apiErr) && apiErr.StatusCode == 404 {
-					return false, nil
-				}
-
-				return false, e
-			}
-
-			return true, nil
-		})
-
-		if err != nil {
-			return from, i, err
-		}
-
-		if !rewritten {
-			l.Info("block not found during reachiving", "slot", id)
-		}
-
-		a.metrics.RecordProcessedBlock(metrics.BlockSourceRearchive)
-	}
-
-	return from, to, nil
-}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -172,8 +173,12 @@ func TestArchiver_ObtainLockfile(t *testing.T) {
 	err := svc.dataStoreClient.WriteLockfile(context.Background(), storage.Lockfile{ArchiverId: "FAKEID", Timestamp: expiredTime})
 	require.NoError(t, err)
 
+	// Stops retaining the lock once the test is done
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	ObtainLockRetryInterval = 1 * time.Second
-	svc.waitObtainStorageLock(context.Background())
+	svc.waitObtainStorageLock(ctx)
 
 	lockfile, err := svc.dataStoreClient.ReadLockfile(context.Background())
 	require.NoError(t, err)
@@ -433,9 +438,21 @@ func TestArchiver_LatestHaltsOnPersistentError(t *testing.T) {
 	fs.CheckExistsOrFail(t, blobtest.Three)
 }
 
-func TestArchiver_RearchiveRange(t *testing.T) {
+func waitForJob(t *testing.T, svc *Archiver, id string) storage.RearchiveJob {
+	var job storage.RearchiveJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = svc.RearchiveJob(context.Background(), id)
+		require.NoError(t, err)
+		return job.Finished()
+	}, 30*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestArchiver_RearchiveJob(t *testing.T) {
 	beacon := beacontest.NewDefaultStubBeaconClient(t)
 	svc, fs := setup(t, beacon)
+	svc.setLeader()
 
 	// 5 is the current head, if three already exists, we should write 5 and 4 and stop at three
 	fs.WriteOrFail(t, storage.BlobData{
@@ -454,15 +471,31 @@ func TestArchiver_RearchiveRange(t *testing.T) {
 	fs.CheckNotExistsOrFail(t, blobtest.Four)
 
 	// this modifies the blobs at 3, purely to test the blob is rearchived
+	storedCount := len(beacon.Blobs[blobtest.Three.String()])
 	beacon.Blobs[blobtest.Three.String()] = blobtest.NewBlobSidecars(t, 6)
 
 	from, to := blobtest.StartSlot+1, blobtest.StartSlot+4
 
-	actualFrom, actualTo, err := svc.rearchiveRange(from, to)
-	// Should index the whole range
+	job, err := svc.CreateRearchiveJob(context.Background(), from, to, false)
 	require.NoError(t, err)
-	require.Equal(t, from, actualFrom)
-	require.Equal(t, to, actualTo)
+	require.Equal(t, from, job.From)
+	require.Equal(t, to, job.To)
+
+	// Should index the whole range
+	job = waitForJob(t, svc, job.Id)
+	require.Equal(t, storage.JobStatusCompleted, job.Status)
+	require.Equal(t, uint64(4), job.Processed)
+	require.Equal(t, to+1, job.NextSlot)
+	require.Empty(t, job.FailedSlots)
+	require.False(t, job.DryRun)
+
+	// The report describes the data before it was overwritten
+	require.Empty(t, job.Report.IdenticalSlots)
+	require.Equal(t, []uint64{from, from + 1, from + 3}, job.Report.MissingSlots)
+	require.Len(t, job.Report.DifferentSlots, 1)
+	require.Equal(t, blobtest.StartSlot+3, job.Report.DifferentSlots[0].Slot)
+	require.Equal(t, blobtest.Three, job.Report.DifferentSlots[0].BlockRoot)
+	require.Contains(t, job.Report.DifferentSlots[0].Differences, fmt.Sprintf("sidecar count: stored %d, beacon node 6", storedCount))
 
 	// Should have written all the blobs
 	fs.CheckExistsOrFail(t, blobtest.One)
@@ -472,4 +505,267 @@ func TestArchiver_RearchiveRange(t *testing.T) {
 
 	// Should have overwritten any existing blobs
 	require.Equal(t, fs.ReadOrFail(t, blobtest.Three).BlobSidecars.Data, beacon.Blobs[blobtest.Three.String()])
+
+	// The job is persisted
+	stored, err := fs.ReadRearchiveJobs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, job, stored[job.Id])
+}
+
+func TestArchiver_RearchiveDryRun(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.setLeader()
+
+	// One is stored as served by the beacon node, Three is stored with different blobs and Two is not stored
+	fs.WriteOrFail(t, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.One},
+		BlobSidecars: storage.BlobSidecars{Data: beacon.Blobs[blobtest.One.String()]},
+	})
+	changed := blobtest.NewBlobSidecars(t, uint(len(beacon.Blobs[blobtest.Three.String()])))
+	fs.WriteOrFail(t, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.Three},
+		BlobSidecars: storage.BlobSidecars{Data: changed},
+	})
+
+	job, err := svc.CreateRearchiveJob(context.Background(), blobtest.StartSlot+1, blobtest.StartSlot+3, true)
+	require.NoError(t, err)
+	require.True(t, job.DryRun)
+
+	job = waitForJob(t, svc, job.Id)
+	require.Equal(t, storage.JobStatusCompleted, job.Status)
+	require.Equal(t, []uint64{blobtest.StartSlot + 1}, job.Report.IdenticalSlots)
+	require.Equal(t, []uint64{blobtest.StartSlot + 2}, job.Report.MissingSlots)
+	require.Len(t, job.Report.DifferentSlots, 1)
+	require.Equal(t, blobtest.StartSlot+3, job.Report.DifferentSlots[0].Slot)
+	require.Equal(t, blobtest.Three, job.Report.DifferentSlots[0].BlockRoot)
+	require.Contains(t, job.Report.DifferentSlots[0].Differences, "sidecar 0: blob")
+
+	// Nothing was overwritten
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+	require.Equal(t, changed, fs.ReadOrFail(t, blobtest.Three).BlobSidecars.Data)
+}
+
+func TestArchiver_RearchiveJobRecordsFailedSlots(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.setLeader()
+
+	// Every attempt to write the first slot fails
+	fs.WritesFailTimes(rearchiveMaximumRetries)
+
+	job, err := svc.CreateRearchiveJob(context.Background(), blobtest.StartSlot+1, blobtest.StartSlot+2, false)
+	require.NoError(t, err)
+
+	job = waitForJob(t, svc, job.Id)
+	require.Equal(t, storage.JobStatusPartial, job.Status)
+	require.Equal(t, uint64(2), job.Processed)
+	require.Equal(t, []uint64{blobtest.StartSlot + 1}, job.FailedSlots)
+
+	fs.CheckNotExistsOrFail(t, blobtest.One)
+	fs.CheckExistsOrFail(t, blobtest.Two)
+
+	// A job that could not rearchive any of its slots failed
+	fs.WritesFailTimes(rearchiveMaximumRetries)
+
+	job, err = svc.CreateRearchiveJob(context.Background(), blobtest.StartSlot+1, blobtest.StartSlot+1, false)
+	require.NoError(t, err)
+
+	job = waitForJob(t, svc, job.Id)
+	require.Equal(t, storage.JobStatusFailed, job.Status)
+	require.Equal(t, []uint64{blobtest.StartSlot + 1}, job.FailedSlots)
+}
+
+func TestArchiver_RearchiveJobResumesAfterRestart(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// A previous run was stopped after rearchiving the first two slots
+	require.NoError(t, fs.WriteRearchiveJobs(context.Background(), storage.RearchiveJobs{
+		"job": storage.RearchiveJob{
+			Id:          "job",
+			Status:      storage.JobStatusRunning,
+			From:        blobtest.StartSlot + 1,
+			To:          blobtest.StartSlot + 4,
+			NextSlot:    blobtest.StartSlot + 3,
+			Processed:   2,
+			FailedSlots: []uint64{},
+		},
+		"done": storage.RearchiveJob{
+			Id:          "done",
+			Status:      storage.JobStatusCompleted,
+			From:        blobtest.StartSlot + 5,
+			To:          blobtest.StartSlot + 5,
+			NextSlot:    blobtest.StartSlot + 6,
+			Processed:   1,
+			FailedSlots: []uint64{},
+		},
+	}))
+
+	require.NoError(t, svc.resumeRearchiveJobs(context.Background()))
+
+	job := waitForJob(t, svc, "job")
+	require.Equal(t, storage.JobStatusCompleted, job.Status)
+	require.Equal(t, uint64(4), job.Processed)
+
+	fs.CheckNotExistsOrFail(t, blobtest.One)
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	fs.CheckExistsOrFail(t, blobtest.Four)
+	fs.CheckNotExistsOrFail(t, blobtest.Five)
+}
+
+func TestArchiver_CancelRearchiveJob(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	_, err := svc.CreateRearchiveJob(context.Background(), blobtest.StartSlot, blobtest.StartSlot+1, false)
+	require.ErrorIs(t, err, ErrNotLeader)
+
+	require.NoError(t, fs.WriteRearchiveJobs(context.Background(), storage.RearchiveJobs{
+		"job": storage.RearchiveJob{
+			Id:          "job",
+			Status:      storage.JobStatusPending,
+			From:        blobtest.StartSlot + 1,
+			To:          blobtest.StartSlot + 4,
+			NextSlot:    blobtest.StartSlot + 1,
+			FailedSlots: []uint64{},
+		},
+	}))
+
+	job, err := svc.CancelRearchiveJob(context.Background(), "job")
+	require.NoError(t, err)
+	require.Equal(t, storage.JobStatusCanceled, job.Status)
+
+	_, err = svc.CancelRearchiveJob(context.Background(), "job")
+	require.ErrorIs(t, err, ErrJobFinished)
+	_, err = svc.CancelRearchiveJob(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrJobNotFound)
+
+	// A canceled job is not resumed
+	require.NoError(t, svc.resumeRearchiveJobs(context.Background()))
+	stored, err := fs.ReadRearchiveJobs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, storage.JobStatusCanceled, stored["job"].Status)
+	fs.CheckNotExistsOrFail(t, blobtest.One)
+}
+
+func TestArchiver_StandbyTracksHeadWithoutWriting(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.cfg.Standby = true
+
+	leader := storage.Lockfile{
+		ArchiverId: "LEADER",
+		Timestamp:  time.Now().Unix(),
+		HeadRoot:   blobtest.Three,
+		HeadSlot:   blobtest.StartSlot + 3,
+	}
+
+	svc.trackHeadInStandby(context.Background(), leader)
+
+	status := svc.Status()
+	require.True(t, status.Standby)
+	require.False(t, status.Leader)
+	require.Equal(t, "LEADER", status.LeaderId)
+	require.Equal(t, blobtest.StartSlot+5, status.HeadSlot)
+	require.Equal(t, blobtest.Three, status.LeaderHeadRoot)
+	require.Equal(t, uint64(2), status.LagSlots)
+
+	// Nothing should have been written while in standby
+	fs.CheckNotExistsOrFail(t, blobtest.Five)
+}
+
+func TestArchiver_StandbyTakesOverFromLeaderHead(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.cfg.Standby = true
+	svc.cfg.PollInterval = 100 * time.Millisecond
+
+	// The leader archived up to three, and its lock is about to expire
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.Three,
+		},
+		BlobSidecars: storage.BlobSidecars{
+			Data: beacon.Blobs[blobtest.Three.String()],
+		},
+	})
+	err := svc.dataStoreClient.WriteLockfile(context.Background(), storage.Lockfile{
+		ArchiverId: "LEADER",
+		Timestamp:  time.Now().Unix() - LockTimeout + 1,
+		HeadRoot:   blobtest.Three,
+		HeadSlot:   blobtest.StartSlot + 3,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leader := svc.waitObtainStorageLock(ctx)
+	require.Equal(t, "LEADER", leader.ArchiverId)
+	require.Equal(t, blobtest.Three, leader.HeadRoot)
+
+	status := svc.Status()
+	require.True(t, status.Leader)
+	require.False(t, status.Standby)
+	require.Equal(t, svc.id, status.LeaderId)
+
+	lockfile, err := svc.dataStoreClient.ReadLockfile(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, svc.id, lockfile.ArchiverId)
+	// Until a new head is archived, the previous leader's head is published
+	require.Equal(t, blobtest.Three, lockfile.HeadRoot)
+
+	// Catching up walks from the head back to the leader head
+	svc.catchUpToLeaderHead(ctx, leader)
+	fs.CheckExistsOrFail(t, blobtest.Five)
+	fs.CheckExistsOrFail(t, blobtest.Four)
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+	require.Equal(t, blobtest.StartSlot+5, svc.Status().LeaderHeadSlot)
+}
+
+func TestArchiver_StandbyCatchUpFillsHolesAboveLeaderHead(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// The leader archived two and four, but not three
+	for _, hash := range []common.Hash{blobtest.Two, blobtest.Four} {
+		fs.WriteOrFail(t, storage.BlobData{
+			Header: storage.Header{
+				BeaconBlockHash: hash,
+			},
+			BlobSidecars: storage.BlobSidecars{
+				Data: beacon.Blobs[hash.String()],
+			},
+		})
+	}
+
+	svc.catchUpToLeaderHead(context.Background(), storage.Lockfile{
+		ArchiverId: "LEADER",
+		HeadRoot:   blobtest.Two,
+		HeadSlot:   blobtest.StartSlot + 2,
+	})
+
+	// The walk continues past four to the leader head
+	fs.CheckExistsOrFail(t, blobtest.Five)
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	fs.CheckNotExistsOrFail(t, blobtest.One)
+}
+
+func TestArchiver_StandbyCatchUpStopsAtLeaderHeadSlot(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// The leader head was reorged out, so the walk stops at its slot
+	svc.catchUpToLeaderHead(context.Background(), storage.Lockfile{
+		ArchiverId: "LEADER",
+		HeadRoot:   common.Hash{0xaa},
+		HeadSlot:   blobtest.StartSlot + 3,
+	})
+
+	fs.CheckExistsOrFail(t, blobtest.Five)
+	fs.CheckExistsOrFail(t, blobtest.Four)
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
 }
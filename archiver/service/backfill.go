@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// isBlockNotFound returns true if the beacon node responded that the requested block does not exist, e.g. because the
+// slot was missed.
+func isBlockNotFound(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == 404
+}
+
+// backfillChunkRef identifies a chunk of a backfill process.
+type backfillChunkRef struct {
+	process common.Hash
+	index   int
+}
+
+// originSlot fetches the slot of the configured origin block, retrying until it succeeds or the context is done.
+func (a *Archiver) originSlot(ctx context.Context) (uint64, error) {
+	for {
+		header, err := a.backfillClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+			Block: a.cfg.OriginBlock.String(),
+		})
+		if err == nil {
+			slot := uint64(header.Data.Header.Message.Slot)
+			a.originMu.Lock()
+			a.originSlotKnown = true
+			a.originSlotValue = slot
+			a.originMu.Unlock()
+			return slot, nil
+		}
+
+		a.log.Error("failed to fetch origin block header, will retry", "err", err, "hash", a.cfg.OriginBlock.String())
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(backfillErrorRetryInterval):
+		}
+	}
+}
+
+// planBackfillChunks splits the slots below the current block of a backfill process, down to and including the origin
+// slot, into chunks of at most size slots. Chunks are ordered from the highest slot to the lowest.
+func planBackfillChunks(current v1.BeaconBlockHeader, originSlot uint64, size uint64) []storage.BackfillChunk {
+	chunks := make([]storage.BackfillChunk, 0)
+
+	currentSlot := uint64(current.Header.Message.Slot)
+	if currentSlot <= originSlot {
+		return chunks
+	}
+
+	for high := currentSlot - 1; ; {
+		low := originSlot
+		if high-originSlot+1 > size {
+			low = high - size + 1
+		}
+
+		chunks = append(chunks, storage.BackfillChunk{StartSlot: high, EndSlot: low, CurrentSlot: high})
+		if low == originSlot {
+			return chunks
+		}
+		high = low - 1
+	}
+}
+
+// backfillParallel fills the given backfill processes concurrently. The slot range of each process, from its current
+// block down to the origin block, is split into chunks that are processed by a pool of workers. Like the sequential
+// backfill, a chunk stops at the first block that is already archived, while the other chunks of the process are still
+// filled. Each chunk records its own progress in the backfill process, so that after a restart every chunk resumes
+// independently.
+func (a *Archiver) backfillParallel(ctx context.Context, starts []common.Hash) {
+	originSlot, err := a.originSlot(ctx)
+	if err != nil {
+		a.log.Error("failed to fetch origin slot, stopping backfill", "err", err)
+		return
+	}
+
+	runs := make(map[common.Hash]context.Context)
+	for _, start := range starts {
+		runCtx, done, ok := a.startBackfillRun(ctx, start)
+		if !ok {
+			continue
+		}
+		defer done()
+		runs[start] = runCtx
+	}
+
+	a.backfillMu.Lock()
+	var queue []backfillChunkRef
+	for start := range runs {
+		process, ok := a.backfillProcesses[start]
+		if !ok {
+			continue
+		}
+
+		if process.Chunks == nil {
+			if common.Hash(process.Current.Root) == a.cfg.OriginBlock {
+				process.Chunks = make([]storage.BackfillChunk, 0)
+			} else {
+				process.Chunks = planBackfillChunks(process.Current, originSlot, a.cfg.BackfillChunkSize)
+			}
+			a.backfillProcesses[start] = process
+		}
+
+		a.log.Info("backfill process initiated",
+			"startHash", start.String(),
+			"startSlot", process.Start.Header.Message.Slot,
+			"chunks", len(process.Chunks),
+		)
+
+		for i, chunk := range process.Chunks {
+			if !chunk.Done {
+				queue = append(queue, backfillChunkRef{process: start, index: i})
+			}
+		}
+	}
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+
+	// Fill the gaps closest to the head first
+	sort.SliceStable(queue, func(i, j int) bool {
+		return a.backfillProcesses[queue[i].process].Chunks[queue[i].index].StartSlot > a.backfillProcesses[queue[j].process].Chunks[queue[j].index].StartSlot
+	})
+
+	for start := range runs {
+		a.completeBackfillProcessIfDone(ctx, start)
+	}
+	a.backfillMu.Unlock()
+
+	work := make(chan backfillChunkRef)
+	var wg sync.WaitGroup
+	for i := 0; i < a.cfg.BackfillWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ref := range work {
+				a.backfillChunk(ctx, runs[ref.process], ref)
+			}
+		}()
+	}
+
+queueLoop:
+	for _, ref := range queue {
+		select {
+		case work <- ref:
+		case <-ctx.Done():
+			break queueLoop
+		}
+	}
+	close(work)
+	wg.Wait()
+}
+
+// backfillChunk persists the blobs for every slot in a chunk, from its current slot down to its end slot. It stops
+// when runCtx is done, which happens if the process is paused or canceled.
+func (a *Archiver) backfillChunk(ctx context.Context, runCtx context.Context, ref backfillChunkRef) {
+	a.backfillMu.Lock()
+	process, ok := a.backfillProcesses[ref.process]
+	if !ok || process.Chunks[ref.index].Done {
+		a.backfillMu.Unlock()
+		return
+	}
+	chunk := process.Chunks[ref.index]
+	a.backfillMu.Unlock()
+
+	a.log.Debug("backfilling chunk", "startHash", ref.process.String(), "fromSlot", chunk.CurrentSlot, "toSlot", chunk.EndSlot)
+
+	count := 0
+	slot := chunk.CurrentSlot
+	for {
+		if runCtx.Err() != nil {
+			if count > 0 {
+				a.updateBackfillChunk(ctx, ref, slot, false)
+			}
+			return
+		}
+
+		// The process may have been canceled
+		a.backfillMu.Lock()
+		_, ok = a.backfillProcesses[ref.process]
+		a.backfillMu.Unlock()
+		if !ok {
+			return
+		}
+
+		id := strconv.FormatUint(slot, 10)
+		header, alreadyExists, err := a.persistBlobsForBlockWithClient(runCtx, a.backfillClient, id, false)
+		parked := errors.Is(err, errBlockParked)
+		if err != nil && !parked && !isBlockNotFound(err) {
+			if runCtx.Err() != nil {
+				continue
+			}
+			a.log.Error("failed to persist blobs for slot, will retry", "err", err, "slot", slot)
+			select {
+			case <-runCtx.Done():
+			case <-time.After(backfillErrorRetryInterval):
+			}
+			continue
+		}
+
+		if header != nil && alreadyExists {
+			a.log.Info("backfill reached archived block", "startHash", ref.process.String(), "hash", header.Root.String(), "slot", slot)
+			a.updateBackfillChunk(ctx, ref, slot, true)
+			return
+		}
+
+		if header != nil && !parked {
+			a.metrics.RecordProcessedBlock(metrics.BlockSourceBackfill)
+		}
+
+		if slot == chunk.EndSlot {
+			a.updateBackfillChunk(ctx, ref, slot, true)
+			return
+		}
+
+		slot--
+		count++
+		if count%10 == 0 {
+			a.updateBackfillChunk(ctx, ref, slot, false)
+		}
+	}
+}
+
+// updateBackfillChunk records the progress of a chunk and persists the backfill processes.
+func (a *Archiver) updateBackfillChunk(ctx context.Context, ref backfillChunkRef, current uint64, done bool) {
+	a.backfillMu.Lock()
+	process, ok := a.backfillProcesses[ref.process]
+	if !ok {
+		a.backfillMu.Unlock()
+		return
+	}
+
+	process.Chunks[ref.index].CurrentSlot = current
+	process.Chunks[ref.index].Done = process.Chunks[ref.index].Done || done
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+	a.completeBackfillProcessIfDone(ctx, ref.process)
+	_, ok = a.backfillProcesses[ref.process]
+	// The chunks are shared with backfillProcesses, which other chunks keep updating once the lock is released
+	process.Chunks = slices.Clone(process.Chunks)
+	a.backfillMu.Unlock()
+
+	if ok {
+		a.recordBackfillProgress(ctx, ref.process, process)
+	}
+}
+
+// completeBackfillProcessIfDone removes a backfill process once all of its chunks are done. The caller must hold
+// backfillMu.
+func (a *Archiver) completeBackfillProcessIfDone(ctx context.Context, start common.Hash) {
+	process, ok := a.backfillProcesses[start]
+	if !ok {
+		return
+	}
+
+	for _, chunk := range process.Chunks {
+		if !chunk.Done {
+			return
+		}
+	}
+
+	a.log.Info("backfill process complete",
+		"startHash", start.String(),
+		"startSlot", process.Start.Header.Message.Slot,
+	)
+	delete(a.backfillProcesses, start)
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+	a.completeBackfillProgress(start)
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BackfillProgress describes the progress of a backfill process towards the origin block.
+type BackfillProgress struct {
+	StartHash common.Hash `json:"start_hash"`
+	StartSlot uint64      `json:"start_slot"`
+	Paused    bool        `json:"paused"`
+	// CurrentSlot is the slot the process has walked back to. For a parallel backfill it is the highest slot that is
+	// still to be filled.
+	CurrentSlot uint64 `json:"current_slot"`
+	Chunks      int    `json:"chunks,omitempty"`
+	ChunksDone  int    `json:"chunks_done,omitempty"`
+	// OriginSlot and RemainingBlocks are omitted if the origin block could not be fetched from the beacon node.
+	// RemainingBlocks counts slots, so it is an upper bound if some slots were missed.
+	OriginSlot          *uint64    `json:"origin_slot,omitempty"`
+	RemainingBlocks     *uint64    `json:"remaining_blocks,omitempty"`
+	BlocksPerSecond     float64    `json:"blocks_per_second"`
+	LastProgress        *time.Time `json:"last_progress,omitempty"`
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+}
+
+// backfillRate tracks how fast a backfill process approaches the origin block since this archiver started it.
+type backfillRate struct {
+	observedAt        time.Time
+	observedRemaining uint64
+	remaining         uint64
+	lastProgress      time.Time
+}
+
+// blocksPerSecond returns the average throughput of the process since it was first observed.
+func (r *backfillRate) blocksPerSecond(now time.Time) float64 {
+	elapsed := now.Sub(r.observedAt).Seconds()
+	if elapsed <= 0 || r.remaining >= r.observedRemaining {
+		return 0
+	}
+	return float64(r.observedRemaining-r.remaining) / elapsed
+}
+
+// remainingBackfillSlots returns the number of slots a backfill process still has to walk to reach the origin slot.
+func remainingBackfillSlots(process storage.BackfillProcess, originSlot uint64) uint64 {
+	if process.Chunks != nil {
+		remaining := uint64(0)
+		for _, chunk := range process.Chunks {
+			if !chunk.Done && chunk.CurrentSlot >= chunk.EndSlot {
+				remaining += chunk.CurrentSlot - chunk.EndSlot + 1
+			}
+		}
+		return remaining
+	}
+
+	current := uint64(process.Current.Header.Message.Slot)
+	if current <= originSlot {
+		return 0
+	}
+	return current - originSlot
+}
+
+// lookupOriginSlot returns the slot of the origin block. Unlike originSlot it does not retry, and returns false if the
+// slot is not known yet and the beacon node could not be reached.
+func (a *Archiver) lookupOriginSlot(ctx context.Context) (uint64, bool) {
+	a.originMu.Lock()
+	defer a.originMu.Unlock()
+
+	if a.originSlotKnown {
+		return a.originSlotValue, true
+	}
+
+	header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: a.cfg.OriginBlock.String(),
+	})
+	if err != nil {
+		a.log.Warn("failed to fetch origin block header", "err", err, "hash", a.cfg.OriginBlock.String())
+		return 0, false
+	}
+
+	a.originSlotKnown = true
+	a.originSlotValue = uint64(header.Data.Header.Message.Slot)
+	return a.originSlotValue, true
+}
+
+// writeBackfillProcesses persists the backfill processes and reports how many are active.
+func (a *Archiver) writeBackfillProcesses(ctx context.Context, backfillProcesses storage.BackfillProcesses) {
+	_ = a.dataStoreClient.WriteBackfillProcesses(ctx, backfillProcesses)
+	a.metrics.RecordBackfillProcesses(len(backfillProcesses))
+}
+
+// recordBackfillProgress updates the throughput and metrics of a backfill process after it made progress.
+func (a *Archiver) recordBackfillProgress(ctx context.Context, start common.Hash, process storage.BackfillProcess) {
+	originSlot, ok := a.lookupOriginSlot(ctx)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	remaining := remainingBackfillSlots(process, originSlot)
+
+	a.progressMu.Lock()
+	rate, ok := a.backfillRates[start]
+	if !ok {
+		rate = &backfillRate{observedAt: now, observedRemaining: remaining, remaining: remaining, lastProgress: now}
+		a.backfillRates[start] = rate
+	}
+	if remaining < rate.remaining {
+		rate.remaining = remaining
+		rate.lastProgress = now
+	}
+	blocksPerSecond := rate.blocksPerSecond(now)
+	lastProgress := rate.lastProgress
+	a.progressMu.Unlock()
+
+	a.metrics.RecordBackfillProgress(start.String(), remaining, blocksPerSecond, lastProgress)
+}
+
+// completeBackfillProgress stops tracking a backfill process once it is complete.
+func (a *Archiver) completeBackfillProgress(start common.Hash) {
+	a.progressMu.Lock()
+	delete(a.backfillRates, start)
+	a.progressMu.Unlock()
+
+	a.metrics.RemoveBackfillProgress(start.String())
+}
+
+// BackfillProgress returns the progress of all active backfill processes, the most recent first.
+func (a *Archiver) BackfillProgress(ctx context.Context) ([]BackfillProgress, error) {
+	backfillProcesses, err := a.dataStoreClient.ReadBackfillProcesses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	originSlot, originKnown := a.lookupOriginSlot(ctx)
+	now := time.Now()
+
+	result := make([]BackfillProgress, 0, len(backfillProcesses))
+	for start, process := range backfillProcesses {
+		progress := BackfillProgress{
+			StartHash:   start,
+			StartSlot:   uint64(process.Start.Header.Message.Slot),
+			CurrentSlot: uint64(process.Current.Header.Message.Slot),
+			Paused:      a.backfillPaused(start),
+		}
+
+		if process.Chunks != nil {
+			progress.Chunks = len(process.Chunks)
+			progress.CurrentSlot = 0
+			for _, chunk := range process.Chunks {
+				if chunk.Done {
+					progress.ChunksDone++
+				} else if chunk.CurrentSlot > progress.CurrentSlot {
+					progress.CurrentSlot = chunk.CurrentSlot
+				}
+			}
+		}
+
+		a.progressMu.Lock()
+		if rate, ok := a.backfillRates[start]; ok {
+			progress.BlocksPerSecond = rate.blocksPerSecond(now)
+			lastProgress := rate.lastProgress
+			progress.LastProgress = &lastProgress
+		}
+		a.progressMu.Unlock()
+
+		if originKnown {
+			origin := originSlot
+			remaining := remainingBackfillSlots(process, originSlot)
+			progress.OriginSlot = &origin
+			progress.RemainingBlocks = &remaining
+
+			if progress.BlocksPerSecond > 0 {
+				eta := now.Add(time.Duration(float64(remaining) / progress.BlocksPerSecond * float64(time.Second)))
+				progress.EstimatedCompletion = &eta
+			}
+		}
+
+		result = append(result, progress)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartSlot > result[j].StartSlot
+	})
+
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemainingBackfillSlots(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	five := *beacon.Headers[blobtest.Five.String()]
+	three := *beacon.Headers[blobtest.Three.String()]
+
+	// A sequential process walks from its current block to the origin
+	require.Equal(t, uint64(3), remainingBackfillSlots(storage.BackfillProcess{Start: five, Current: three}, blobtest.StartSlot))
+	require.Equal(t, uint64(0), remainingBackfillSlots(storage.BackfillProcess{Start: five, Current: three}, blobtest.StartSlot+4))
+
+	// A parallel process still has to fill the pending slots of every chunk
+	require.Equal(t, uint64(3), remainingBackfillSlots(storage.BackfillProcess{
+		Start:   five,
+		Current: five,
+		Chunks: []storage.BackfillChunk{
+			{StartSlot: blobtest.StartSlot + 4, EndSlot: blobtest.StartSlot + 3, CurrentSlot: blobtest.StartSlot + 3, Done: true},
+			{StartSlot: blobtest.StartSlot + 2, EndSlot: blobtest.StartSlot + 1, CurrentSlot: blobtest.StartSlot + 2},
+			{StartSlot: blobtest.StartSlot, EndSlot: blobtest.StartSlot, CurrentSlot: blobtest.StartSlot},
+		},
+	}, blobtest.StartSlot))
+}
+
+func TestArchiver_BackfillProgress(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 2, 2)
+
+	five := *beacon.Headers[blobtest.Five.String()]
+	process := storage.BackfillProcess{
+		Start:   five,
+		Current: five,
+		Chunks: []storage.BackfillChunk{
+			{StartSlot: blobtest.StartSlot + 4, EndSlot: blobtest.StartSlot + 3, CurrentSlot: blobtest.StartSlot + 4},
+			{StartSlot: blobtest.StartSlot + 2, EndSlot: blobtest.StartSlot + 1, CurrentSlot: blobtest.StartSlot + 2},
+			{StartSlot: blobtest.StartSlot, EndSlot: blobtest.StartSlot, CurrentSlot: blobtest.StartSlot},
+		},
+	}
+	processes := storage.BackfillProcesses{blobtest.Five: process}
+	require.NoError(t, fs.WriteBackfillProcesses(context.Background(), processes))
+
+	// Without any observed progress there is no throughput or estimate
+	progress, err := svc.BackfillProgress(context.Background())
+	require.NoError(t, err)
+	require.Len(t, progress, 1)
+	require.Equal(t, blobtest.Five, progress[0].StartHash)
+	require.Equal(t, blobtest.StartSlot+5, progress[0].StartSlot)
+	require.Equal(t, blobtest.StartSlot+4, progress[0].CurrentSlot)
+	require.Equal(t, 3, progress[0].Chunks)
+	require.Equal(t, blobtest.StartSlot, *progress[0].OriginSlot)
+	require.Equal(t, uint64(5), *progress[0].RemainingBlocks)
+	require.Zero(t, progress[0].BlocksPerSecond)
+	require.Nil(t, progress[0].EstimatedCompletion)
+
+	svc.recordBackfillProgress(context.Background(), blobtest.Five, process)
+
+	// The first chunk is completed, which yields a throughput and an estimate for the rest
+	process.Chunks[0].CurrentSlot = blobtest.StartSlot + 3
+	process.Chunks[0].Done = true
+	require.NoError(t, fs.WriteBackfillProcesses(context.Background(), processes))
+	svc.backfillRates[blobtest.Five].observedAt = time.Now().Add(-time.Second)
+	svc.recordBackfillProgress(context.Background(), blobtest.Five, process)
+
+	progress, err = svc.BackfillProgress(context.Background())
+	require.NoError(t, err)
+	require.Len(t, progress, 1)
+	require.Equal(t, blobtest.StartSlot+2, progress[0].CurrentSlot)
+	require.Equal(t, 1, progress[0].ChunksDone)
+	require.Equal(t, uint64(3), *progress[0].RemainingBlocks)
+	require.Greater(t, progress[0].BlocksPerSecond, float64(0))
+	require.NotNil(t, progress[0].LastProgress)
+	require.NotNil(t, progress[0].EstimatedCompletion)
+	require.True(t, progress[0].EstimatedCompletion.After(time.Now()))
+
+	svc.completeBackfillProgress(blobtest.Five)
+	require.Empty(t, svc.backfillRates)
+}
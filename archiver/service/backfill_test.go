@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/archiver/flags"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/storage/storagetest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func setupParallel(t *testing.T, beacon *beacontest.StubBeaconClient, workers int, chunkSize uint64) (*Archiver, *storagetest.TestFileStorage) {
+	l := testlog.Logger(t, log.LvlInfo)
+	fs := storagetest.NewTestFileStorage(t, l)
+	m := metrics.NewMetrics()
+
+	svc, err := NewArchiver(l, flags.ArchiverConfig{
+		PollInterval:      5 * time.Second,
+		OriginBlock:       blobtest.OriginBlock,
+		BackfillWorkers:   workers,
+		BackfillChunkSize: chunkSize,
+	}, fs, beacon, m)
+	require.NoError(t, err)
+	return svc, fs
+}
+
+func TestPlanBackfillChunks(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	five := *beacon.Headers[blobtest.Five.String()]
+
+	// Five is at StartSlot+5, the origin at StartSlot
+	chunks := planBackfillChunks(five, blobtest.StartSlot, 2)
+	require.Equal(t, []storage.BackfillChunk{
+		{StartSlot: blobtest.StartSlot + 4, EndSlot: blobtest.StartSlot + 3, CurrentSlot: blobtest.StartSlot + 4},
+		{StartSlot: blobtest.StartSlot + 2, EndSlot: blobtest.StartSlot + 1, CurrentSlot: blobtest.StartSlot + 2},
+		{StartSlot: blobtest.StartSlot, EndSlot: blobtest.StartSlot, CurrentSlot: blobtest.StartSlot},
+	}, chunks)
+
+	// A single chunk if the range fits
+	chunks = planBackfillChunks(five, blobtest.StartSlot, 100)
+	require.Equal(t, []storage.BackfillChunk{
+		{StartSlot: blobtest.StartSlot + 4, EndSlot: blobtest.StartSlot, CurrentSlot: blobtest.StartSlot + 4},
+	}, chunks)
+
+	// Nothing to do at the origin
+	origin := *beacon.Headers[blobtest.OriginBlock.String()]
+	require.Empty(t, planBackfillChunks(origin, blobtest.StartSlot, 2))
+}
+
+func TestArchiver_ParallelBackfillToOrigin(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 3, 2)
+
+	// We have the current head, which is block 5 written to storage
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.Five,
+		},
+		BlobSidecars: storage.BlobSidecars{
+			Data: beacon.Blobs[blobtest.Five.String()],
+		},
+	})
+
+	expectedBlobs := []common.Hash{blobtest.Four, blobtest.Three, blobtest.Two, blobtest.One, blobtest.OriginBlock}
+	for _, blob := range expectedBlobs {
+		fs.CheckNotExistsOrFail(t, blob)
+	}
+
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	for _, blob := range expectedBlobs {
+		fs.CheckExistsOrFail(t, blob)
+		data := fs.ReadOrFail(t, blob)
+		require.Equal(t, data.BlobSidecars.Data, beacon.Blobs[blob.String()])
+	}
+
+	processes, err := fs.ReadBackfillProcesses(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, processes)
+}
+
+func TestArchiver_ParallelBackfillSkipsMissedSlots(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 2, 2)
+
+	// The slot of block two was missed
+	delete(beacon.Headers, "12")
+	delete(beacon.Blobs, "12")
+
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.Five,
+		},
+	})
+
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	for _, blob := range []common.Hash{blobtest.Four, blobtest.Three, blobtest.One, blobtest.OriginBlock} {
+		fs.CheckExistsOrFail(t, blob)
+	}
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+}
+
+func TestArchiver_ParallelBackfillStopsAtExistingBlock(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 2, 2)
+
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.Five,
+		},
+		BlobSidecars: storage.BlobSidecars{
+			Data: beacon.Blobs[blobtest.Five.String()],
+		},
+	})
+
+	// We also have block 1 written to storage
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.One,
+		},
+		BlobSidecars: storage.BlobSidecars{
+			Data: beacon.Blobs[blobtest.One.String()],
+		},
+	})
+
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	// We expect to backfill all blobs between 5 and 1
+	for _, blob := range []common.Hash{blobtest.Four, blobtest.Three, blobtest.Two} {
+		fs.CheckExistsOrFail(t, blob)
+		data := fs.ReadOrFail(t, blob)
+		require.Equal(t, data.BlobSidecars.Data, beacon.Blobs[blob.String()])
+	}
+
+	processes, err := fs.ReadBackfillProcesses(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, processes)
+}
+
+func TestArchiver_ParallelBackfillFillsChunksBelowExistingBlock(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 2, 2)
+
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.Five,
+		},
+	})
+
+	// Block 4 is archived, which stops the first chunk (4, 3) only
+	fs.WriteOrFail(t, storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: blobtest.Four,
+		},
+	})
+
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	fs.CheckNotExistsOrFail(t, blobtest.Three)
+	for _, blob := range []common.Hash{blobtest.Two, blobtest.One, blobtest.OriginBlock} {
+		fs.CheckExistsOrFail(t, blob)
+	}
+
+	processes, err := fs.ReadBackfillProcesses(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, processes)
+}
+
+func TestArchiver_ParallelBackfillResumesChunks(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setupParallel(t, beacon, 2, 2)
+
+	// A previous run completed the first chunk and processed the first slot of the second chunk
+	five := *beacon.Headers[blobtest.Five.String()]
+	processes := storage.BackfillProcesses{
+		blobtest.Five: storage.BackfillProcess{
+			Start:   five,
+			Current: five,
+			Chunks: []storage.BackfillChunk{
+				{StartSlot: blobtest.StartSlot + 4, EndSlot: blobtest.StartSlot + 3, CurrentSlot: blobtest.StartSlot + 3, Done: true},
+				{StartSlot: blobtest.StartSlot + 2, EndSlot: blobtest.StartSlot + 1, CurrentSlot: blobtest.StartSlot + 1},
+				{StartSlot: blobtest.StartSlot, EndSlot: blobtest.StartSlot, CurrentSlot: blobtest.StartSlot},
+			},
+		},
+	}
+	require.NoError(t, fs.WriteBackfillProcesses(context.Background(), processes))
+
+	svc.backfillProcesses = processes
+	svc.backfillParallel(context.Background(), []common.Hash{blobtest.Five})
+
+	// Only the remaining slots are filled
+	fs.CheckExistsOrFail(t, blobtest.One)
+	fs.CheckExistsOrFail(t, blobtest.OriginBlock)
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+	fs.CheckNotExistsOrFail(t, blobtest.Three)
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+
+	stored, err := fs.ReadBackfillProcesses(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// segmentPruneDelay is how long the per-block objects of a sealed segment are kept, so that API instances reload the
+// segment catalog before the blocks disappear from the per-block namespace.
+const segmentPruneDelay = 10 * time.Minute
+
+// segmentStore is implemented by storage that packs finalized blocks into segment files, see storage.SegmentedStorage.
+type segmentStore interface {
+	SegmentSlots() uint64
+	Catalog() storage.SegmentCatalog
+	Seal(ctx context.Context, number uint64, blocks []storage.SegmentBlock) error
+	PruneSealed(ctx context.Context, delay time.Duration) error
+}
+
+// runCompactor seals finalized segments at the configured interval, until the context is done. It does nothing if the
+// storage does not use segments.
+func (a *Archiver) runCompactor(ctx context.Context) {
+	segments, ok := a.dataStoreClient.(segmentStore)
+	if !ok {
+		return
+	}
+
+	t := time.NewTicker(a.cfg.CompactionInterval)
+	defer t.Stop()
+
+	for {
+		a.compactSegments(ctx, segments)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// compactSegments seals every segment after the latest sealed one that is finalized, then prunes the per-block objects
+// of segments that were sealed long enough ago. Segments are sealed in order, starting from the segment of the origin
+// block. A segment whose blocks are not all archived yet, e.g. because the backfill has not reached it, is retried
+// later.
+func (a *Archiver) compactSegments(ctx context.Context, segments segmentStore) {
+	finalized, err := a.backfillClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: "finalized"})
+	if err != nil {
+		a.log.Error("failed to fetch finalized header for compaction", "err", err)
+		return
+	}
+	origin, err := a.backfillClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: a.cfg.OriginBlock.String()})
+	if err != nil {
+		a.log.Error("failed to fetch origin header for compaction", "err", err)
+		return
+	}
+	finalizedSlot := uint64(finalized.Data.Header.Message.Slot)
+	originSlot := uint64(origin.Data.Header.Message.Slot)
+
+	slots := segments.SegmentSlots()
+	next := originSlot / slots
+	if sealed := segments.Catalog().Segments; len(sealed) > 0 {
+		next = sealed[len(sealed)-1].Number + 1
+	}
+
+	for (next+1)*slots-1 <= finalizedSlot {
+		blocks, err := a.segmentBlocks(ctx, max(next*slots, originSlot), (next+1)*slots-1)
+		if err != nil {
+			a.log.Error("failed to resolve blocks of segment", "segment", next, "err", err)
+			return
+		}
+
+		err = segments.Seal(ctx, next, blocks)
+		if errors.Is(err, storage.ErrNotFound) {
+			a.log.Info("segment is not fully archived yet, retrying later", "segment", next, "err", err)
+			break
+		}
+		if err != nil {
+			a.log.Error("failed to seal segment", "segment", next, "err", err)
+			return
+		}
+
+		a.metrics.RecordSealedSegments(len(segments.Catalog().Segments))
+		next++
+	}
+
+	if err := segments.PruneSealed(ctx, segmentPruneDelay); err != nil {
+		a.log.Error("failed to prune sealed segments", "err", err)
+	}
+}
+
+// segmentBlocks returns the canonical blocks from fromSlot to toSlot (inclusive).
+func (a *Archiver) segmentBlocks(ctx context.Context, fromSlot, toSlot uint64) ([]storage.SegmentBlock, error) {
+	var blocks []storage.SegmentBlock
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		header, err := a.backfillClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: strconv.FormatUint(slot, 10)})
+		if isBlockNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, storage.SegmentBlock{Slot: slot, Root: common.Hash(header.Data.Root)})
+	}
+	return blocks, nil
+}
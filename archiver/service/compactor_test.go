@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/archiver/flags"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/storage/storagetest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSegments(t *testing.T, beacon *beacontest.StubBeaconClient) (*Archiver, *storagetest.TestFileStorage, *storage.SegmentedStorage) {
+	l := testlog.Logger(t, log.LvlInfo)
+	fs := storagetest.NewTestFileStorage(t, l)
+	segments, err := storage.NewSegmentedStorage(context.Background(), fs, 4, l)
+	require.NoError(t, err)
+
+	svc, err := NewArchiver(l, flags.ArchiverConfig{
+		PollInterval:       5 * time.Second,
+		CompactionInterval: time.Minute,
+		OriginBlock:        blobtest.OriginBlock,
+	}, segments, beacon, metrics.NewMetrics())
+	require.NoError(t, err)
+	return svc, fs, segments
+}
+
+func TestArchiver_CompactSegments(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs, segments := setupSegments(t, beacon)
+
+	// Nothing is sealed until the blocks of the finalized segment are archived
+	svc.compactSegments(context.Background(), segments)
+	require.Empty(t, segments.Catalog().Segments)
+
+	for _, hash := range []common.Hash{blobtest.OriginBlock, blobtest.One, blobtest.Two, blobtest.Three, blobtest.Four, blobtest.Five} {
+		fs.WriteOrFail(t, storage.BlobData{
+			Header:       storage.Header{BeaconBlockHash: hash},
+			BlobSidecars: storage.BlobSidecars{Data: beacon.Blobs[hash.String()]},
+		})
+	}
+
+	// The origin is at slot 10 and the finalized block at slot 13, so only the segment of slots 8-11 is sealed, holding
+	// the origin and the following block
+	svc.compactSegments(context.Background(), segments)
+	catalog := segments.Catalog()
+	require.Len(t, catalog.Segments, 1)
+	require.Equal(t, uint64(2), catalog.Segments[0].Number)
+	require.Equal(t, uint64(8), catalog.Segments[0].FirstSlot)
+	require.Equal(t, uint64(11), catalog.Segments[0].LastSlot)
+	require.Equal(t, 2, catalog.Segments[0].Blocks)
+	require.False(t, catalog.Segments[0].Pruned)
+
+	// Sealing again is a no-op
+	svc.compactSegments(context.Background(), segments)
+	require.Len(t, segments.Catalog().Segments, 1)
+
+	// Once the following segment is finalized it is sealed too
+	beacon.Headers["finalized"] = beacon.Headers[blobtest.Five.String()]
+	svc.compactSegments(context.Background(), segments)
+	catalog = segments.Catalog()
+	require.Len(t, catalog.Segments, 2)
+	require.Equal(t, uint64(3), catalog.Segments[1].Number)
+	require.Equal(t, 4, catalog.Segments[1].Blocks)
+
+	// Sealed blocks can still be read through the segmented storage
+	data, err := segments.ReadBlob(context.Background(), blobtest.Three)
+	require.NoError(t, err)
+	require.Equal(t, blobtest.Three, data.Header.BeaconBlockHash)
+}
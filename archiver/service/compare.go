@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/base-org/blob-archiver/common/storage"
+)
+
+// diffBlobData compares stored blob data with the blob data served by the beacon node, sidecar by sidecar. It returns
+// a description of every difference, or nothing if the data is identical.
+func diffBlobData(stored storage.BlobData, fetched storage.BlobData) []string {
+	differences := make([]string, 0)
+
+	if stored.Header != fetched.Header {
+		differences = append(differences, "header")
+	}
+
+	storedSidecars, fetchedSidecars := stored.BlobSidecars.Data, fetched.BlobSidecars.Data
+	if len(storedSidecars) != len(fetchedSidecars) {
+		differences = append(differences, fmt.Sprintf("sidecar count: stored %d, beacon node %d", len(storedSidecars), len(fetchedSidecars)))
+	}
+
+	for i := 0; i < min(len(storedSidecars), len(fetchedSidecars)); i++ {
+		s, f := storedSidecars[i], fetchedSidecars[i]
+		if s == nil || f == nil {
+			if s != f {
+				differences = append(differences, fmt.Sprintf("sidecar %d: missing", i))
+			}
+			continue
+		}
+
+		if s.Index != f.Index {
+			differences = append(differences, fmt.Sprintf("sidecar %d: index", i))
+		}
+		if s.KZGCommitment != f.KZGCommitment {
+			differences = append(differences, fmt.Sprintf("sidecar %d: kzg_commitment", i))
+		}
+		if s.KZGProof != f.KZGProof {
+			differences = append(differences, fmt.Sprintf("sidecar %d: kzg_proof", i))
+		}
+		if s.Blob != f.Blob {
+			differences = append(differences, fmt.Sprintf("sidecar %d: blob", i))
+		}
+		if !reflect.DeepEqual(s.SignedBlockHeader, f.SignedBlockHeader) {
+			differences = append(differences, fmt.Sprintf("sidecar %d: signed_block_header", i))
+		}
+		if s.KZGCommitmentInclusionProof != f.KZGCommitmentInclusionProof {
+			differences = append(differences, fmt.Sprintf("sidecar %d: kzg_commitment_inclusion_proof", i))
+		}
+	}
+
+	return differences
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffBlobData(t *testing.T) {
+	sidecars := blobtest.NewBlobSidecars(t, 2)
+	data := storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.One},
+		BlobSidecars: storage.BlobSidecars{Data: sidecars},
+	}
+
+	require.Empty(t, diffBlobData(data, data))
+
+	// Every field of a sidecar is compared
+	changed := *sidecars[1]
+	changed.KZGCommitment[0] ^= 1
+	changed.KZGProof[0] ^= 1
+	changed.Blob[0] ^= 1
+	changed.KZGCommitmentInclusionProof[0][0] ^= 1
+	header := *changed.SignedBlockHeader
+	header.Signature[0] ^= 1
+	changed.SignedBlockHeader = &header
+
+	other := storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.Two},
+		BlobSidecars: storage.BlobSidecars{Data: []*deneb.BlobSidecar{sidecars[0], &changed}},
+	}
+	require.Equal(t, []string{
+		"header",
+		"sidecar 1: kzg_commitment",
+		"sidecar 1: kzg_proof",
+		"sidecar 1: blob",
+		"sidecar 1: signed_block_header",
+		"sidecar 1: kzg_commitment_inclusion_proof",
+	}, diffBlobData(data, other))
+
+	// A missing sidecar is reported once
+	other = storage.BlobData{
+		Header:       data.Header,
+		BlobSidecars: storage.BlobSidecars{Data: sidecars[:1]},
+	}
+	require.Equal(t, []string{"sidecar count: stored 2, beacon node 1"}, diffBlobData(data, other))
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// parkedRetryInterval is how often parked blocks are fetched again.
+const parkedRetryInterval = time.Minute
+
+// errBlobSidecarsDisputed is returned when the beacon nodes returned different blob sidecars for a block, and not
+// enough of them agree.
+var errBlobSidecarsDisputed = errors.New("beacon nodes disagree on blob sidecars")
+
+// errBlockParked is returned with the header of a block whose blob sidecars are disputed, and that was parked instead of
+// stored. Callers that walk the chain carry on with its parent, but must not count the block as stored.
+var errBlockParked = errors.New("block parked with disputed blob sidecars")
+
+// consensusBeaconClient fetches the blob sidecars of a block from every beacon node, and only returns them if at least
+// quorum nodes returned byte-identical sidecars. Every other request is sent to the wrapped client.
+type consensusBeaconClient struct {
+	BeaconClient
+	nodes   []beacon.Node
+	quorum  int
+	log     log.Logger
+	metrics metrics.Metricer
+}
+
+// newConsensusBeaconClient wraps the given client, which must send requests to at least quorum beacon nodes.
+func newConsensusBeaconClient(client BeaconClient, quorum int, l log.Logger, m metrics.Metricer) (*consensusBeaconClient, error) {
+	provider, ok := client.(beacon.NodesProvider)
+	if !ok {
+		return nil, fmt.Errorf("consensus quorum of %d requires several beacon nodes", quorum)
+	}
+	nodes := provider.Nodes()
+	if len(nodes) < quorum {
+		return nil, fmt.Errorf("consensus quorum of %d requires at least as many beacon nodes, got %d", quorum, len(nodes))
+	}
+
+	return &consensusBeaconClient{
+		BeaconClient: client,
+		nodes:        nodes,
+		quorum:       quorum,
+		log:          l,
+		metrics:      m,
+	}, nil
+}
+
+// sidecarsResponse is the response of a single beacon node. digest is the hash of the SSZ encoding of the sidecars.
+type sidecarsResponse struct {
+	res    *api.Response[[]*deneb.BlobSidecar]
+	digest common.Hash
+	err    error
+}
+
+// BlobSidecars implements client.BlobSidecarsProvider. It returns errBlobSidecarsDisputed if the beacon nodes returned
+// different sidecars and not enough of them agree. If the quorum is not reached only because some nodes failed, their
+// error is returned instead.
+func (c *consensusBeaconClient) BlobSidecars(ctx context.Context, opts *api.BlobSidecarsOpts) (*api.Response[[]*deneb.BlobSidecar], error) {
+	responses := make([]sidecarsResponse, len(c.nodes))
+	var wg sync.WaitGroup
+	for i, node := range c.nodes {
+		wg.Add(1)
+		go func(i int, node beacon.Node) {
+			defer wg.Done()
+			res, err := node.Client.BlobSidecars(ctx, opts)
+			if err == nil {
+				responses[i].digest, err = sidecarsDigest(res.Data)
+			}
+			responses[i].res, responses[i].err = res, err
+		}(i, node)
+	}
+	wg.Wait()
+
+	// Group the nodes by the sidecars they returned
+	votes := make(map[common.Hash][]int)
+	var lastErr error
+	for i, response := range responses {
+		if response.err != nil {
+			c.log.Warn("failed to fetch blob sidecars from beacon node", "node", c.nodes[i].Name, "block", opts.Block, "err", response.err)
+			lastErr = response.err
+			continue
+		}
+		votes[response.digest] = append(votes[response.digest], i)
+	}
+
+	digests := make([]common.Hash, 0, len(votes))
+	for digest := range votes {
+		digests = append(digests, digest)
+	}
+	// The largest group comes first, and the configured order of the nodes breaks ties
+	sort.Slice(digests, func(i, j int) bool {
+		a, b := votes[digests[i]], votes[digests[j]]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a[0] < b[0]
+	})
+
+	if len(digests) == 0 {
+		return nil, lastErr
+	}
+
+	majority := votes[digests[0]]
+	if len(digests) > 1 {
+		c.recordDisagreement(opts.Block, votes, digests)
+	}
+	if len(majority) >= c.quorum {
+		return responses[majority[0]].res, nil
+	}
+	if len(digests) == 1 {
+		return nil, fmt.Errorf("only %d of %d beacon nodes returned blob sidecars, %d required: %w", len(majority), len(c.nodes), c.quorum, lastErr)
+	}
+	return nil, errBlobSidecarsDisputed
+}
+
+// recordDisagreement logs the responses of the beacon nodes, and records the nodes that disagree with the largest group.
+func (c *consensusBeaconClient) recordDisagreement(block string, votes map[common.Hash][]int, digests []common.Hash) {
+	var responses []any
+	for _, digest := range digests {
+		for _, i := range votes[digest] {
+			responses = append(responses, c.nodes[i].Name, digest.String())
+		}
+	}
+	for _, digest := range digests[1:] {
+		for _, i := range votes[digest] {
+			c.metrics.RecordConsensusDisagreement(c.nodes[i].Name)
+		}
+	}
+
+	c.log.Warn("beacon nodes disagree on blob sidecars", append([]any{"block", block, "agreeing", len(votes[digests[0]]), "quorum", c.quorum}, responses...)...)
+}
+
+// sidecarsDigest hashes the SSZ encoding of the given sidecars, so that byte-identical responses have the same digest.
+func sidecarsDigest(sidecars []*deneb.BlobSidecar) (common.Hash, error) {
+	hasher := sha256.New()
+	for _, sidecar := range sidecars {
+		encoded, err := sidecar.MarshalSSZ()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		hasher.Write(encoded)
+	}
+	return common.Hash(hasher.Sum(nil)), nil
+}
+
+// parkBlock records a block whose blob sidecars are disputed, so that it is fetched again later instead of storing
+// disputed data.
+func (a *Archiver) parkBlock(ctx context.Context, header *v1.BeaconBlockHeader) {
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+
+	if err := a.loadChainState(ctx); err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	root := common.Hash(header.Root)
+	parked, ok := a.chain.Parked[root]
+	if !ok {
+		parked = storage.ParkedBlock{
+			Slot:     uint64(header.Header.Message.Slot),
+			ParkedAt: time.Now().Unix(),
+		}
+		a.log.Warn("parked block with disputed blob sidecars", "hash", root.String(), "slot", parked.Slot)
+	}
+	parked.Attempts++
+	a.chain.Parked[root] = parked
+	a.metrics.RecordParkedBlocks(len(a.chain.Parked))
+	a.writeChainState(ctx)
+}
+
+// retryParkedBlocks fetches the parked blocks again, and stores the ones that the beacon nodes now agree on. Blocks that
+// the beacon nodes no longer know are dropped.
+func (a *Archiver) retryParkedBlocks(ctx context.Context) {
+	a.chainMu.Lock()
+	err := a.loadChainState(ctx)
+	roots := make([]common.Hash, 0, len(a.chain.Parked))
+	for root := range a.chain.Parked {
+		roots = append(roots, root)
+	}
+	a.chainMu.Unlock()
+	if err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	for _, root := range roots {
+		// A block that is still disputed is parked again
+		_, _, err := a.persistBlobsForBlockWithClient(ctx, a.beaconClient, root.String(), false)
+		if errors.Is(err, errBlockParked) {
+			continue
+		}
+		if err != nil && !isBlockNotFound(err) {
+			a.log.Warn("failed to retry parked block", "err", err, "hash", root.String())
+			continue
+		}
+
+		a.chainMu.Lock()
+		exists, existsErr := a.dataStoreClient.Exists(ctx, root)
+		if existsErr == nil && (exists || isBlockNotFound(err)) {
+			delete(a.chain.Parked, root)
+			a.metrics.RecordParkedBlocks(len(a.chain.Parked))
+			a.writeChainState(ctx)
+			a.log.Info("unparked block", "hash", root.String(), "stored", exists)
+		}
+		a.chainMu.Unlock()
+	}
+}
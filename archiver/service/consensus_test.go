@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/archiver/flags"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/storage/storagetest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+// multiNodeBeaconClient is a beacon client with several beacon nodes, which serves headers from the first node.
+type multiNodeBeaconClient struct {
+	*beacontest.StubBeaconClient
+	nodes []*beacontest.StubBeaconClient
+}
+
+func (c *multiNodeBeaconClient) Nodes() []beacon.Node {
+	var nodes []beacon.Node
+	for i, node := range c.nodes {
+		nodes = append(nodes, beacon.Node{Name: string(rune('a' + i)), Client: node})
+	}
+	return nodes
+}
+
+// newMultiNodeBeaconClient returns a beacon client with the given number of nodes, which all serve the same chain.
+func newMultiNodeBeaconClient(t *testing.T, count int) *multiNodeBeaconClient {
+	chain := beacontest.NewDefaultStubBeaconClient(t)
+	c := &multiNodeBeaconClient{StubBeaconClient: chain}
+	for i := 0; i < count; i++ {
+		c.nodes = append(c.nodes, &beacontest.StubBeaconClient{
+			Headers: chain.Headers,
+			Blobs:   maps.Clone(chain.Blobs),
+		})
+	}
+	return c
+}
+
+func setupConsensus(t *testing.T, beacon *multiNodeBeaconClient, quorum int) (*Archiver, *storagetest.TestFileStorage) {
+	l := testlog.Logger(t, log.LvlInfo)
+	fs := storagetest.NewTestFileStorage(t, l)
+
+	svc, err := NewArchiver(l, flags.ArchiverConfig{
+		PollInterval:    5 * time.Second,
+		OriginBlock:     blobtest.OriginBlock,
+		ConsensusQuorum: quorum,
+	}, fs, beacon, metrics.NewMetrics())
+	require.NoError(t, err)
+	return svc, fs
+}
+
+func TestArchiver_ConsensusRequiresBeaconNodes(t *testing.T) {
+	l := testlog.Logger(t, log.LvlInfo)
+	fs := storagetest.NewTestFileStorage(t, l)
+
+	_, err := NewArchiver(l, flags.ArchiverConfig{ConsensusQuorum: 2}, fs, beacontest.NewDefaultStubBeaconClient(t), metrics.NewMetrics())
+	require.Error(t, err)
+
+	_, err = NewArchiver(l, flags.ArchiverConfig{ConsensusQuorum: 3}, fs, newMultiNodeBeaconClient(t, 2), metrics.NewMetrics())
+	require.Error(t, err)
+}
+
+func TestArchiver_ConsensusStoresMajority(t *testing.T) {
+	beacon := newMultiNodeBeaconClient(t, 3)
+	svc, fs := setupConsensus(t, beacon, 2)
+
+	// One node disagrees on Five
+	expected := beacon.nodes[0].Blobs[blobtest.Five.String()]
+	beacon.nodes[1].Blobs[blobtest.Five.String()] = blobtest.NewBlobSidecars(t, 1)
+
+	_, _, err := svc.persistBlobsForBlockToS3(context.Background(), blobtest.Five.String(), false)
+	require.NoError(t, err)
+
+	data := fs.ReadOrFail(t, blobtest.Five)
+	require.Equal(t, expected, data.BlobSidecars.Data)
+
+	// A node that fails does not count towards the quorum
+	delete(beacon.nodes[0].Blobs, blobtest.Four.String())
+	beacon.nodes[1].Blobs[blobtest.Four.String()] = blobtest.NewBlobSidecars(t, 1)
+	_, _, err = svc.persistBlobsForBlockToS3(context.Background(), blobtest.Four.String(), false)
+	require.ErrorIs(t, err, errBlockParked)
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+}
+
+func TestArchiver_ConsensusParksDisputedBlocks(t *testing.T) {
+	beacon := newMultiNodeBeaconClient(t, 2)
+	svc, fs := setupConsensus(t, beacon, 2)
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Two}})
+
+	// The nodes disagree on Four
+	disputed := beacon.nodes[1].Blobs[blobtest.Four.String()]
+	beacon.nodes[1].Blobs[blobtest.Four.String()] = []*deneb.BlobSidecar{}
+
+	svc.processBlocksUntilKnownBlock(context.Background())
+
+	// The live walk carries on past the parked block
+	fs.CheckExistsOrFail(t, blobtest.Five)
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, state.Parked, blobtest.Four)
+	require.Equal(t, blobtest.StartSlot+4, state.Parked[blobtest.Four].Slot)
+	require.Equal(t, 1, state.Parked[blobtest.Four].Attempts)
+	// Only stored blocks are tracked until they are finalized
+	unfinalized := readUnfinalized(t, fs)
+	require.Contains(t, unfinalized, blobtest.Five)
+	require.NotContains(t, unfinalized, blobtest.Four)
+
+	// Parked blocks are reported to the caller with their header
+	header, exists, err := svc.persistBlobsForBlockToS3(context.Background(), blobtest.Four.String(), false)
+	require.ErrorIs(t, err, errBlockParked)
+	require.False(t, exists)
+	require.Equal(t, blobtest.Four, common.Hash(header.Root))
+	state, err = fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, state.Parked[blobtest.Four].Attempts)
+
+	// Parked blocks stay parked while the nodes disagree
+	svc.retryParkedBlocks(context.Background())
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+	state, err = fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, state.Parked[blobtest.Four].Attempts)
+
+	beacon.nodes[1].Blobs[blobtest.Four.String()] = disputed
+	svc.retryParkedBlocks(context.Background())
+
+	data := fs.ReadOrFail(t, blobtest.Four)
+	require.Equal(t, disputed, data.BlobSidecars.Data)
+	state, err = fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, state.Parked)
+}
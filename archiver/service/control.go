@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrBackfillNotStarted = errors.New("backfill has not started yet")
+	ErrBackfillNotFound   = errors.New("backfill process not found")
+	ErrBackfillExists     = errors.New("backfill process already exists")
+)
+
+// backfillRun is a running backfill process. Canceling it stops the process, e.g. when it is paused.
+type backfillRun struct {
+	cancel context.CancelFunc
+}
+
+// startBackfillRun registers a run of the given backfill process. It returns false if the process is already running.
+// Otherwise, the returned context is done once the process is paused or canceled, and the returned function must be
+// called when the run ends.
+func (a *Archiver) startBackfillRun(ctx context.Context, start common.Hash) (context.Context, func(), bool) {
+	a.backfillMu.Lock()
+	defer a.backfillMu.Unlock()
+
+	if _, running := a.backfillRuns[start]; running {
+		return nil, nil, false
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &backfillRun{cancel: cancel}
+	a.backfillRuns[start] = run
+
+	return runCtx, func() {
+		cancel()
+		a.backfillMu.Lock()
+		if a.backfillRuns[start] == run {
+			delete(a.backfillRuns, start)
+		}
+		a.backfillMu.Unlock()
+	}, true
+}
+
+// stopBackfillRun stops the run of the given backfill process, if it is running. The caller must hold backfillMu.
+func (a *Archiver) stopBackfillRun(start common.Hash) {
+	if run, ok := a.backfillRuns[start]; ok {
+		run.cancel()
+		delete(a.backfillRuns, start)
+	}
+}
+
+// loadControlState reads the control state that was persisted by a previous run of the archiver.
+func (a *Archiver) loadControlState(ctx context.Context) error {
+	state, err := a.dataStoreClient.ReadControlState(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.controlMu.Lock()
+	a.control = state
+	a.controlMu.Unlock()
+
+	if state.LiveTrackingPaused {
+		a.log.Info("live tracking is paused")
+	}
+	return nil
+}
+
+// updateControlState applies the given change to the control state and persists it.
+func (a *Archiver) updateControlState(ctx context.Context, update func(state *storage.ControlState)) (storage.ControlState, error) {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	state := a.control
+	state.PausedBackfills = slices.Clone(a.control.PausedBackfills)
+	update(&state)
+
+	err := a.dataStoreClient.WriteControlState(ctx, state)
+	if err != nil {
+		return a.control, err
+	}
+
+	a.control = state
+	return state, nil
+}
+
+// ControlState returns the runtime controls that are currently in effect.
+func (a *Archiver) ControlState() storage.ControlState {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	state := a.control
+	state.PausedBackfills = slices.Clone(a.control.PausedBackfills)
+	return state
+}
+
+func (a *Archiver) liveTrackingPaused() bool {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+	return a.control.LiveTrackingPaused
+}
+
+func (a *Archiver) backfillPaused(start common.Hash) bool {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+	return slices.Contains(a.control.PausedBackfills, start)
+}
+
+// SetLiveTrackingPaused pauses or resumes polling the beacon node for new blocks. While paused, the storage lock is
+// still held, and once resumed the archiver catches up from the last archived block.
+func (a *Archiver) SetLiveTrackingPaused(ctx context.Context, paused bool) (storage.ControlState, error) {
+	state, err := a.updateControlState(ctx, func(state *storage.ControlState) {
+		state.LiveTrackingPaused = paused
+	})
+	if err != nil {
+		return state, err
+	}
+
+	a.log.Info("updated live tracking", "paused", paused)
+	return state, nil
+}
+
+// PauseBackfill stops a backfill process, keeping its progress so that it can be resumed later.
+func (a *Archiver) PauseBackfill(ctx context.Context, start common.Hash) (storage.ControlState, error) {
+	a.backfillMu.Lock()
+	defer a.backfillMu.Unlock()
+
+	if a.backfillProcesses == nil {
+		return a.ControlState(), ErrBackfillNotStarted
+	}
+	if _, ok := a.backfillProcesses[start]; !ok {
+		return a.ControlState(), ErrBackfillNotFound
+	}
+
+	state, err := a.updateControlState(ctx, func(state *storage.ControlState) {
+		if !slices.Contains(state.PausedBackfills, start) {
+			state.PausedBackfills = append(state.PausedBackfills, start)
+		}
+	})
+	if err != nil {
+		return state, err
+	}
+
+	a.stopBackfillRun(start)
+	a.log.Info("paused backfill process", "startHash", start.String())
+	return state, nil
+}
+
+// ResumeBackfill continues a paused backfill process from where it stopped.
+func (a *Archiver) ResumeBackfill(ctx context.Context, start common.Hash) (storage.ControlState, error) {
+	a.backfillMu.Lock()
+	defer a.backfillMu.Unlock()
+
+	if a.backfillProcesses == nil {
+		return a.ControlState(), ErrBackfillNotStarted
+	}
+	if _, ok := a.backfillProcesses[start]; !ok {
+		return a.ControlState(), ErrBackfillNotFound
+	}
+
+	state, err := a.updateControlState(ctx, func(state *storage.ControlState) {
+		state.PausedBackfills = slices.DeleteFunc(state.PausedBackfills, func(h common.Hash) bool {
+			return h == start
+		})
+	})
+	if err != nil {
+		return state, err
+	}
+
+	a.log.Info("resumed backfill process", "startHash", start.String())
+	go a.runBackfill(a.backfillCtx, start)
+	return state, nil
+}
+
+// CancelBackfill stops a backfill process and removes it, discarding its progress.
+func (a *Archiver) CancelBackfill(ctx context.Context, start common.Hash) (storage.ControlState, error) {
+	a.backfillMu.Lock()
+	defer a.backfillMu.Unlock()
+
+	if a.backfillProcesses == nil {
+		return a.ControlState(), ErrBackfillNotStarted
+	}
+	if _, ok := a.backfillProcesses[start]; !ok {
+		return a.ControlState(), ErrBackfillNotFound
+	}
+
+	a.stopBackfillRun(start)
+	delete(a.backfillProcesses, start)
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+	a.completeBackfillProgress(start)
+	a.log.Info("canceled backfill process", "startHash", start.String())
+
+	return a.updateControlState(ctx, func(state *storage.ControlState) {
+		state.PausedBackfills = slices.DeleteFunc(state.PausedBackfills, func(h common.Hash) bool {
+			return h == start
+		})
+	})
+}
+
+// StartBackfill archives the given block, identified by its root or slot, and starts a backfill process from it that
+// walks back to the last archived block or the origin block.
+func (a *Archiver) StartBackfill(ctx context.Context, blockId string) (*v1.BeaconBlockHeader, error) {
+	a.backfillMu.Lock()
+	started := a.backfillProcesses != nil
+	a.backfillMu.Unlock()
+	if !started {
+		return nil, ErrBackfillNotStarted
+	}
+
+	header, err := a.backfillClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: blockId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A parked start block is stored once the beacon nodes agree, the backfill carries on with its parent
+	_, _, err = a.persistBlobsForBlockWithClient(ctx, a.backfillClient, header.Data.Root.String(), false)
+	if err != nil && !errors.Is(err, errBlockParked) {
+		return nil, err
+	}
+
+	start := common.Hash(header.Data.Root)
+
+	a.backfillMu.Lock()
+	defer a.backfillMu.Unlock()
+
+	if _, ok := a.backfillProcesses[start]; ok {
+		return nil, ErrBackfillExists
+	}
+
+	a.backfillProcesses[start] = storage.BackfillProcess{Start: *header.Data, Current: *header.Data}
+	a.writeBackfillProcesses(ctx, a.backfillProcesses)
+	a.log.Info("started backfill process", "startHash", start.String(), "startSlot", header.Data.Header.Message.Slot)
+
+	go a.runBackfill(a.backfillCtx, start)
+	return header.Data, nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_LiveTrackingPausePersists(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	state, err := svc.SetLiveTrackingPaused(context.Background(), true)
+	require.NoError(t, err)
+	require.True(t, state.LiveTrackingPaused)
+	require.True(t, svc.liveTrackingPaused())
+
+	// A restarted archiver picks up the stored state
+	restarted, err := NewArchiver(svc.log, svc.cfg, fs, beacon, svc.metrics)
+	require.NoError(t, err)
+	require.False(t, restarted.liveTrackingPaused())
+	require.NoError(t, restarted.loadControlState(context.Background()))
+	require.True(t, restarted.liveTrackingPaused())
+
+	state, err = svc.SetLiveTrackingPaused(context.Background(), false)
+	require.NoError(t, err)
+	require.False(t, state.LiveTrackingPaused)
+
+	stored, err := fs.ReadControlState(context.Background())
+	require.NoError(t, err)
+	require.False(t, stored.LiveTrackingPaused)
+}
+
+func TestArchiver_PausedBackfillIsSkippedUntilResumed(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// Block five is the head, and the process started at block three is paused
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Five}})
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Four}})
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Three}})
+	three := *beacon.Headers[blobtest.Three.String()]
+	require.NoError(t, fs.WriteBackfillProcesses(context.Background(), storage.BackfillProcesses{
+		blobtest.Three: storage.BackfillProcess{Start: three, Current: three},
+	}))
+	require.NoError(t, fs.WriteControlState(context.Background(), storage.ControlState{
+		PausedBackfills: []common.Hash{blobtest.Three},
+	}))
+	require.NoError(t, svc.loadControlState(context.Background()))
+
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+	processes, err := fs.ReadBackfillProcesses(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, processes, blobtest.Three)
+
+	progress, err := svc.BackfillProgress(context.Background())
+	require.NoError(t, err)
+	require.Len(t, progress, 1)
+	require.True(t, progress[0].Paused)
+
+	state, err := svc.ResumeBackfill(context.Background(), blobtest.Three)
+	require.NoError(t, err)
+	require.Empty(t, state.PausedBackfills)
+
+	require.Eventually(t, func() bool {
+		processes, err := fs.ReadBackfillProcesses(context.Background())
+		require.NoError(t, err)
+		return len(processes) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, blob := range []common.Hash{blobtest.Two, blobtest.One, blobtest.OriginBlock} {
+		fs.CheckExistsOrFail(t, blob)
+	}
+}
+
+func TestArchiver_CancelBackfill(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Five}})
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Four}})
+	three := *beacon.Headers[blobtest.Three.String()]
+	require.NoError(t, fs.WriteBackfillProcesses(context.Background(), storage.BackfillProcesses{
+		blobtest.Three: storage.BackfillProcess{Start: three, Current: three},
+	}))
+	require.NoError(t, fs.WriteControlState(context.Background(), storage.ControlState{
+		PausedBackfills: []common.Hash{blobtest.Three},
+	}))
+	require.NoError(t, svc.loadControlState(context.Background()))
+
+	_, err := svc.CancelBackfill(context.Background(), blobtest.Three)
+	require.ErrorIs(t, err, ErrBackfillNotStarted)
+
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	state, err := svc.CancelBackfill(context.Background(), blobtest.Three)
+	require.NoError(t, err)
+	require.Empty(t, state.PausedBackfills)
+
+	processes, err := fs.ReadBackfillProcesses(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, processes)
+
+	_, err = svc.CancelBackfill(context.Background(), blobtest.Three)
+	require.ErrorIs(t, err, ErrBackfillNotFound)
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+}
+
+func TestArchiver_StartBackfill(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// Block five is the head and block one is already archived
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Five}})
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.One}})
+	svc.backfillBlobs(context.Background(), beacon.Headers[blobtest.Five.String()])
+
+	// Start from the slot of block three
+	header, err := svc.StartBackfill(context.Background(), "13")
+	require.NoError(t, err)
+	require.Equal(t, blobtest.Three, common.Hash(header.Root))
+
+	require.Eventually(t, func() bool {
+		processes, err := fs.ReadBackfillProcesses(context.Background())
+		require.NoError(t, err)
+		return len(processes) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, blob := range []common.Hash{blobtest.Three, blobtest.Two} {
+		data := fs.ReadOrFail(t, blob)
+		require.Equal(t, beacon.Blobs[blob.String()], data.BlobSidecars.Data)
+	}
+}
+
+func TestArchiver_PauseStopsRunningBackfill(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	three := *beacon.Headers[blobtest.Three.String()]
+	svc.backfillProcesses = storage.BackfillProcesses{
+		blobtest.Three: storage.BackfillProcess{Start: three, Current: three},
+	}
+	svc.backfillCtx = context.Background()
+
+	// Simulate a running process, which is stopped as soon as it is paused
+	runCtx, done, ok := svc.startBackfillRun(context.Background(), blobtest.Three)
+	require.True(t, ok)
+	defer done()
+
+	_, _, ok = svc.startBackfillRun(context.Background(), blobtest.Three)
+	require.False(t, ok)
+
+	state, err := svc.PauseBackfill(context.Background(), blobtest.Three)
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{blobtest.Three}, state.PausedBackfills)
+	require.Error(t, runCtx.Err())
+
+	stored, err := fs.ReadControlState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{blobtest.Three}, stored.PausedBackfills)
+}
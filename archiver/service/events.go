@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/base-org/blob-archiver/common/beacon"
+)
+
+const (
+	eventStreamMinReconnectInterval = time.Second
+	eventStreamMaxReconnectInterval = 30 * time.Second
+)
+
+var (
+	eventTopics          = []string{beacon.TopicHead, beacon.TopicBlock, beacon.TopicBlobSidecar, beacon.TopicChainReorg, beacon.TopicFinalizedCheckpoint}
+	finalizedEventTopics = []string{beacon.TopicFinalizedCheckpoint}
+)
+
+// chainReorgEvent is the data of a chain_reorg event.
+type chainReorgEvent struct {
+	Slot         string `json:"slot"`
+	Depth        string `json:"depth"`
+	OldHeadBlock string `json:"old_head_block"`
+	NewHeadBlock string `json:"new_head_block"`
+}
+
+// blobSidecarEvent is the data of a blob_sidecar event.
+type blobSidecarEvent struct {
+	BlockRoot string `json:"block_root"`
+	Index     string `json:"index"`
+	Slot      string `json:"slot"`
+}
+
+// subscribeEvents follows the event stream of the beacon node and signals newBlocks whenever a block is imported or the
+// head changes, or in finalized-only mode whenever the chain finalizes. Reorgs and finalized checkpoints are handed to
+// trackChain. If the stream is lost it reconnects with an exponential backoff, and live tracking falls back to polling
+// in the meantime.
+func (a *Archiver) subscribeEvents(ctx context.Context, events beacon.EventSubscriber, newBlocks chan<- struct{}, chain chainEvents) {
+	backoff := eventStreamMinReconnectInterval
+	topics := eventTopics
+	if a.cfg.FinalizedOnly {
+		topics = finalizedEventTopics
+	}
+
+	for {
+		connected := false
+		err := events.SubscribeEvents(ctx, topics, func(event beacon.Event) {
+			if !connected {
+				connected = true
+				a.setStreaming(true)
+				a.log.Info("receiving new blocks from the beacon node event stream")
+			}
+			a.handleEvent(event, newBlocks, chain)
+		})
+		a.setStreaming(false)
+
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = eventStreamMinReconnectInterval
+		}
+		a.log.Warn("beacon node event stream unavailable, polling for new blocks", "err", err, "reconnectIn", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, eventStreamMaxReconnectInterval)
+	}
+}
+
+// handleEvent signals newBlocks for events that may bring a new block. A pending signal is not duplicated, as a single
+// refresh archives every block up to the head. Reorgs and finalized checkpoints are queued for trackChain, unless only
+// finalized blocks are archived. It never blocks, so that the event stream is read without delay.
+func (a *Archiver) handleEvent(event beacon.Event, newBlocks chan<- struct{}, chain chainEvents) {
+	switch event.Topic {
+	case beacon.TopicHead, beacon.TopicBlock:
+		a.log.Debug("received beacon node event", "topic", event.Topic)
+	case beacon.TopicChainReorg:
+		var reorg chainReorgEvent
+		if err := json.Unmarshal(event.Data, &reorg); err != nil {
+			a.log.Warn("failed to decode chain reorg event", "err", err)
+		} else {
+			a.log.Info("chain reorg", "slot", reorg.Slot, "depth", reorg.Depth, "oldHead", reorg.OldHeadBlock, "newHead", reorg.NewHeadBlock)
+			select {
+			case chain.reorgs <- reorg:
+			default:
+				a.log.Warn("dropping chain reorg, too many reorgs are pending", "oldHead", reorg.OldHeadBlock)
+			}
+		}
+	case beacon.TopicFinalizedCheckpoint:
+		if !a.cfg.FinalizedOnly {
+			// A pending check covers this checkpoint as well
+			select {
+			case chain.finalized <- struct{}{}:
+			default:
+			}
+			return
+		}
+		a.log.Debug("received beacon node event", "topic", event.Topic)
+	case beacon.TopicBlobSidecar:
+		// The block of a sidecar is archived once it is imported
+		var sidecar blobSidecarEvent
+		if err := json.Unmarshal(event.Data, &sidecar); err == nil {
+			a.log.Debug("received blob sidecar", "blockRoot", sidecar.BlockRoot, "index", sidecar.Index, "slot", sidecar.Slot)
+		}
+		return
+	default:
+		return
+	}
+
+	select {
+	case newBlocks <- struct{}{}:
+	default:
+	}
+}
+
+// setStreaming records whether new blocks are received from the event stream, in which case polling is not needed.
+func (a *Archiver) setStreaming(streaming bool) {
+	a.streaming.Store(streaming)
+	a.metrics.RecordEventStreamConnected(streaming)
+}
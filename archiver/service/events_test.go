@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/archiver/flags"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/storage/storagetest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingBeaconClient is a stub beacon client with an event stream.
+type streamingBeaconClient struct {
+	*beacontest.StubBeaconClient
+	*beacon.EventStream
+}
+
+func setupStreaming(t *testing.T, pollInterval time.Duration) (*Archiver, *storagetest.TestFileStorage, *beacontest.StubEventStream) {
+	l := testlog.Logger(t, log.LvlInfo)
+	fs := storagetest.NewTestFileStorage(t, l)
+	stream := beacontest.NewStubEventStream(t)
+	client := &streamingBeaconClient{
+		StubBeaconClient: beacontest.NewDefaultStubBeaconClient(t),
+		EventStream:      beacon.NewEventStream(stream.URL()),
+	}
+
+	svc, err := NewArchiver(l, flags.ArchiverConfig{
+		PollInterval: pollInterval,
+		OriginBlock:  blobtest.OriginBlock,
+		EventStream:  true,
+	}, fs, client, metrics.NewMetrics())
+	require.NoError(t, err)
+	return svc, fs, stream
+}
+
+func TestArchiver_EventStreamArchivesNewBlocks(t *testing.T) {
+	// Polling would not pick up the new block within the test
+	svc, fs, stream := setupStreaming(t, time.Hour)
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Four}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = svc.trackLatestBlocks(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return stream.Subscribers() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, []string{"head", "block", "blob_sidecar", "chain_reorg", "finalized_checkpoint"}, stream.Topics())
+
+	// Sidecars are only archived once their block is imported
+	stream.Publish(beacon.TopicBlobSidecar, map[string]string{"block_root": blobtest.Five.String(), "index": "0"})
+	stream.Publish(beacon.TopicBlock, map[string]string{"slot": "15", "block": blobtest.Five.String()})
+
+	require.Eventually(t, func() bool {
+		exists, err := fs.Exists(context.Background(), blobtest.Five)
+		require.NoError(t, err)
+		return exists
+	}, 5*time.Second, 10*time.Millisecond)
+	require.True(t, svc.streaming.Load())
+	// The head is recorded once its blobs are written
+	require.Eventually(t, func() bool {
+		return svc.Status().HeadSlot == blobtest.StartSlot+5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The stream is reconnected after it is lost
+	stream.Disconnect()
+	require.Eventually(t, func() bool {
+		return stream.Connections() == 2 && stream.Subscribers() == 1
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestArchiver_EventStreamFallsBackToPolling(t *testing.T) {
+	svc, fs, stream := setupStreaming(t, 10*time.Millisecond)
+	stream.SetUnavailable(true)
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Four}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = svc.trackLatestBlocks(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		exists, err := fs.Exists(context.Background(), blobtest.Five)
+		require.NoError(t, err)
+		return exists
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, svc.streaming.Load())
+
+	// Once the stream is back, it is used instead of polling
+	stream.SetUnavailable(false)
+	require.Eventually(t, func() bool {
+		return stream.Subscribers() == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	stream.Publish(beacon.TopicHead, map[string]string{"slot": "15", "block": blobtest.Five.String()})
+	require.Eventually(t, svc.streaming.Load, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestArchiver_HandleEventDoesNotBlock(t *testing.T) {
+	svc, _, _ := setupStreaming(t, time.Hour)
+	newBlocks := make(chan struct{}, 1)
+	chain := newChainEvents()
+
+	// Nothing handles the queued events, as if the beacon node was slow to answer
+	reorg, err := json.Marshal(chainReorgEvent{Slot: "15", Depth: "1", OldHeadBlock: blobtest.Five.String()})
+	require.NoError(t, err)
+	for i := 0; i < reorgQueueSize+1; i++ {
+		svc.handleEvent(beacon.Event{Topic: beacon.TopicChainReorg, Data: reorg}, newBlocks, chain)
+		svc.handleEvent(beacon.Event{Topic: beacon.TopicFinalizedCheckpoint}, newBlocks, chain)
+	}
+
+	require.Len(t, chain.reorgs, reorgQueueSize)
+	require.Len(t, chain.finalized, 1)
+}
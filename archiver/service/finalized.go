@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum-optimism/optimism/op-service/retry"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// latestBlockId returns the block that live tracking follows: the finalized checkpoint in finalized-only mode, the head
+// otherwise.
+func (a *Archiver) latestBlockId() string {
+	if a.cfg.FinalizedOnly {
+		return "finalized"
+	}
+	return "head"
+}
+
+// processLatestBlocks archives the blocks up to the block followed by live tracking.
+func (a *Archiver) processLatestBlocks(ctx context.Context) {
+	if a.cfg.FinalizedOnly {
+		a.processFinalizedBlocks(ctx)
+		return
+	}
+	a.processBlocksUntilKnownBlock(ctx)
+}
+
+// processFinalizedBlocks archives every block between the finalized cursor and the latest finalized checkpoint, walking
+// back from the finalized block, and then moves the cursor to it. If the walk fails, the cursor is left in place so that
+// the blocks are archived with the next finalized checkpoint.
+func (a *Archiver) processFinalizedBlocks(ctx context.Context) {
+	a.log.Debug("refreshing finalized data")
+
+	checkpoint, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: "finalized",
+	})
+	if err != nil {
+		a.log.Error("failed to fetch finalized block header", "err", err)
+		return
+	}
+
+	a.chainMu.Lock()
+	err = a.loadChainState(ctx)
+	cursor := a.chain.FinalizedCursor
+	if err == nil {
+		// The blocks of the walk are finalized, so they are not tracked as unfinalized when they are stored
+		a.chain.FinalizedSlot = max(a.chain.FinalizedSlot, uint64(checkpoint.Data.Header.Message.Slot))
+	}
+	a.chainMu.Unlock()
+	if err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	var finalized *v1.BeaconBlockHeader
+	currentBlockId := checkpoint.Data.Root.String()
+	count := 0
+
+	for {
+		parked := false
+		current, alreadyExisted, err := retry.Do2(ctx, liveFetchBlobMaximumRetries, retry.Exponential(), func() (*v1.BeaconBlockHeader, bool, error) {
+			header, exists, err := a.persistBlobsForBlockToS3(ctx, currentBlockId, false)
+			if parked = errors.Is(err, errBlockParked); parked {
+				return header, exists, nil
+			}
+			return header, exists, err
+		})
+
+		if err != nil {
+			a.log.Error("failed to update finalized blobs for block", "err", err, "blockId", currentBlockId)
+			return
+		}
+
+		if finalized == nil {
+			finalized = current
+		}
+
+		if !alreadyExisted && !parked {
+			a.metrics.RecordProcessedBlock(metrics.BlockSourceLive)
+			count++
+		}
+
+		// Without a cursor, the blocks before the finalized block are left to the backfill
+		parent := common.Hash(current.Header.Message.ParentRoot)
+		if cursor == nil || common.Hash(current.Root) == cursor.Root || parent == cursor.Root ||
+			uint64(current.Header.Message.Slot) <= cursor.Slot || common.Hash(current.Root) == a.cfg.OriginBlock {
+			break
+		}
+
+		currentBlockId = parent.String()
+	}
+
+	a.setLatestHead(finalized)
+	a.advanceFinalizedCursor(ctx, finalized)
+	a.recordFinalityLag(ctx, finalized)
+
+	a.log.Info("finalized data refreshed", "finalizedHash", finalized.Root.String(), "finalizedSlot", finalized.Header.Message.Slot, "archived", count)
+}
+
+// advanceFinalizedCursor moves the finalized cursor to the given block, unless the cursor is already at or past it.
+func (a *Archiver) advanceFinalizedCursor(ctx context.Context, finalized *v1.BeaconBlockHeader) {
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+
+	if err := a.loadChainState(ctx); err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	slot := uint64(finalized.Header.Message.Slot)
+	if a.chain.FinalizedCursor != nil && a.chain.FinalizedCursor.Slot >= slot {
+		return
+	}
+	a.chain.FinalizedCursor = &storage.FinalizedCursor{
+		Root: common.Hash(finalized.Root),
+		Slot: slot,
+	}
+	a.writeChainState(ctx)
+}
+
+// recordFinalityLag reports how many slots the given finalized block is behind the head.
+func (a *Archiver) recordFinalityLag(ctx context.Context, finalized *v1.BeaconBlockHeader) {
+	head, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: "head",
+	})
+	if err != nil {
+		a.log.Warn("failed to fetch head block header", "err", err)
+		return
+	}
+
+	headSlot, finalizedSlot := uint64(head.Data.Header.Message.Slot), uint64(finalized.Header.Message.Slot)
+	if headSlot < finalizedSlot {
+		headSlot = finalizedSlot
+	}
+	a.metrics.RecordFinalityLag(headSlot - finalizedSlot)
+}
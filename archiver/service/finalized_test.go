@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_FinalizedOnlyArchivesBetweenCheckpoints(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.cfg.FinalizedOnly = true
+
+	require.NoError(t, fs.WriteChainState(context.Background(), storage.ChainState{
+		FinalizedCursor: &storage.FinalizedCursor{Root: blobtest.One, Slot: blobtest.StartSlot + 1},
+	}))
+
+	// Three is finalized
+	svc.processFinalizedBlocks(context.Background())
+
+	fs.CheckExistsOrFail(t, blobtest.Two)
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	fs.CheckNotExistsOrFail(t, blobtest.One)
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+	fs.CheckNotExistsOrFail(t, blobtest.Five)
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &storage.FinalizedCursor{Root: blobtest.Three, Slot: blobtest.StartSlot + 3}, state.FinalizedCursor)
+	require.Empty(t, readUnfinalized(t, fs))
+
+	// The chain finalizes up to Five
+	beacon.Headers["finalized"] = beacon.Headers[blobtest.Five.String()]
+	svc.processFinalizedBlocks(context.Background())
+
+	fs.CheckExistsOrFail(t, blobtest.Four)
+	fs.CheckExistsOrFail(t, blobtest.Five)
+
+	state, err = fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &storage.FinalizedCursor{Root: blobtest.Five, Slot: blobtest.StartSlot + 5}, state.FinalizedCursor)
+	require.Equal(t, blobtest.StartSlot+5, svc.Status().HeadSlot)
+}
+
+func TestArchiver_FinalizedOnlyWithoutCursor(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.cfg.FinalizedOnly = true
+
+	svc.processLatestBlocks(context.Background())
+
+	// The blocks before the finalized block are left to the backfill, and the head is not archived
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	fs.CheckNotExistsOrFail(t, blobtest.Two)
+	fs.CheckNotExistsOrFail(t, blobtest.Five)
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &storage.FinalizedCursor{Root: blobtest.Three, Slot: blobtest.StartSlot + 3}, state.FinalizedCursor)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/verify"
+	validator "github.com/base-org/blob-archiver/validator/service"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// ingestInterval is how often the ingest queue is processed.
+	ingestInterval = time.Minute
+	// maxIngestAttempts is the number of times a queued block is fetched from its peer before it is dropped.
+	maxIngestAttempts = 5
+)
+
+// runIngest processes the ingest queue at a fixed interval, until the context is done.
+func (a *Archiver) runIngest(ctx context.Context) {
+	t := time.NewTicker(ingestInterval)
+	defer t.Stop()
+
+	for {
+		a.processIngestQueue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// processIngestQueue stores the blocks that the API served from a peer blob API because they were missing from
+// storage. Every block is fetched from the same peer again and verified before it is stored. Blocks that could not be
+// ingested stay queued, until they failed maxIngestAttempts times. Only the object of each processed block is
+// rewritten, so blocks the API queues in the meantime are kept.
+func (a *Archiver) processIngestQueue(ctx context.Context) {
+	queue, err := a.dataStoreClient.ReadIngestQueue(ctx)
+	if err != nil {
+		a.log.Error("failed to read ingest queue", "err", err)
+		return
+	}
+
+	for root, block := range queue {
+		if a.ingestBlock(ctx, root, block) {
+			a.dequeueIngestBlock(ctx, root)
+			continue
+		}
+
+		block.Attempts++
+		if block.Attempts >= maxIngestAttempts {
+			a.log.Warn("dropping block from ingest queue", "block", root, "peer", block.Peer, "attempts", block.Attempts)
+			a.dequeueIngestBlock(ctx, root)
+		} else if err := a.dataStoreClient.WriteIngestBlock(ctx, root, block); err != nil {
+			a.log.Error("failed to update ingest queue", "block", root, "err", err)
+		}
+	}
+}
+
+// dequeueIngestBlock removes a block from the ingest queue.
+func (a *Archiver) dequeueIngestBlock(ctx context.Context, root common.Hash) {
+	if err := a.dataStoreClient.DeleteIngestBlock(ctx, root); err != nil {
+		a.log.Error("failed to remove block from ingest queue", "block", root, "err", err)
+	}
+}
+
+// ingestBlock fetches a queued block from its peer, verifies it against the block from the beacon node and stores it.
+// It returns true if the block is stored.
+func (a *Archiver) ingestBlock(ctx context.Context, root common.Hash, block storage.IngestBlock) bool {
+	exists, err := a.dataStoreClient.Exists(ctx, root)
+	if err != nil {
+		a.log.Error("failed to check if queued block exists", "block", root, "err", err)
+		return false
+	}
+	if exists {
+		return true
+	}
+
+	status, sidecars, err := a.newPeerClient(block.Peer).FetchSidecars(ctx, root.String(), validator.FormatSSZ)
+	if err != nil || status != http.StatusOK || len(sidecars.Data) == 0 {
+		a.log.Warn("failed to fetch queued block from peer", "block", root, "peer", block.Peer, "status", status, "err", err)
+		return false
+	}
+
+	if a.blocks == nil {
+		a.log.Warn("queued block cannot be verified without a beacon client that returns signed blocks", "block", root)
+		return false
+	}
+	commitments, err := verify.BlockCommitments(ctx, a.blocks, root)
+	if err != nil {
+		a.log.Warn("failed to fetch queued block from beacon node", "block", root, "err", err)
+		return false
+	}
+
+	if err := verify.CompleteBlobSidecars(root, commitments, sidecars.Data); err != nil {
+		a.metrics.RecordPeerSidecars(false)
+		a.log.Error("peer returned invalid blob sidecars", "block", root, "peer", block.Peer, "err", err)
+		return false
+	}
+	a.metrics.RecordPeerSidecars(true)
+
+	// The header tells whether the block is canonical once it is stored
+	header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: root.String()})
+	if err != nil {
+		a.log.Warn("failed to fetch queued block header from beacon node", "block", root, "err", err)
+		return false
+	}
+
+	err = a.dataStoreClient.WriteBlob(ctx, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: root},
+		BlobSidecars: sidecars,
+	})
+	if err != nil {
+		a.log.Error("failed to store queued block", "block", root, "err", err)
+		return false
+	}
+	a.trackStoredBlock(ctx, header.Data)
+
+	a.log.Info("ingested block from peer", "block", root, "peer", block.Peer, "blobs", len(sidecars.Data))
+	return true
+}
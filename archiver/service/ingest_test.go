@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	validator "github.com/base-org/blob-archiver/validator/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_ProcessIngestQueue(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 2)
+	truncated, truncatedSidecars := blobtest.NewVerifiableBlobSidecars(t, 2)
+	_, otherSidecars := blobtest.NewVerifiableBlobSidecars(t, 1)
+	beacon.Commitments[root.String()] = blobtest.Commitments(sidecars)
+	beacon.Commitments[truncated.String()] = blobtest.Commitments(truncatedSidecars)
+	beacon.Commitments[blobtest.Six.String()] = blobtest.Commitments(otherSidecars)
+	beacon.Headers[root.String()] = &v1.BeaconBlockHeader{
+		Root:      phase0.Root(root),
+		Canonical: true,
+		Header:    &phase0.SignedBeaconBlockHeader{Message: &phase0.BeaconBlockHeader{Slot: 100}},
+	}
+
+	peer := &stubPeer{sidecars: map[string][]*deneb.BlobSidecar{
+		root.String():         sidecars,
+		truncated.String():    truncatedSidecars[:1],
+		blobtest.Six.String(): otherSidecars,
+	}}
+	var urls []string
+	svc.newPeerClient = func(url string) validator.BlobSidecarClient {
+		urls = append(urls, url)
+		return peer
+	}
+
+	// Five is already stored, Six is invalid, the peer only has some of the blobs of truncated, and Seven is unknown to
+	// the peer and dropped after this attempt
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Five}})
+	queued := storage.IngestQueue{
+		root:           {Peer: "http://peer"},
+		truncated:      {Peer: "http://peer"},
+		blobtest.Five:  {Peer: "http://peer"},
+		blobtest.Six:   {Peer: "http://peer"},
+		blobtest.Seven: {Peer: "http://peer", Attempts: maxIngestAttempts - 1},
+	}
+	for hash, block := range queued {
+		require.NoError(t, fs.WriteIngestBlock(context.Background(), hash, block))
+	}
+
+	svc.processIngestQueue(context.Background())
+
+	data := fs.ReadOrFail(t, root)
+	require.Equal(t, sidecars, data.BlobSidecars.Data)
+	fs.CheckNotExistsOrFail(t, blobtest.Six)
+	fs.CheckNotExistsOrFail(t, truncated)
+	fs.CheckNotExistsOrFail(t, blobtest.Seven)
+	require.Equal(t, 4, peer.requests)
+	require.Equal(t, []string{"http://peer", "http://peer", "http://peer", "http://peer"}, urls)
+
+	queue, err := fs.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, storage.IngestQueue{
+		blobtest.Six: {Peer: "http://peer", Attempts: 1},
+		truncated:    {Peer: "http://peer", Attempts: 1},
+	}, queue)
+}
@@ -0,0 +1,417 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum-optimism/optimism/op-service/retry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxFinishedRearchiveJobs is the number of finished jobs that are kept in storage, so that their results can
+	// still be queried for a while.
+	maxFinishedRearchiveJobs = 100
+	// jobProgressInterval is the number of slots after which the progress of a job is persisted.
+	jobProgressInterval = 10
+)
+
+var (
+	ErrNotLeader   = errors.New("archiver does not hold the storage lock")
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// loadRearchiveJobs reads the rearchive jobs from storage, unless they are loaded already. The caller must hold jobsMu.
+func (a *Archiver) loadRearchiveJobs(ctx context.Context) error {
+	if a.jobs != nil {
+		return nil
+	}
+
+	jobs, err := a.dataStoreClient.ReadRearchiveJobs(ctx)
+	if err != nil {
+		return err
+	}
+	a.jobs = jobs
+	return nil
+}
+
+// writeRearchiveJobs persists the rearchive jobs, dropping the oldest finished jobs beyond maxFinishedRearchiveJobs.
+// The caller must hold jobsMu.
+func (a *Archiver) writeRearchiveJobs(ctx context.Context) {
+	var finished []storage.RearchiveJob
+	for _, job := range a.jobs {
+		if job.Finished() {
+			finished = append(finished, job)
+		}
+	}
+
+	if len(finished) > maxFinishedRearchiveJobs {
+		sort.Slice(finished, func(i, j int) bool {
+			return finished[i].UpdatedAt < finished[j].UpdatedAt
+		})
+		for _, job := range finished[:len(finished)-maxFinishedRearchiveJobs] {
+			delete(a.jobs, job.Id)
+		}
+	}
+
+	err := a.dataStoreClient.WriteRearchiveJobs(ctx, a.jobs)
+	if err != nil {
+		a.log.Error("failed to write rearchive jobs", "err", err)
+	}
+}
+
+// resumeRearchiveJobs starts all jobs that were not finished before the archiver was stopped. It must only be called
+// once the storage lock is obtained.
+func (a *Archiver) resumeRearchiveJobs(ctx context.Context) error {
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	if err := a.loadRearchiveJobs(ctx); err != nil {
+		return err
+	}
+
+	for id, job := range a.jobs {
+		if !job.Finished() {
+			a.log.Info("resuming rearchive job", "id", id, "nextSlot", job.NextSlot, "to", job.To)
+			a.startRearchiveJob(id)
+		}
+	}
+	return nil
+}
+
+// CreateRearchiveJob persists a job that rearchives the given range of slots, and starts it in the background. A dry
+// run job only reports how the stored blobs differ from the beacon node.
+func (a *Archiver) CreateRearchiveJob(ctx context.Context, from uint64, to uint64, dryRun bool) (storage.RearchiveJob, error) {
+	if !a.Status().Leader {
+		return storage.RearchiveJob{}, ErrNotLeader
+	}
+
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	if err := a.loadRearchiveJobs(ctx); err != nil {
+		return storage.RearchiveJob{}, err
+	}
+
+	now := time.Now().Unix()
+	job := storage.RearchiveJob{
+		Id:          uuid.New().String(),
+		Status:      storage.JobStatusPending,
+		From:        from,
+		To:          to,
+		NextSlot:    from,
+		FailedSlots: make([]uint64, 0),
+		DryRun:      dryRun,
+		Report: storage.RearchiveReport{
+			IdenticalSlots: make([]uint64, 0),
+			DifferentSlots: make([]storage.SlotDifference, 0),
+			MissingSlots:   make([]uint64, 0),
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	a.jobs[job.Id] = job
+	a.writeRearchiveJobs(ctx)
+
+	a.log.Info("created rearchive job", "id", job.Id, "from", from, "to", to, "dryRun", dryRun)
+	a.startRearchiveJob(job.Id)
+	return job, nil
+}
+
+// RearchiveJob returns the job with the given id.
+func (a *Archiver) RearchiveJob(ctx context.Context, id string) (storage.RearchiveJob, error) {
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	if err := a.loadRearchiveJobs(ctx); err != nil {
+		return storage.RearchiveJob{}, err
+	}
+
+	job, ok := a.jobs[id]
+	if !ok {
+		return storage.RearchiveJob{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// RearchiveJobs returns all jobs, the most recently created first.
+func (a *Archiver) RearchiveJobs(ctx context.Context) ([]storage.RearchiveJob, error) {
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	if err := a.loadRearchiveJobs(ctx); err != nil {
+		return nil, err
+	}
+
+	result := make([]storage.RearchiveJob, 0, len(a.jobs))
+	for _, job := range a.jobs {
+		result = append(result, job)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt == result[j].CreatedAt {
+			return result[i].Id < result[j].Id
+		}
+		return result[i].CreatedAt > result[j].CreatedAt
+	})
+	return result, nil
+}
+
+// CancelRearchiveJob stops the job with the given id. Slots that were rearchived before are not reverted.
+func (a *Archiver) CancelRearchiveJob(ctx context.Context, id string) (storage.RearchiveJob, error) {
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	if err := a.loadRearchiveJobs(ctx); err != nil {
+		return storage.RearchiveJob{}, err
+	}
+
+	job, ok := a.jobs[id]
+	if !ok {
+		return storage.RearchiveJob{}, ErrJobNotFound
+	}
+	if job.Finished() {
+		return job, ErrJobFinished
+	}
+
+	if cancel, ok := a.jobRuns[id]; ok {
+		cancel()
+		delete(a.jobRuns, id)
+	}
+
+	job.Status = storage.JobStatusCanceled
+	job.UpdatedAt = time.Now().Unix()
+	a.jobs[id] = job
+	a.writeRearchiveJobs(ctx)
+
+	a.log.Info("canceled rearchive job", "id", id, "nextSlot", job.NextSlot)
+	return job, nil
+}
+
+// startRearchiveJob runs the job with the given id in the background. The caller must hold jobsMu.
+func (a *Archiver) startRearchiveJob(id string) {
+	if _, running := a.jobRuns[id]; running {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.jobRuns[id] = cancel
+
+	go func() {
+		defer cancel()
+		a.runRearchiveJob(ctx, id)
+
+		a.jobsMu.Lock()
+		delete(a.jobRuns, id)
+		a.jobsMu.Unlock()
+	}()
+}
+
+// updateRearchiveJob applies the given change to a job and persists it, unless the job was canceled in the meantime.
+// It returns false if the job should stop.
+func (a *Archiver) updateRearchiveJob(ctx context.Context, id string, persist bool, update func(job *storage.RearchiveJob)) bool {
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	job, ok := a.jobs[id]
+	if !ok || job.Finished() {
+		return false
+	}
+
+	update(&job)
+	job.UpdatedAt = time.Now().Unix()
+	a.jobs[id] = job
+
+	if persist || job.Finished() {
+		a.writeRearchiveJobs(ctx)
+	}
+	return true
+}
+
+// runRearchiveJob rearchives the remaining slots or items of a job. If the job is canceled or the archiver stops, it
+// returns early; in the latter case the job resumes from its last persisted progress after a restart.
+func (a *Archiver) runRearchiveJob(ctx context.Context, id string) {
+	a.jobsMu.Lock()
+	job := a.jobs[id]
+	a.jobsMu.Unlock()
+
+	a.updateRearchiveJob(ctx, id, true, func(job *storage.RearchiveJob) {
+		job.Status = storage.JobStatusRunning
+		if (job.Items == nil && job.NextSlot > job.To) || (job.Items != nil && job.NextItem >= len(job.Items)) {
+			job.Status = job.FinalStatus()
+		}
+	})
+
+	if job.Items != nil {
+		a.rearchiveJobItems(ctx, job)
+	} else {
+		a.rearchiveJobRange(ctx, job)
+	}
+
+	a.jobsMu.Lock()
+	job = a.jobs[id]
+	a.jobsMu.Unlock()
+	a.log.Info("rearchive job finished", "id", id, "status", job.Status, "processed", job.Processed, "failed", len(job.FailedSlots))
+}
+
+// rearchiveJobRange rearchives the remaining slots of a job.
+func (a *Archiver) rearchiveJobRange(ctx context.Context, job storage.RearchiveJob) {
+	id := job.Id
+	count := 0
+	for slot := job.NextSlot; slot <= job.To; slot++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		l := a.log.New("job", id, "slot", slot)
+		l.Info("rearchiving block")
+
+		comparison, err := a.rearchiveSlot(ctx, slot, job.DryRun)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			l.Error("failed to rearchive block", "err", err)
+		} else if !comparison.found {
+			l.Info("block not found during reachiving")
+		} else if len(comparison.differences) > 0 {
+			l.Warn("stored blobs differ from beacon node", "hash", comparison.root.String(), "differences", comparison.differences)
+		}
+		a.metrics.RecordProcessedBlock(metrics.BlockSourceRearchive)
+
+		count++
+		last := slot == job.To
+		ok := a.updateRearchiveJob(ctx, id, err != nil || count%jobProgressInterval == 0, func(job *storage.RearchiveJob) {
+			job.NextSlot = slot + 1
+			job.Processed++
+			if err != nil {
+				job.FailedSlots = append(job.FailedSlots, slot)
+			} else {
+				comparison.addTo(&job.Report)
+			}
+			if last {
+				job.Status = job.FinalStatus()
+			}
+		})
+		if !ok || last {
+			break
+		}
+	}
+}
+
+// blockComparison is the result of comparing the stored blobs of a block with the blobs served by the beacon node.
+type blockComparison struct {
+	// found is false if the beacon node has no block, e.g. because the slot was missed.
+	found     bool
+	slot      uint64
+	root      common.Hash
+	canonical bool
+	// stored is false if no blobs were stored for the block.
+	stored      bool
+	differences []string
+}
+
+// result returns whether the stored blobs were identical, different or missing, or nothing if there is no block.
+func (c blockComparison) result() string {
+	switch {
+	case !c.found:
+		return ""
+	case !c.stored:
+		return "missing"
+	case len(c.differences) == 0:
+		return "identical"
+	default:
+		return "different"
+	}
+}
+
+// addTo records the comparison in a rearchive report.
+func (c blockComparison) addTo(report *storage.RearchiveReport) {
+	switch c.result() {
+	case "missing":
+		report.MissingSlots = append(report.MissingSlots, c.slot)
+	case "identical":
+		report.IdenticalSlots = append(report.IdenticalSlots, c.slot)
+	case "different":
+		report.DifferentSlots = append(report.DifferentSlots, storage.SlotDifference{
+			Slot:        c.slot,
+			BlockRoot:   c.root,
+			Differences: c.differences,
+		})
+	}
+}
+
+// rearchiveSlot rearchives the block at the given slot, retrying on errors. See rearchiveBlock.
+func (a *Archiver) rearchiveSlot(ctx context.Context, slot uint64, dryRun bool) (blockComparison, error) {
+	id := strconv.FormatUint(slot, 10)
+
+	return retry.Do(ctx, rearchiveMaximumRetries, retry.Exponential(), func() (blockComparison, error) {
+		return a.rearchiveBlock(ctx, id, dryRun)
+	})
+}
+
+// rearchiveBlock compares the stored blobs of a block with the blobs served by the beacon node and, unless dryRun is
+// set, overwrites them with the data from the beacon node.
+func (a *Archiver) rearchiveBlock(ctx context.Context, blockId string, dryRun bool) (blockComparison, error) {
+	header, err := a.rearchiveClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: blockId,
+	})
+	if err != nil {
+		// If the block is not found, we can assume that the slot has been skipped
+		if isBlockNotFound(err) {
+			return blockComparison{}, nil
+		}
+		return blockComparison{}, err
+	}
+
+	root := common.Hash(header.Data.Root)
+	blobSidecars, err := a.rearchiveClient.BlobSidecars(ctx, &api.BlobSidecarsOpts{
+		Block: root.String(),
+	})
+	if err != nil {
+		return blockComparison{}, err
+	}
+
+	fetched := storage.BlobData{
+		Header: storage.Header{
+			BeaconBlockHash: root,
+		},
+		BlobSidecars: storage.BlobSidecars{Data: blobSidecars.Data},
+	}
+
+	result := blockComparison{
+		found:     true,
+		slot:      uint64(header.Data.Header.Message.Slot),
+		root:      root,
+		canonical: header.Data.Canonical,
+	}
+
+	stored, err := a.dataStoreClient.ReadBlob(ctx, root)
+	if err == nil {
+		result.stored = true
+		result.differences = diffBlobData(stored, fetched)
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return blockComparison{}, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	// The blob that is being written has not been validated. It is assumed that the beacon node is trusted.
+	err = a.dataStoreClient.WriteBlob(ctx, fetched)
+	if err != nil {
+		return blockComparison{}, err
+	}
+	a.metrics.RecordStoredBlobs(len(blobSidecars.Data))
+	a.trackStoredBlock(ctx, header.Data)
+
+	return result, nil
+}
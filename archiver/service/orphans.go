@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// finalityCheckInterval is how often the finalized checkpoint is polled, in addition to finalized_checkpoint events.
+	finalityCheckInterval = time.Minute
+	// maxReorgDepth bounds the number of blocks that are orphaned for a single chain_reorg event.
+	maxReorgDepth = 64
+	// slotsPerEpoch groups the unfinalized blocks in storage. It only sets the size of the stored objects, so it does not
+	// have to match the chain.
+	slotsPerEpoch = 32
+	// reorgQueueSize bounds the chain_reorg events waiting to be handled. Further events are dropped, as the blocks of
+	// their old chain are still orphaned by the finality check.
+	reorgQueueSize = 16
+)
+
+// chainEvents hands the reorgs and finalized checkpoints reported by the event stream to trackChain, so that reading
+// the stream never waits for the beacon node or the storage.
+type chainEvents struct {
+	reorgs    chan chainReorgEvent
+	finalized chan struct{}
+}
+
+func newChainEvents() chainEvents {
+	return chainEvents{
+		reorgs:    make(chan chainReorgEvent, reorgQueueSize),
+		finalized: make(chan struct{}, 1),
+	}
+}
+
+// trackChain orphans the blocks of the old chain of reorgs, and checks the finality of the archived blocks whenever a
+// finalized checkpoint is reported and otherwise every finalityCheckInterval, until the context is done. Checks that
+// are requested while one is running are coalesced into a single one.
+func (a *Archiver) trackChain(ctx context.Context, events chainEvents) {
+	t := time.NewTicker(finalityCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case reorg := <-events.reorgs:
+			a.handleReorg(ctx, reorg)
+			continue
+		case <-events.finalized:
+		case <-t.C:
+		}
+
+		// A pending request is covered by this check
+		select {
+		case <-events.finalized:
+		default:
+		}
+		a.checkFinality(ctx)
+		t.Reset(finalityCheckInterval)
+	}
+}
+
+// loadChainState reads the chain state and the unfinalized blocks from storage, unless they are loaded already. The
+// caller must hold chainMu.
+func (a *Archiver) loadChainState(ctx context.Context) error {
+	if a.unfinalized != nil {
+		return nil
+	}
+
+	state, err := a.dataStoreClient.ReadChainState(ctx)
+	if err != nil {
+		return err
+	}
+	unfinalized, err := a.dataStoreClient.ReadUnfinalizedEpochs(ctx)
+	if err != nil {
+		return err
+	}
+	a.chain = state
+	a.unfinalized = unfinalized
+	a.metrics.RecordStoredOrphans(len(a.chain.Orphans))
+	a.metrics.RecordParkedBlocks(len(a.chain.Parked))
+	return nil
+}
+
+// writeChainState persists the chain state. The caller must hold chainMu.
+func (a *Archiver) writeChainState(ctx context.Context) {
+	err := a.dataStoreClient.WriteChainState(ctx, a.chain)
+	if err != nil {
+		a.log.Error("failed to write chain state", "err", err)
+	}
+}
+
+// writeUnfinalizedEpoch persists the unfinalized blocks of an epoch. The caller must hold chainMu.
+func (a *Archiver) writeUnfinalizedEpoch(ctx context.Context, epoch uint64) {
+	err := a.dataStoreClient.WriteUnfinalizedEpoch(ctx, epoch, a.unfinalized[epoch])
+	if err != nil {
+		a.log.Error("failed to write unfinalized epoch", "err", err, "epoch", epoch)
+	}
+	if len(a.unfinalized[epoch]) == 0 {
+		delete(a.unfinalized, epoch)
+	}
+}
+
+// forgetUnfinalizedBlock drops a block from the unfinalized blocks, and returns its epoch. The caller must hold chainMu
+// and persist the epoch.
+func (a *Archiver) forgetUnfinalizedBlock(root common.Hash, slot uint64) uint64 {
+	epoch := slot / slotsPerEpoch
+	delete(a.unfinalized[epoch], root)
+	return epoch
+}
+
+// trackStoredBlock classifies a block that was just written to storage, whichever way it was archived. A block that the
+// beacon node does not consider canonical is orphaned right away, any other block is remembered until it is finalized.
+func (a *Archiver) trackStoredBlock(ctx context.Context, header *v1.BeaconBlockHeader) {
+	if header.Canonical {
+		a.recordUnfinalizedBlock(ctx, header)
+		return
+	}
+
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+
+	if err := a.loadChainState(ctx); err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	root, slot := common.Hash(header.Root), uint64(header.Header.Message.Slot)
+	if !a.orphanBlock(ctx, root, slot) {
+		return
+	}
+	if epoch := slot / slotsPerEpoch; a.unfinalized[epoch] != nil {
+		if _, ok := a.unfinalized[epoch][root]; ok {
+			a.forgetUnfinalizedBlock(root, slot)
+			a.writeUnfinalizedEpoch(ctx, epoch)
+		}
+	}
+	a.writeChainState(ctx)
+}
+
+// recordUnfinalizedBlock remembers an archived block, so that it can be orphaned if it does not become finalized. Only
+// the epoch of the block is rewritten in storage.
+func (a *Archiver) recordUnfinalizedBlock(ctx context.Context, header *v1.BeaconBlockHeader) {
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+
+	if err := a.loadChainState(ctx); err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	root, slot := common.Hash(header.Root), uint64(header.Header.Message.Slot)
+	if slot <= a.chain.FinalizedSlot {
+		return
+	}
+	epoch := slot / slotsPerEpoch
+	if _, ok := a.unfinalized[epoch][root]; ok {
+		return
+	}
+	if a.unfinalized[epoch] == nil {
+		a.unfinalized[epoch] = make(storage.UnfinalizedEpoch)
+	}
+	a.unfinalized[epoch][root] = slot
+	a.writeUnfinalizedEpoch(ctx, epoch)
+}
+
+// handleReorg orphans the blocks of the old chain of a reorg, walking back from its old head until a canonical block is
+// reached. The beacon node is queried before chainMu is taken.
+func (a *Archiver) handleReorg(ctx context.Context, reorg chainReorgEvent) {
+	depth, err := strconv.ParseUint(reorg.Depth, 10, 64)
+	if err != nil {
+		a.log.Warn("invalid chain reorg depth", "depth", reorg.Depth)
+		return
+	}
+
+	orphaned := make(map[common.Hash]uint64)
+	root := common.HexToHash(reorg.OldHeadBlock)
+	for i := uint64(0); i < min(depth, maxReorgDepth); i++ {
+		header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+			Block: root.String(),
+		})
+		if err != nil {
+			if !isBlockNotFound(err) {
+				a.log.Warn("failed to fetch orphaned block header", "err", err, "hash", root.String())
+			}
+			break
+		}
+		if header.Data.Canonical {
+			break
+		}
+
+		orphaned[root] = uint64(header.Data.Header.Message.Slot)
+		root = common.Hash(header.Data.Header.Message.ParentRoot)
+	}
+	if len(orphaned) == 0 {
+		return
+	}
+
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+
+	if err := a.loadChainState(ctx); err != nil {
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+
+	epochs := make(map[uint64]struct{})
+	for root, slot := range orphaned {
+		if a.orphanBlock(ctx, root, slot) {
+			epochs[a.forgetUnfinalizedBlock(root, slot)] = struct{}{}
+		}
+	}
+	for epoch := range epochs {
+		a.writeUnfinalizedEpoch(ctx, epoch)
+	}
+	a.writeChainState(ctx)
+}
+
+// checkFinality confirms or orphans the archived blocks once they are finalized, and deletes orphans whose retention
+// expired. The beacon node is queried without holding chainMu, so that live tracking is not blocked on it.
+func (a *Archiver) checkFinality(ctx context.Context) {
+	header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: "finalized",
+	})
+	if err != nil {
+		a.log.Warn("failed to fetch finalized block header", "err", err)
+		return
+	}
+	finalizedSlot := uint64(header.Data.Header.Message.Slot)
+
+	a.chainMu.Lock()
+	if err := a.loadChainState(ctx); err != nil {
+		a.chainMu.Unlock()
+		a.log.Error("failed to read chain state", "err", err)
+		return
+	}
+	// This includes blocks that failed to be checked before, or that were recorded while the last check was running
+	finalized := make(map[common.Hash]uint64)
+	for _, blocks := range a.unfinalized {
+		for root, slot := range blocks {
+			if slot <= finalizedSlot {
+				finalized[root] = slot
+			}
+		}
+	}
+	a.chainMu.Unlock()
+
+	canonical := make(map[common.Hash]bool, len(finalized))
+	for root := range finalized {
+		ok, err := a.isCanonical(ctx, root)
+		if err != nil {
+			// The block is checked again with the next check
+			a.log.Warn("failed to check whether block is canonical", "err", err, "hash", root.String())
+			continue
+		}
+		canonical[root] = ok
+	}
+
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+
+	epochs := make(map[uint64]struct{})
+	for root, ok := range canonical {
+		slot := finalized[root]
+		if ok {
+			a.restoreCanonical(ctx, root)
+		} else if !a.orphanBlock(ctx, root, slot) {
+			continue
+		}
+		epochs[a.forgetUnfinalizedBlock(root, slot)] = struct{}{}
+	}
+	for epoch := range epochs {
+		a.writeUnfinalizedEpoch(ctx, epoch)
+	}
+
+	if finalizedSlot > a.chain.FinalizedSlot {
+		a.chain.FinalizedSlot = finalizedSlot
+		a.log.Debug("checked finalized blocks", "finalizedSlot", finalizedSlot, "checked", len(canonical))
+	}
+
+	a.pruneOrphans(ctx)
+	a.writeChainState(ctx)
+}
+
+// isCanonical returns whether the beacon node considers the block canonical. Blocks the beacon node does not know are not
+// canonical.
+func (a *Archiver) isCanonical(ctx context.Context, root common.Hash) (bool, error) {
+	header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: root.String(),
+	})
+	if err != nil {
+		if isBlockNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return header.Data.Canonical, nil
+}
+
+// orphanBlock moves the blobs of a block to the orphan namespace. It returns false if the block is to be orphaned again
+// later. The caller must hold chainMu, and forget the unfinalized block and persist the chain state.
+func (a *Archiver) orphanBlock(ctx context.Context, root common.Hash, slot uint64) bool {
+	err := a.dataStoreClient.OrphanBlob(ctx, root)
+	if errors.Is(err, storage.ErrNotFound) {
+		// The block was never archived, or it was orphaned before
+		return true
+	}
+	if err != nil {
+		a.log.Error("failed to orphan block", "err", err, "hash", root.String())
+		return false
+	}
+
+	a.chain.Orphans[root] = storage.OrphanedBlock{
+		Slot:       slot,
+		OrphanedAt: time.Now().Unix(),
+	}
+	a.metrics.RecordOrphanedBlock()
+	a.metrics.RecordStoredOrphans(len(a.chain.Orphans))
+	a.log.Info("orphaned block", "hash", root.String(), "slot", slot)
+	return true
+}
+
+// restoreCanonical drops the orphaned copy of a block that became canonical again, and was rearchived since. The caller
+// must hold chainMu and persist the chain state.
+func (a *Archiver) restoreCanonical(ctx context.Context, root common.Hash) {
+	if _, ok := a.chain.Orphans[root]; !ok {
+		return
+	}
+
+	exists, err := a.dataStoreClient.Exists(ctx, root)
+	if err != nil || !exists {
+		return
+	}
+
+	if err := a.dataStoreClient.DeleteOrphanedBlob(ctx, root); err != nil {
+		a.log.Error("failed to delete orphaned blob", "err", err, "hash", root.String())
+		return
+	}
+	delete(a.chain.Orphans, root)
+	a.metrics.RecordStoredOrphans(len(a.chain.Orphans))
+}
+
+// pruneOrphans deletes the blobs of orphaned blocks once the orphan retention has expired. The caller must hold chainMu
+// and persist the chain state.
+func (a *Archiver) pruneOrphans(ctx context.Context) {
+	if a.cfg.OrphanRetention == 0 {
+		return
+	}
+
+	expired := time.Now().Add(-a.cfg.OrphanRetention).Unix()
+	for root, orphan := range a.chain.Orphans {
+		if orphan.OrphanedAt > expired {
+			continue
+		}
+
+		if err := a.dataStoreClient.DeleteOrphanedBlob(ctx, root); err != nil {
+			a.log.Error("failed to delete orphaned blob", "err", err, "hash", root.String())
+			continue
+		}
+		delete(a.chain.Orphans, root)
+		a.log.Info("deleted expired orphaned block", "hash", root.String(), "slot", orphan.Slot)
+	}
+	a.metrics.RecordStoredOrphans(len(a.chain.Orphans))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// addForkBlock adds a non-canonical block to the stub beacon node.
+func addForkBlock(t *testing.T, beacon *beacontest.StubBeaconClient, root common.Hash, slot uint64, parent common.Hash) {
+	beacon.Headers[root.String()] = &v1.BeaconBlockHeader{
+		Root:      phase0.Root(root),
+		Canonical: false,
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{
+				Slot:       phase0.Slot(slot),
+				ParentRoot: phase0.Root(parent),
+			},
+		},
+	}
+	beacon.Blobs[root.String()] = blobtest.NewBlobSidecars(t, 1)
+}
+
+// readUnfinalized returns the stored unfinalized blocks of every epoch.
+func readUnfinalized(t *testing.T, fs storage.DataStore) map[common.Hash]uint64 {
+	epochs, err := fs.ReadUnfinalizedEpochs(context.Background())
+	require.NoError(t, err)
+
+	blocks := make(map[common.Hash]uint64)
+	for _, epoch := range epochs {
+		for root, slot := range epoch {
+			blocks[root] = slot
+		}
+	}
+	return blocks
+}
+
+func TestArchiver_HandleReorgOrphansOldChain(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// The old chain forked off after Three
+	forkFour, forkFive := common.Hash{4, 4}, common.Hash{5, 5}
+	addForkBlock(t, beacon, forkFour, blobtest.StartSlot+4, blobtest.Three)
+	addForkBlock(t, beacon, forkFive, blobtest.StartSlot+5, forkFour)
+	for _, root := range []common.Hash{blobtest.Three, forkFour, forkFive} {
+		fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: root}})
+	}
+
+	svc.handleReorg(context.Background(), chainReorgEvent{
+		Slot:         "15",
+		Depth:        "3",
+		OldHeadBlock: forkFive.String(),
+		NewHeadBlock: blobtest.Five.String(),
+	})
+
+	fs.CheckExistsOrFail(t, blobtest.Three)
+	for _, root := range []common.Hash{forkFour, forkFive} {
+		fs.CheckNotExistsOrFail(t, root)
+		data, err := fs.ReadOrphanedBlob(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, root, data.Header.BeaconBlockHash)
+	}
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Len(t, state.Orphans, 2)
+	require.Equal(t, blobtest.StartSlot+4, state.Orphans[forkFour].Slot)
+	require.NotZero(t, state.Orphans[forkFive].OrphanedAt)
+}
+
+func TestArchiver_FinalityConfirmsAndOrphansBlocks(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// Blocks Three to Five are archived by live tracking, next to a block that lost the fork choice
+	fork := common.Hash{2, 2}
+	addForkBlock(t, beacon, fork, blobtest.StartSlot+2, blobtest.One)
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: blobtest.Two}})
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: fork}})
+	svc.recordUnfinalizedBlock(context.Background(), beacon.Headers[fork.String()])
+	svc.processBlocksUntilKnownBlock(context.Background())
+
+	require.Len(t, readUnfinalized(t, fs), 4)
+
+	// Three is finalized
+	svc.checkFinality(context.Background())
+
+	fs.CheckNotExistsOrFail(t, fork)
+	for _, root := range []common.Hash{blobtest.Three, blobtest.Four, blobtest.Five} {
+		fs.CheckExistsOrFail(t, root)
+	}
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, blobtest.StartSlot+3, state.FinalizedSlot)
+	require.Equal(t, map[common.Hash]uint64{
+		blobtest.Four: blobtest.StartSlot + 4,
+		blobtest.Five: blobtest.StartSlot + 5,
+	}, readUnfinalized(t, fs))
+	require.Contains(t, state.Orphans, fork)
+}
+
+func TestArchiver_UnfinalizedBlocksStoredPerEpoch(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	later := common.Hash{9, 9}
+	addForkBlock(t, beacon, later, 2*slotsPerEpoch+1, blobtest.Five)
+	svc.recordUnfinalizedBlock(context.Background(), beacon.Headers[blobtest.Five.String()])
+	svc.recordUnfinalizedBlock(context.Background(), beacon.Headers[later.String()])
+
+	epochs, err := fs.ReadUnfinalizedEpochs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]storage.UnfinalizedEpoch{
+		0: {blobtest.Five: blobtest.StartSlot + 5},
+		2: {later: 2*slotsPerEpoch + 1},
+	}, epochs)
+
+	// Once every block of an epoch is finalized, the epoch is deleted
+	beacon.Headers["finalized"] = beacon.Headers[blobtest.Five.String()]
+	svc.checkFinality(context.Background())
+
+	epochs, err = fs.ReadUnfinalizedEpochs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]storage.UnfinalizedEpoch{
+		2: {later: 2*slotsPerEpoch + 1},
+	}, epochs)
+}
+
+func TestArchiver_StoredBlocksAreTracked(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	fork := common.Hash{4, 4}
+	addForkBlock(t, beacon, fork, blobtest.StartSlot+4, blobtest.Three)
+
+	// Blocks stored by the backfill or a rearchive are tracked like the blocks of live tracking
+	_, _, err := svc.persistBlobsForBlockWithClient(context.Background(), svc.backfillClient, "14", false)
+	require.NoError(t, err)
+	_, err = svc.rearchiveBlock(context.Background(), blobtest.Five.String(), false)
+	require.NoError(t, err)
+	require.Equal(t, map[common.Hash]uint64{
+		blobtest.Four: blobtest.StartSlot + 4,
+		blobtest.Five: blobtest.StartSlot + 5,
+	}, readUnfinalized(t, fs))
+
+	// A block that is not canonical is stored as an orphan right away
+	_, err = svc.rearchiveBlock(context.Background(), fork.String(), false)
+	require.NoError(t, err)
+	fs.CheckNotExistsOrFail(t, fork)
+	_, err = fs.ReadOrphanedBlob(context.Background(), fork)
+	require.NoError(t, err)
+	require.NotContains(t, readUnfinalized(t, fs), fork)
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, state.Orphans, fork)
+}
+
+func TestArchiver_PruneExpiredOrphans(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.cfg.OrphanRetention = time.Hour
+
+	expired, recent := common.Hash{7, 7}, common.Hash{8, 8}
+	for _, root := range []common.Hash{expired, recent} {
+		fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: root}})
+		require.NoError(t, fs.OrphanBlob(context.Background(), root))
+	}
+	require.NoError(t, fs.WriteChainState(context.Background(), storage.ChainState{
+		FinalizedSlot: blobtest.StartSlot + 3,
+		Orphans: map[common.Hash]storage.OrphanedBlock{
+			expired: {Slot: 1, OrphanedAt: time.Now().Add(-2 * time.Hour).Unix()},
+			recent:  {Slot: 2, OrphanedAt: time.Now().Unix()},
+		},
+	}))
+
+	svc.checkFinality(context.Background())
+
+	_, err := fs.ReadOrphanedBlob(context.Background(), expired)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = fs.ReadOrphanedBlob(context.Background(), recent)
+	require.NoError(t, err)
+
+	state, err := fs.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.NotContains(t, state.Orphans, expired)
+	require.Contains(t, state.Orphans, recent)
+}
+
+func TestArchiver_TrackChainHandlesQueuedEvents(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	fork := common.Hash{5, 5}
+	addForkBlock(t, beacon, fork, blobtest.StartSlot+5, blobtest.Four)
+	fs.WriteOrFail(t, storage.BlobData{Header: storage.Header{BeaconBlockHash: fork}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chain := newChainEvents()
+	go svc.trackChain(ctx, chain)
+
+	chain.reorgs <- chainReorgEvent{Slot: "15", Depth: "1", OldHeadBlock: fork.String(), NewHeadBlock: blobtest.Five.String()}
+	require.Eventually(t, func() bool {
+		_, err := fs.ReadOrphanedBlob(context.Background(), fork)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Three is finalized
+	chain.finalized <- struct{}{}
+	require.Eventually(t, func() bool {
+		state, err := fs.ReadChainState(context.Background())
+		require.NoError(t, err)
+		return state.FinalizedSlot == blobtest.StartSlot+3
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/verify"
+	validator "github.com/base-org/blob-archiver/validator/service"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// blockProvider is implemented by beacon clients that return signed blocks, which peer sidecars are checked against.
+type blockProvider interface {
+	client.SignedBeaconBlockProvider
+}
+
+// peerBeaconClient fetches the blob sidecars of a block from another blob archiver when the beacon node no longer has
+// them, e.g. because they are past its retention window. Sidecars from the peer are only returned if they are the
+// complete set of blobs of the requested block, as listed in the block from the beacon node, and pass KZG
+// verification. Every other request is sent to the wrapped client.
+type peerBeaconClient struct {
+	BeaconClient
+	blocks  blockProvider
+	peer    validator.BlobSidecarClient
+	log     log.Logger
+	metrics metrics.Metricer
+}
+
+// BlobSidecars implements client.BlobSidecarsProvider. The peer is asked if the beacon node does not know the block, or
+// returns no sidecars for it. The response of the beacon node is returned if the block has no blobs, and an error if
+// neither the beacon node nor the peer return the blobs of a block that has some.
+func (c *peerBeaconClient) BlobSidecars(ctx context.Context, opts *api.BlobSidecarsOpts) (*api.Response[[]*deneb.BlobSidecar], error) {
+	res, err := c.BeaconClient.BlobSidecars(ctx, opts)
+	if err == nil && len(res.Data) > 0 {
+		return res, nil
+	}
+	if err != nil && !isBlockNotFound(err) {
+		return nil, err
+	}
+
+	// Only blocks identified by their root can be verified
+	root := common.HexToHash(opts.Block)
+	if root.Hex() != opts.Block {
+		return res, err
+	}
+
+	commitments, blockErr := verify.BlockCommitments(ctx, c.blocks, root)
+	if isBlockNotFound(blockErr) {
+		return res, err
+	} else if blockErr != nil {
+		return nil, fmt.Errorf("failed to fetch block: %w", blockErr)
+	}
+	if len(commitments) == 0 {
+		return res, err
+	}
+
+	status, sidecars, peerErr := c.peer.FetchSidecars(ctx, root.Hex(), validator.FormatSSZ)
+	if peerErr != nil {
+		return nil, fmt.Errorf("failed to fetch blob sidecars from peer: %w", peerErr)
+	}
+	if status != http.StatusOK && status != http.StatusNotFound {
+		return nil, fmt.Errorf("failed to fetch blob sidecars from peer: status %d", status)
+	}
+	if status == http.StatusNotFound || len(sidecars.Data) == 0 {
+		return nil, fmt.Errorf("%d blob sidecars of block %s are neither on the beacon node nor on the peer", len(commitments), root)
+	}
+
+	if err := verify.CompleteBlobSidecars(root, commitments, sidecars.Data); err != nil {
+		c.metrics.RecordPeerSidecars(false)
+		c.log.Error("peer returned invalid blob sidecars", "block", opts.Block, "err", err)
+		return nil, fmt.Errorf("invalid blob sidecars from peer: %w", err)
+	}
+
+	c.metrics.RecordPeerSidecars(true)
+	c.log.Debug("fetched blob sidecars from peer", "block", opts.Block, "count", len(sidecars.Data))
+	return &api.Response[[]*deneb.BlobSidecar]{Data: sidecars.Data}, nil
+}
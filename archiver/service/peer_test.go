@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	validator "github.com/base-org/blob-archiver/validator/service"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPeer is a blob API that serves the given sidecars by block root.
+type stubPeer struct {
+	sidecars map[string][]*deneb.BlobSidecar
+	requests int
+}
+
+func (p *stubPeer) FetchSidecars(_ context.Context, id string, _ validator.Format) (int, storage.BlobSidecars, error) {
+	p.requests++
+	sidecars, ok := p.sidecars[id]
+	if !ok {
+		return http.StatusNotFound, storage.BlobSidecars{}, nil
+	}
+	return http.StatusOK, storage.BlobSidecars{Data: sidecars}, nil
+}
+
+func TestPeerBeaconClient(t *testing.T) {
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 2)
+	pruned, prunedSidecars := blobtest.NewVerifiableBlobSidecars(t, 1)
+	truncated, truncatedSidecars := blobtest.NewVerifiableBlobSidecars(t, 3)
+	beacon := beacontest.NewEmptyStubBeaconClient()
+	beacon.Blobs[root.String()] = sidecars
+	beacon.Blobs[pruned.String()] = []*deneb.BlobSidecar{}
+	beacon.Commitments[pruned.String()] = blobtest.Commitments(prunedSidecars)
+	beacon.Blobs[truncated.String()] = []*deneb.BlobSidecar{}
+	beacon.Commitments[truncated.String()] = blobtest.Commitments(truncatedSidecars)
+	beacon.Blobs[blobtest.Three.String()] = []*deneb.BlobSidecar{}
+	peer := &stubPeer{sidecars: map[string][]*deneb.BlobSidecar{}}
+
+	c := &peerBeaconClient{
+		BeaconClient: beacon,
+		blocks:       beacon,
+		peer:         peer,
+		log:          testlog.Logger(t, log.LvlInfo),
+		metrics:      metrics.NewMetrics(),
+	}
+	fetch := func(block string) (*api.Response[[]*deneb.BlobSidecar], error) {
+		return c.BlobSidecars(context.Background(), &api.BlobSidecarsOpts{Block: block})
+	}
+
+	// The beacon node is asked first
+	res, err := fetch(root.String())
+	require.NoError(t, err)
+	require.Equal(t, sidecars, res.Data)
+	require.Zero(t, peer.requests)
+
+	// Blocks without blobs are not requested from the peer
+	res, err = fetch(blobtest.Three.String())
+	require.NoError(t, err)
+	require.Empty(t, res.Data)
+	require.Zero(t, peer.requests)
+
+	// Blocks the beacon node has no sidecars for are fetched from the peer, and fail if the peer has none either
+	_, err = fetch(pruned.String())
+	require.ErrorContains(t, err, "neither on the beacon node nor on the peer")
+	require.Equal(t, 1, peer.requests)
+
+	peer.sidecars[pruned.String()] = prunedSidecars
+	res, err = fetch(pruned.String())
+	require.NoError(t, err)
+	require.Equal(t, prunedSidecars, res.Data)
+
+	// Blocks unknown to both return the beacon node's response
+	_, err = fetch(blobtest.One.String())
+	require.True(t, isBlockNotFound(err))
+
+	// Invalid or incomplete sidecars are never returned
+	beacon.Commitments[blobtest.Two.String()] = blobtest.Commitments(prunedSidecars)
+	peer.sidecars[blobtest.Two.String()] = prunedSidecars
+	_, err = fetch(blobtest.Two.String())
+	require.ErrorContains(t, err, "invalid blob sidecars from peer")
+
+	peer.sidecars[truncated.String()] = truncatedSidecars[:2]
+	_, err = fetch(truncated.String())
+	require.ErrorContains(t, err, "expected 3 sidecars, got 2")
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum-optimism/optimism/op-service/retry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/google/uuid"
+)
+
+// CreateRearchiveItemsJob persists a job that rearchives the given blocks, and starts it in the background. Blocks that
+// are identified by a versioned hash are searched for between the slots searchFrom and searchTo.
+func (a *Archiver) CreateRearchiveItemsJob(ctx context.Context, items []storage.RearchiveItem, searchFrom uint64, searchTo uint64, dryRun bool) (storage.RearchiveJob, error) {
+	if !a.Status().Leader {
+		return storage.RearchiveJob{}, ErrNotLeader
+	}
+
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	if err := a.loadRearchiveJobs(ctx); err != nil {
+		return storage.RearchiveJob{}, err
+	}
+
+	jobItems := make([]storage.RearchiveItem, len(items))
+	for i, item := range items {
+		jobItems[i] = storage.RearchiveItem{
+			BlockRoot:     item.BlockRoot,
+			Slot:          item.Slot,
+			VersionedHash: item.VersionedHash,
+			Status:        storage.ItemStatusPending,
+		}
+	}
+
+	now := time.Now().Unix()
+	job := storage.RearchiveJob{
+		Id:          uuid.New().String(),
+		Status:      storage.JobStatusPending,
+		From:        searchFrom,
+		To:          searchTo,
+		NextSlot:    searchFrom,
+		FailedSlots: make([]uint64, 0),
+		Items:       jobItems,
+		DryRun:      dryRun,
+		Report: storage.RearchiveReport{
+			IdenticalSlots: make([]uint64, 0),
+			DifferentSlots: make([]storage.SlotDifference, 0),
+			MissingSlots:   make([]uint64, 0),
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	a.jobs[job.Id] = job
+	a.writeRearchiveJobs(ctx)
+
+	a.log.Info("created rearchive job", "id", job.Id, "items", len(jobItems), "dryRun", dryRun)
+	a.startRearchiveJob(job.Id)
+	return job, nil
+}
+
+// rearchiveJobItems rearchives the remaining items of a job. Items that fail are recorded, and do not stop the job.
+func (a *Archiver) rearchiveJobItems(ctx context.Context, job storage.RearchiveJob) {
+	id := job.Id
+	index := &versionedHashIndex{next: job.From, to: job.To, roots: make(map[common.Hash]common.Hash)}
+
+	count := 0
+	for i := job.NextItem; i < len(job.Items); i++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		item := job.Items[i]
+		l := a.log.New("job", id, "item", i)
+		l.Info("rearchiving block")
+
+		comparison, found, err := a.rearchiveItem(ctx, index, item, job.DryRun)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+
+		item.Status = storage.ItemStatusRearchived
+		if job.DryRun {
+			item.Status = storage.ItemStatusCompared
+		}
+		switch {
+		case err != nil:
+			l.Error("failed to rearchive block", "err", err)
+			item.Status = storage.ItemStatusFailed
+			item.Error = err.Error()
+		case !found || !comparison.found:
+			l.Info("block not found during reachiving")
+			item.Status = storage.ItemStatusNotFound
+		default:
+			if len(comparison.differences) > 0 {
+				l.Warn("stored blobs differ from beacon node", "hash", comparison.root.String(), "differences", comparison.differences)
+			}
+			root, slot, canonical := comparison.root, comparison.slot, comparison.canonical
+			item.ResolvedRoot = &root
+			item.ResolvedSlot = &slot
+			item.Canonical = &canonical
+			item.Comparison = comparison.result()
+			item.Differences = comparison.differences
+		}
+		a.metrics.RecordProcessedBlock(metrics.BlockSourceRearchive)
+
+		count++
+		last := i == len(job.Items)-1
+		ok := a.updateRearchiveJob(ctx, id, err != nil || count%jobProgressInterval == 0, func(job *storage.RearchiveJob) {
+			// The items are copied, so that jobs returned by the archiver are not modified concurrently
+			job.Items = slices.Clone(job.Items)
+			job.Items[i] = item
+			job.NextItem = i + 1
+			job.Processed++
+			if err == nil {
+				comparison.addTo(&job.Report)
+			}
+			if last {
+				job.Status = job.FinalStatus()
+			}
+		})
+		if !ok {
+			return
+		}
+	}
+}
+
+// rearchiveItem resolves the block of an item and rearchives it, retrying on errors. It returns false if the block of a
+// versioned hash could not be found. See rearchiveBlock.
+func (a *Archiver) rearchiveItem(ctx context.Context, index *versionedHashIndex, item storage.RearchiveItem, dryRun bool) (blockComparison, bool, error) {
+	var blockId string
+	switch {
+	case item.BlockRoot != nil:
+		blockId = item.BlockRoot.String()
+	case item.Slot != nil:
+		blockId = strconv.FormatUint(*item.Slot, 10)
+	case item.VersionedHash != nil:
+		root, found, err := a.findVersionedHash(ctx, index, *item.VersionedHash)
+		if err != nil || !found {
+			return blockComparison{}, false, err
+		}
+		blockId = root.String()
+	default:
+		return blockComparison{}, false, errors.New("item has no block root, slot or versioned hash")
+	}
+
+	comparison, err := retry.Do(ctx, rearchiveMaximumRetries, retry.Exponential(), func() (blockComparison, error) {
+		return a.rearchiveBlock(ctx, blockId, dryRun)
+	})
+	return comparison, true, err
+}
+
+// versionedHashIndex maps the versioned hashes of the blobs in a range of slots to the roots of their blocks. It is
+// filled lazily, so that the search stops as soon as all versioned hashes of a job are found.
+type versionedHashIndex struct {
+	// next is the next slot to search, and to is the last slot of the range.
+	next  uint64
+	to    uint64
+	done  bool
+	roots map[common.Hash]common.Hash
+}
+
+// findVersionedHash returns the root of the block that contains the blob with the given versioned hash, searching the
+// canonical blocks of the index range.
+func (a *Archiver) findVersionedHash(ctx context.Context, index *versionedHashIndex, versionedHash common.Hash) (common.Hash, bool, error) {
+	for {
+		if root, ok := index.roots[versionedHash]; ok {
+			return root, true, nil
+		}
+		if index.done || index.next > index.to {
+			return common.Hash{}, false, nil
+		}
+
+		slot := index.next
+		_, err := retry.Do(ctx, rearchiveMaximumRetries, retry.Exponential(), func() (struct{}, error) {
+			return struct{}{}, a.indexVersionedHashes(ctx, index, slot)
+		})
+		if err != nil {
+			return common.Hash{}, false, err
+		}
+
+		if slot == index.to {
+			index.done = true
+		} else {
+			index.next = slot + 1
+		}
+	}
+}
+
+// indexVersionedHashes adds the versioned hashes of the blobs at the given slot to the index.
+func (a *Archiver) indexVersionedHashes(ctx context.Context, index *versionedHashIndex, slot uint64) error {
+	header, err := a.rearchiveClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: strconv.FormatUint(slot, 10),
+	})
+	if err != nil {
+		if isBlockNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	root := common.Hash(header.Data.Root)
+	blobSidecars, err := a.rearchiveClient.BlobSidecars(ctx, &api.BlobSidecarsOpts{
+		Block: root.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sidecar := range blobSidecars.Data {
+		commitment := kzg4844.Commitment(sidecar.KZGCommitment)
+		index.roots[common.Hash(kzg4844.CalcBlobHashV1(sha256.New(), &commitment))] = root
+	}
+	return nil
+}
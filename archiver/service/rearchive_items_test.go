@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_RearchiveItemsJob(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.setLeader()
+
+	// A block at the slot of Four that was orphaned, but is still known to the beacon node
+	fork := common.Hash{4, 4}
+	beacon.Headers[fork.String()] = &v1.BeaconBlockHeader{
+		Root:      phase0.Root(fork),
+		Canonical: false,
+		Header: &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{
+				Slot:       phase0.Slot(blobtest.StartSlot + 4),
+				ParentRoot: phase0.Root(blobtest.Three),
+			},
+		},
+	}
+	beacon.Blobs[fork.String()] = blobtest.NewBlobSidecars(t, 2)
+
+	// Three is stored with different blobs
+	fs.WriteOrFail(t, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.Three},
+		BlobSidecars: storage.BlobSidecars{Data: blobtest.NewBlobSidecars(t, 1)},
+	})
+
+	commitment := kzg4844.Commitment(beacon.Blobs[blobtest.Five.String()][2].KZGCommitment)
+	versionedHash := common.Hash(kzg4844.CalcBlobHashV1(sha256.New(), &commitment))
+	unknownHash := common.Hash{0x01, 0xff}
+	slot := blobtest.StartSlot + 1
+	unknownRoot := common.Hash{0xff}
+
+	job, err := svc.CreateRearchiveItemsJob(context.Background(), []storage.RearchiveItem{
+		{BlockRoot: &blobtest.Three},
+		{BlockRoot: &fork},
+		{BlockRoot: &unknownRoot},
+		{Slot: &slot},
+		{VersionedHash: &versionedHash},
+		{VersionedHash: &unknownHash},
+	}, blobtest.StartSlot, blobtest.EndSlot, false)
+	require.NoError(t, err)
+	require.Len(t, job.Items, 6)
+	require.Equal(t, storage.ItemStatusPending, job.Items[0].Status)
+
+	job = waitForJob(t, svc, job.Id)
+	require.Equal(t, storage.JobStatusCompleted, job.Status)
+	require.Equal(t, uint64(6), job.Processed)
+	require.Equal(t, 6, job.NextItem)
+
+	three := job.Items[0]
+	require.Equal(t, storage.ItemStatusRearchived, three.Status)
+	require.Equal(t, blobtest.Three, *three.ResolvedRoot)
+	require.Equal(t, blobtest.StartSlot+3, *three.ResolvedSlot)
+	require.True(t, *three.Canonical)
+	require.Equal(t, "different", three.Comparison)
+	require.NotEmpty(t, three.Differences)
+
+	orphaned := job.Items[1]
+	require.Equal(t, storage.ItemStatusRearchived, orphaned.Status)
+	require.Equal(t, fork, *orphaned.ResolvedRoot)
+	require.False(t, *orphaned.Canonical)
+	require.Equal(t, "missing", orphaned.Comparison)
+
+	require.Equal(t, storage.ItemStatusNotFound, job.Items[2].Status)
+	require.Nil(t, job.Items[2].ResolvedRoot)
+
+	one := job.Items[3]
+	require.Equal(t, storage.ItemStatusRearchived, one.Status)
+	require.Equal(t, blobtest.One, *one.ResolvedRoot)
+
+	five := job.Items[4]
+	require.Equal(t, storage.ItemStatusRearchived, five.Status)
+	require.Equal(t, blobtest.Five, *five.ResolvedRoot)
+	require.Equal(t, blobtest.StartSlot+5, *five.ResolvedSlot)
+
+	require.Equal(t, storage.ItemStatusNotFound, job.Items[5].Status)
+
+	require.Equal(t, []uint64{blobtest.StartSlot + 4, blobtest.StartSlot + 1, blobtest.StartSlot + 5}, job.Report.MissingSlots)
+	require.Len(t, job.Report.DifferentSlots, 1)
+
+	for _, blob := range []common.Hash{blobtest.Three, blobtest.One, blobtest.Five} {
+		data := fs.ReadOrFail(t, blob)
+		require.Equal(t, beacon.Blobs[blob.String()], data.BlobSidecars.Data)
+	}
+
+	// The block that is no longer canonical is stored as an orphan
+	fs.CheckNotExistsOrFail(t, fork)
+	data, err := fs.ReadOrphanedBlob(context.Background(), fork)
+	require.NoError(t, err)
+	require.Equal(t, beacon.Blobs[fork.String()], data.BlobSidecars.Data)
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+
+	// The results are persisted
+	stored, err := fs.ReadRearchiveJobs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, job, stored[job.Id])
+}
+
+func TestArchiver_RearchiveItemsDryRun(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+	svc.setLeader()
+
+	fs.WriteOrFail(t, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.Two},
+		BlobSidecars: storage.BlobSidecars{Data: beacon.Blobs[blobtest.Two.String()]},
+	})
+
+	job, err := svc.CreateRearchiveItemsJob(context.Background(), []storage.RearchiveItem{
+		{BlockRoot: &blobtest.Two},
+		{BlockRoot: &blobtest.Four},
+	}, 0, 0, true)
+	require.NoError(t, err)
+
+	job = waitForJob(t, svc, job.Id)
+	require.Equal(t, storage.ItemStatusCompared, job.Items[0].Status)
+	require.Equal(t, "identical", job.Items[0].Comparison)
+	require.Equal(t, storage.ItemStatusCompared, job.Items[1].Status)
+	require.Equal(t, "missing", job.Items[1].Comparison)
+
+	fs.CheckNotExistsOrFail(t, blobtest.Four)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/base-org/blob-archiver/archiver/flags"
 	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/tlsutil"
 	"github.com/ethereum-optimism/optimism/op-service/httputil"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
 	"github.com/ethereum/go-ethereum/log"
@@ -48,12 +49,17 @@ func (a *ArchiverService) Start(ctx context.Context) error {
 		a.metricsServer = srv
 	}
 
-	srv, err := httputil.StartHTTPServer(a.cfg.ListenAddr, a.api.router)
+	tlsConfig, err := tlsutil.NewServerConfig(a.cfg.TLSConfig, a.log)
+	if err != nil {
+		return fmt.Errorf("failed to load Archiver API server TLS config: %w", err)
+	}
+
+	srv, err := tlsutil.StartHTTPServer(a.cfg.ListenAddr, a.api.router, tlsConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start Archiver API server: %w", err)
 	}
 
-	a.log.Info("Archiver API server started", "address", srv.Addr().String())
+	a.log.Info("Archiver API server started", "address", srv.Addr().String(), "tls", tlsConfig != nil)
 
 	return a.archiver.Start(ctx)
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/base-org/blob-archiver/common/beacon"
+	"github.com/base-org/blob-archiver/common/storage"
+)
+
+// snapshotInterval is how often the chain configuration of the beacon node is captured again, to pick up spec changes
+// such as newly scheduled forks.
+const snapshotInterval = time.Hour
+
+// chainConfigProvider is implemented by beacon clients that provide the chain configuration captured in snapshots.
+type chainConfigProvider interface {
+	client.GenesisProvider
+	client.SpecProvider
+}
+
+// runSnapshots captures the chain configuration of the beacon node at a fixed interval, until the context is done.
+func (a *Archiver) runSnapshots(ctx context.Context) {
+	t := time.NewTicker(snapshotInterval)
+	defer t.Stop()
+
+	for {
+		if err := a.captureSnapshot(ctx); err != nil {
+			a.log.Warn("failed to capture beacon snapshot", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// captureSnapshot stores the genesis and spec of the beacon node, so the API can serve them without a beacon node. The
+// snapshot is only written if it changed.
+func (a *Archiver) captureSnapshot(ctx context.Context) error {
+	if a.chainConfig == nil {
+		return errors.New("beacon client does not provide the genesis and spec")
+	}
+
+	genesis, err := a.chainConfig.Genesis(ctx, &api.GenesisOpts{})
+	if err != nil {
+		return err
+	}
+	if genesis.Data == nil {
+		return errors.New("beacon node returned no genesis")
+	}
+	spec, err := a.chainConfig.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		return err
+	}
+
+	snapshot := storage.BeaconSnapshot{
+		Genesis:    genesis.Data,
+		Spec:       beacon.FormatSpec(spec.Data),
+		CapturedAt: time.Now().Unix(),
+	}
+
+	stored, err := a.dataStoreClient.ReadBeaconSnapshot(ctx)
+	if err == nil && sameGenesis(stored.Genesis, snapshot.Genesis) && maps.Equal(stored.Spec, snapshot.Spec) {
+		return nil
+	} else if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	return a.dataStoreClient.WriteBeaconSnapshot(ctx, snapshot)
+}
+
+// sameGenesis returns whether two genesis are the same. The genesis time is compared as an instant, as its location
+// is lost when stored.
+func sameGenesis(a, b *v1.Genesis) bool {
+	return a != nil && b != nil && a.GenesisTime.Equal(b.GenesisTime) &&
+		a.GenesisValidatorsRoot == b.GenesisValidatorsRoot && a.GenesisForkVersion == b.GenesisForkVersion
+}
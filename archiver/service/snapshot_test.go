@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_CaptureSnapshot(t *testing.T) {
+	beacon := beacontest.NewDefaultStubBeaconClient(t)
+	svc, fs := setup(t, beacon)
+
+	// Nothing is stored until the beacon node provides both the genesis and the spec
+	require.Error(t, svc.captureSnapshot(context.Background()))
+	_, err := fs.ReadBeaconSnapshot(context.Background())
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	beacon.GenesisData = &v1.Genesis{
+		GenesisTime:           time.Unix(1606824023, 0),
+		GenesisValidatorsRoot: phase0.Root{1},
+		GenesisForkVersion:    phase0.Version{0, 0, 0, 0},
+	}
+	beacon.Config = map[string]any{
+		"SECONDS_PER_SLOT": 12 * time.Second,
+		"SLOTS_PER_EPOCH":  uint64(32),
+	}
+	require.NoError(t, svc.captureSnapshot(context.Background()))
+
+	snapshot, err := fs.ReadBeaconSnapshot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, beacon.GenesisData.GenesisValidatorsRoot, snapshot.Genesis.GenesisValidatorsRoot)
+	require.True(t, beacon.GenesisData.GenesisTime.Equal(snapshot.Genesis.GenesisTime))
+	require.Equal(t, map[string]string{"SECONDS_PER_SLOT": "12", "SLOTS_PER_EPOCH": "32"}, snapshot.Spec)
+
+	// An unchanged snapshot is not written again
+	require.NoError(t, fs.WriteBeaconSnapshot(context.Background(), storage.BeaconSnapshot{
+		Genesis:    snapshot.Genesis,
+		Spec:       snapshot.Spec,
+		CapturedAt: 1,
+	}))
+	require.NoError(t, svc.captureSnapshot(context.Background()))
+	snapshot, err = fs.ReadBeaconSnapshot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), snapshot.CapturedAt)
+
+	// A scheduled fork updates the snapshot
+	beacon.Config["ELECTRA_FORK_EPOCH"] = uint64(100)
+	require.NoError(t, svc.captureSnapshot(context.Background()))
+	snapshot, err = fs.ReadBeaconSnapshot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "100", snapshot.Spec["ELECTRA_FORK_EPOCH"])
+	require.NotEqual(t, int64(1), snapshot.CapturedAt)
+}
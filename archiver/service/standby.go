@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/base-org/blob-archiver/archiver/metrics"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum-optimism/optimism/op-service/retry"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ArchiverStatus describes the role of an archiver and how far the current leader is behind the beacon head.
+type ArchiverStatus struct {
+	ArchiverId string `json:"archiver_id"`
+	// Leader is true once this archiver holds the storage lock.
+	Leader bool `json:"leader"`
+	// Standby is true while this archiver is tracking the head and waiting for the storage lock.
+	Standby  bool   `json:"standby"`
+	LeaderId string `json:"leader_id,omitempty"`
+	// HeadSlot is the latest head seen by this archiver.
+	HeadSlot uint64 `json:"head_slot"`
+	// LeaderHeadRoot and LeaderHeadSlot are the latest head archived by the leader.
+	LeaderHeadRoot common.Hash `json:"leader_head_root"`
+	LeaderHeadSlot uint64      `json:"leader_head_slot"`
+	LagSlots       uint64      `json:"lag_slots"`
+}
+
+// Status returns the current status of the archiver.
+func (a *Archiver) Status() ArchiverStatus {
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+	return a.status
+}
+
+// setLatestHead records the latest head that was archived while holding the storage lock. It is published in the
+// lockfile so a standby archiver can catch up from it.
+func (a *Archiver) setLatestHead(header *v1.BeaconBlockHeader) {
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+
+	slot := uint64(header.Header.Message.Slot)
+	a.status.HeadSlot = slot
+	a.status.LeaderHeadRoot = common.Hash(header.Root)
+	a.status.LeaderHeadSlot = slot
+	a.status.LagSlots = 0
+}
+
+// setLeader records that this archiver obtained the storage lock.
+func (a *Archiver) setLeader() {
+	a.statusMu.Lock()
+	a.status.Leader = true
+	a.status.Standby = false
+	a.status.LeaderId = a.id
+	a.status.LagSlots = 0
+	a.statusMu.Unlock()
+
+	a.metrics.RecordStandby(false)
+	a.metrics.RecordStandbyLag(0)
+}
+
+// lockfile returns the lockfile content that this archiver writes while it holds the storage lock.
+func (a *Archiver) lockfile(timestamp int64) storage.Lockfile {
+	status := a.Status()
+	return storage.Lockfile{
+		ArchiverId: a.id,
+		Timestamp:  timestamp,
+		HeadRoot:   status.LeaderHeadRoot,
+		HeadSlot:   status.LeaderHeadSlot,
+	}
+}
+
+// trackHeadInStandby fetches the current head while another archiver holds the storage lock. This keeps the beacon
+// connection warm and records how far behind the leader is, without writing anything to storage.
+func (a *Archiver) trackHeadInStandby(ctx context.Context, leader storage.Lockfile) {
+	header, err := a.beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: "head",
+	})
+
+	if err != nil {
+		a.log.Warn("failed to fetch head while in standby", "err", err)
+		return
+	}
+
+	headSlot := uint64(header.Data.Header.Message.Slot)
+	lag := uint64(0)
+	if leader.HeadSlot != 0 && headSlot > leader.HeadSlot {
+		lag = headSlot - leader.HeadSlot
+	}
+
+	a.statusMu.Lock()
+	a.status.Standby = true
+	a.status.LeaderId = leader.ArchiverId
+	a.status.HeadSlot = headSlot
+	a.status.LeaderHeadRoot = leader.HeadRoot
+	a.status.LeaderHeadSlot = leader.HeadSlot
+	a.status.LagSlots = lag
+	a.statusMu.Unlock()
+
+	a.metrics.RecordStandby(true)
+	a.metrics.RecordStandbyLag(lag)
+
+	a.log.Debug("standby tracked head", "headSlot", headSlot, "leader", leader.ArchiverId, "leaderHeadSlot", leader.HeadSlot, "lag", lag)
+}
+
+// catchUpToLeaderHead archives every block from the current head back to the last head archived by the previous
+// leader. Unlike live tracking it does not stop at the first block that is already archived, so blocks the leader
+// missed before it stopped are filled in. If the leader head is no longer canonical, the walk stops at its slot.
+func (a *Archiver) catchUpToLeaderHead(ctx context.Context, leader storage.Lockfile) {
+	var start *v1.BeaconBlockHeader
+	currentBlockId := "head"
+	count := 0
+
+	for {
+		parked := false
+		current, alreadyExisted, err := retry.Do2(ctx, liveFetchBlobMaximumRetries, retry.Exponential(), func() (*v1.BeaconBlockHeader, bool, error) {
+			header, exists, err := a.persistBlobsForBlockToS3(ctx, currentBlockId, false)
+			if parked = errors.Is(err, errBlockParked); parked {
+				return header, exists, nil
+			}
+			return header, exists, err
+		})
+
+		if err != nil {
+			// The backfill fills whatever is left
+			a.log.Error("failed to catch up to leader head", "err", err, "blockId", currentBlockId)
+			return
+		}
+
+		if start == nil {
+			start = current
+			a.setLatestHead(current)
+		}
+
+		if !alreadyExisted && !parked {
+			a.metrics.RecordProcessedBlock(metrics.BlockSourceLive)
+			count++
+		}
+
+		if common.Hash(current.Root) == leader.HeadRoot || uint64(current.Header.Message.Slot) <= leader.HeadSlot ||
+			common.Hash(current.Root) == a.cfg.OriginBlock {
+			break
+		}
+
+		currentBlockId = current.Header.Message.ParentRoot.String()
+	}
+
+	a.log.Info("caught up to leader head", "startHash", start.Root.String(), "leaderHeadHash", leader.HeadRoot, "archived", count)
+}
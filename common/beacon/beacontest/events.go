@@ -0,0 +1,148 @@
+package beacontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// StubEventStream is a local beacon node event stream that serves /eth/v1/events as server-sent events. Events are
+// published to every connected subscriber.
+type StubEventStream struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu          sync.Mutex
+	subscribers map[chan string]struct{}
+	connections int
+	topics      [][]string
+	unavailable bool
+}
+
+// NewStubEventStream starts a stub event stream, which is closed at the end of the test.
+func NewStubEventStream(t *testing.T) *StubEventStream {
+	s := &StubEventStream{
+		t:           t,
+		subscribers: make(map[chan string]struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveEvents))
+	t.Cleanup(func() {
+		s.Disconnect()
+		s.server.Close()
+	})
+	return s
+}
+
+// URL returns the address of the stub beacon node.
+func (s *StubEventStream) URL() string {
+	return s.server.URL
+}
+
+// SetUnavailable makes the stream reject new subscribers, and disconnects the current ones if unavailable is true.
+func (s *StubEventStream) SetUnavailable(unavailable bool) {
+	s.mu.Lock()
+	s.unavailable = unavailable
+	s.mu.Unlock()
+
+	if unavailable {
+		s.Disconnect()
+	}
+}
+
+// Subscribers returns the number of currently connected subscribers.
+func (s *StubEventStream) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+// Connections returns the number of subscriptions made so far.
+func (s *StubEventStream) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// Topics returns the topics requested by the most recent subscription.
+func (s *StubEventStream) Topics() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.topics) == 0 {
+		return nil
+	}
+	return s.topics[len(s.topics)-1]
+}
+
+// Publish sends an event with the given topic and JSON encoded data to every connected subscriber.
+func (s *StubEventStream) Publish(topic string, data any) {
+	encoded, err := json.Marshal(data)
+	require.NoError(s.t, err)
+	message := fmt.Sprintf("event: %s\ndata: %s\n\n", topic, encoded)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for subscriber := range s.subscribers {
+		subscriber <- message
+	}
+}
+
+// Disconnect closes the connections of all current subscribers.
+func (s *StubEventStream) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for subscriber := range s.subscribers {
+		close(subscriber)
+		delete(s.subscribers, subscriber)
+	}
+}
+
+func (s *StubEventStream) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/eth/v1/events" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	if s.unavailable {
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	messages := make(chan string, 16)
+	s.subscribers[messages] = struct{}{}
+	s.connections++
+	s.topics = append(s.topics, r.URL.Query()["topics"])
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.subscribers[messages]; ok {
+			delete(s.subscribers, messages)
+			close(messages)
+		}
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher := w.(http.Flusher)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			_, _ = fmt.Fprint(w, message)
+			flusher.Flush()
+		}
+	}
+}
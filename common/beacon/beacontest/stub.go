@@ -8,6 +8,7 @@ import (
 
 	"github.com/attestantio/go-eth2-client/api"
 	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/base-org/blob-archiver/common/blobtest"
@@ -17,22 +18,71 @@ import (
 type StubBeaconClient struct {
 	Headers map[string]*v1.BeaconBlockHeader
 	Blobs   map[string][]*deneb.BlobSidecar
+	// Commitments holds the blob KZG commitments of blocks whose blobs are not in Blobs, e.g. blocks only known to peers.
+	// The commitments of blocks in Blobs are derived from their sidecars.
+	Commitments map[string][]deneb.KZGCommitment
+	// GenesisData is returned as the genesis of the chain, if set.
+	GenesisData *v1.Genesis
+	// Config is returned as the spec of the chain, if set.
+	Config map[string]any
+	// ExecutionOptimistic is returned in the metadata of every header.
+	ExecutionOptimistic bool
 }
 
 func (s *StubBeaconClient) BeaconBlockHeader(ctx context.Context, opts *api.BeaconBlockHeaderOpts) (*api.Response[*v1.BeaconBlockHeader], error) {
 	header, found := s.Headers[opts.Block]
 	if !found {
-		return nil, fmt.Errorf("block not found")
+		return nil, &api.Error{StatusCode: 404, Method: "GET", Endpoint: fmt.Sprintf("/eth/v1/beacon/headers/%s", opts.Block)}
 	}
 	return &api.Response[*v1.BeaconBlockHeader]{
-		Data: header,
+		Data:     header,
+		Metadata: map[string]any{"execution_optimistic": s.ExecutionOptimistic},
+	}, nil
+}
+
+func (s *StubBeaconClient) SignedBeaconBlock(ctx context.Context, opts *api.SignedBeaconBlockOpts) (*api.Response[*spec.VersionedSignedBeaconBlock], error) {
+	commitments, found := s.Commitments[opts.Block]
+	if !found {
+		blobs, ok := s.Blobs[opts.Block]
+		if !ok {
+			return nil, &api.Error{StatusCode: 404, Method: "GET", Endpoint: fmt.Sprintf("/eth/v2/beacon/blocks/%s", opts.Block)}
+		}
+		commitments = blobtest.Commitments(blobs)
+	}
+	return &api.Response[*spec.VersionedSignedBeaconBlock]{
+		Data: &spec.VersionedSignedBeaconBlock{
+			Version: spec.DataVersionDeneb,
+			Deneb: &deneb.SignedBeaconBlock{
+				Message: &deneb.BeaconBlock{
+					Body: &deneb.BeaconBlockBody{BlobKZGCommitments: commitments},
+				},
+			},
+		},
+	}, nil
+}
+
+func (s *StubBeaconClient) Genesis(ctx context.Context, opts *api.GenesisOpts) (*api.Response[*v1.Genesis], error) {
+	if s.GenesisData == nil {
+		return nil, &api.Error{StatusCode: 404, Method: "GET", Endpoint: "/eth/v1/beacon/genesis"}
+	}
+	return &api.Response[*v1.Genesis]{
+		Data: s.GenesisData,
+	}, nil
+}
+
+func (s *StubBeaconClient) Spec(ctx context.Context, opts *api.SpecOpts) (*api.Response[map[string]any], error) {
+	if s.Config == nil {
+		return nil, &api.Error{StatusCode: 404, Method: "GET", Endpoint: "/eth/v1/config/spec"}
+	}
+	return &api.Response[map[string]any]{
+		Data: s.Config,
 	}, nil
 }
 
 func (s *StubBeaconClient) BlobSidecars(ctx context.Context, opts *api.BlobSidecarsOpts) (*api.Response[[]*deneb.BlobSidecar], error) {
 	blobs, found := s.Blobs[opts.Block]
 	if !found {
-		return nil, fmt.Errorf("block not found")
+		return nil, &api.Error{StatusCode: 404, Method: "GET", Endpoint: fmt.Sprintf("/eth/v1/beacon/blob_sidecars/%s", opts.Block)}
 	}
 	return &api.Response[[]*deneb.BlobSidecar]{
 		Data: blobs,
@@ -41,15 +91,17 @@ func (s *StubBeaconClient) BlobSidecars(ctx context.Context, opts *api.BlobSidec
 
 func NewEmptyStubBeaconClient() *StubBeaconClient {
 	return &StubBeaconClient{
-		Headers: make(map[string]*v1.BeaconBlockHeader),
-		Blobs:   make(map[string][]*deneb.BlobSidecar),
+		Headers:     make(map[string]*v1.BeaconBlockHeader),
+		Blobs:       make(map[string][]*deneb.BlobSidecar),
+		Commitments: make(map[string][]deneb.KZGCommitment),
 	}
 }
 
 func NewDefaultStubBeaconClient(t *testing.T) *StubBeaconClient {
 	makeHeader := func(slot uint64, hash, parent common.Hash) *v1.BeaconBlockHeader {
 		return &v1.BeaconBlockHeader{
-			Root: phase0.Root(hash),
+			Root:      phase0.Root(hash),
+			Canonical: true,
 			Header: &phase0.SignedBeaconBlockHeader{
 				Message: &phase0.BeaconBlockHeader{
 					Slot:       phase0.Slot(slot),
@@ -111,5 +163,6 @@ func NewDefaultStubBeaconClient(t *testing.T) *StubBeaconClient {
 			strconv.FormatUint(startSlot+4, 10): fourBlobs,
 			strconv.FormatUint(startSlot+5, 10): fiveBlobs,
 		},
+		Commitments: make(map[string][]deneb.KZGCommitment),
 	}
 }
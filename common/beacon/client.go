@@ -2,10 +2,12 @@ package beacon
 
 import (
 	"context"
+	"fmt"
 
 	client "github.com/attestantio/go-eth2-client"
 	"github.com/attestantio/go-eth2-client/http"
 	"github.com/base-org/blob-archiver/common/flags"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/rs/zerolog"
 )
 
@@ -15,15 +17,43 @@ type Client interface {
 	client.BlobSidecarsProvider
 }
 
-// NewBeaconClient returns a new HTTP beacon client.
-func NewBeaconClient(ctx context.Context, cfg flags.BeaconConfig) (Client, error) {
+// httpClient is the HTTP beacon client, which can also stream events from the beacon node.
+type httpClient struct {
+	*http.Service
+	*EventStream
+}
+
+// NewBeaconClient returns a new HTTP beacon client for the configured beacon nodes. Requests go to the healthiest node
+// and fail over to the others (see multiClient). The client also implements EventSubscriber. It returns an error if
+// none of the beacon nodes can be reached.
+func NewBeaconClient(ctx context.Context, cfg flags.BeaconConfig, l log.Logger, m Metrics) (Client, error) {
 	cctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	c, err := http.New(cctx, http.WithAddress(cfg.BeaconURL), http.WithTimeout(cfg.BeaconClientTimeout), http.WithEnforceJSON(cfg.EnforceJSON), http.WithLogLevel(zerolog.ErrorLevel))
-	if err != nil {
-		return nil, err
+	var endpoints []*endpoint
+	for _, address := range cfg.Endpoints() {
+		// Nodes that are down are checked again by the health checks
+		c, err := http.New(cctx, http.WithAddress(address), http.WithTimeout(cfg.BeaconClientTimeout), http.WithEnforceJSON(cfg.EnforceJSON), http.WithLogLevel(zerolog.ErrorLevel), http.WithAllowDelayedStart(true))
+		if err != nil {
+			return nil, err
+		}
+
+		endpoints = append(endpoints, newEndpoint(address, &httpClient{
+			Service:     c.(*http.Service),
+			EventStream: NewEventStream(address),
+		}))
+	}
+
+	c := newMultiClient(endpoints, cfg.BeaconClientTimeout, l, m)
+	if !c.checkHealth(ctx) {
+		return nil, fmt.Errorf("none of the %d beacon nodes is reachable: %w", len(endpoints), client.ErrNotActive)
+	}
+
+	interval := cfg.HealthCheckInterval
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
 	}
+	go c.runHealthChecks(ctx, interval)
 
-	return c.(*http.Service), nil
+	return c, nil
 }
@@ -0,0 +1,135 @@
+package beacon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TopicHead        = "head"
+	TopicBlock       = "block"
+	TopicBlobSidecar = "blob_sidecar"
+	TopicChainReorg  = "chain_reorg"
+	// TopicFinalizedCheckpoint is emitted when a new checkpoint is finalized.
+	TopicFinalizedCheckpoint = "finalized_checkpoint"
+
+	// defaultEventStreamIdleTimeout is how long a stream may go without any data before it is considered stalled. A
+	// beacon node emits a head event every slot.
+	defaultEventStreamIdleTimeout = 60 * time.Second
+	maxEventSize                  = 1024 * 1024
+)
+
+// Event is a single event from the beacon node event stream. Data is the JSON payload of the event, which depends on
+// the topic.
+type Event struct {
+	Topic string
+	Data  json.RawMessage
+}
+
+// EventSubscriber is implemented by beacon clients that can stream events from the beacon node.
+type EventSubscriber interface {
+	// SubscribeEvents connects to the event stream and calls the handler for every event of the given topics. It blocks
+	// until the stream ends or the context is done, and returns an error if the stream could not be established or was
+	// lost.
+	SubscribeEvents(ctx context.Context, topics []string, handler func(Event)) error
+}
+
+// EventStream subscribes to the server-sent events of a beacon node, see
+// https://ethereum.github.io/beacon-APIs/#/Events/eventstream.
+type EventStream struct {
+	address     string
+	client      *http.Client
+	idleTimeout time.Duration
+}
+
+var _ EventSubscriber = (*EventStream)(nil)
+
+// NewEventStream returns an event stream for the beacon node at the given address.
+func NewEventStream(address string) *EventStream {
+	return &EventStream{
+		address:     strings.TrimSuffix(address, "/"),
+		client:      &http.Client{},
+		idleTimeout: defaultEventStreamIdleTimeout,
+	}
+}
+
+// SubscribeEvents implements EventSubscriber. A stream that does not send any data for a while is closed, so that a
+// stalled connection is noticed.
+func (s *EventStream) SubscribeEvents(ctx context.Context, topics []string, handler func(Event)) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	endpoint := fmt.Sprintf("%s/eth/v1/events?%s", s.address, url.Values{"topics": topics}.Encode())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected event stream status: %d", res.StatusCode)
+	}
+
+	idle := time.AfterFunc(s.idleTimeout, cancel)
+	defer idle.Stop()
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var topic string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		idle.Reset(s.idleTimeout)
+
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// A blank line dispatches the event
+			if data.Len() > 0 {
+				handler(Event{Topic: topic, Data: json.RawMessage(bytes.Clone(data.Bytes()))})
+			}
+			topic = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Comments are used as keep-alives
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				topic = value
+			case "data":
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(value)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if streamCtx.Err() != nil {
+		return fmt.Errorf("event stream idle for %s", s.idleTimeout)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("event stream closed")
+}
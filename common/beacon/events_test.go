@@ -0,0 +1,65 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream_ParsesEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/eth/v1/events", r.URL.Path)
+		require.Equal(t, []string{TopicHead, TopicChainReorg}, r.URL.Query()["topics"])
+		require.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		_, _ = fmt.Fprint(w, "event: head\ndata: {\"slot\":\"10\"}\n\n")
+		_, _ = fmt.Fprint(w, "event:chain_reorg\ndata: {\"slot\":\"11\",\ndata: \"depth\":\"2\"}\n\n")
+	}))
+	defer server.Close()
+
+	var events []Event
+	err := NewEventStream(server.URL+"/").SubscribeEvents(context.Background(), []string{TopicHead, TopicChainReorg}, func(event Event) {
+		events = append(events, event)
+	})
+	require.EqualError(t, err, "event stream closed")
+
+	require.Len(t, events, 2)
+	require.Equal(t, TopicHead, events[0].Topic)
+	require.JSONEq(t, `{"slot":"10"}`, string(events[0].Data))
+	require.Equal(t, TopicChainReorg, events[1].Topic)
+	require.JSONEq(t, `{"slot":"11","depth":"2"}`, string(events[1].Data))
+}
+
+func TestEventStream_Errors(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	err := NewEventStream(unavailable.URL).SubscribeEvents(context.Background(), []string{TopicHead}, func(Event) {})
+	require.EqualError(t, err, "unexpected event stream status: 503")
+
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer stalled.Close()
+
+	stream := NewEventStream(stalled.URL)
+	stream.idleTimeout = 50 * time.Millisecond
+	err = stream.SubscribeEvents(context.Background(), []string{TopicHead}, func(Event) {})
+	require.EqualError(t, err, "event stream idle for 50ms")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = NewEventStream(stalled.URL).SubscribeEvents(ctx, []string{TopicHead}, func(Event) {})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,81 @@
+package beacon
+
+import (
+	"github.com/ethereum-optimism/optimism/op-service/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records the health of the beacon nodes and the requests sent to them. Beacon nodes are identified by host, so
+// that credentials in their URL are not exposed.
+type Metrics interface {
+	RecordEndpointHealth(endpoint string, healthy bool, syncDistance uint64)
+	RecordEndpointRequest(endpoint string, success bool)
+	RecordEndpointFailover(endpoint string)
+}
+
+type metricsRecorder struct {
+	endpointHealthy      *prometheus.GaugeVec
+	endpointSyncDistance *prometheus.GaugeVec
+	endpointRequests     *prometheus.CounterVec
+	endpointFailovers    *prometheus.CounterVec
+}
+
+// NewMetrics registers the beacon node metrics on the given registry, in the namespace of the binary.
+func NewMetrics(registry *prometheus.Registry, namespace string) Metrics {
+	factory := metrics.With(registry)
+	return &metricsRecorder{
+		endpointHealthy: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "beacon_endpoint_healthy",
+			Help:      "1 if the beacon node answered its latest health check and is not syncing, 0 otherwise",
+		}, []string{"endpoint"}),
+		endpointSyncDistance: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "beacon_endpoint_sync_distance",
+			Help:      "number of slots the beacon node is behind the head, as reported by its latest health check",
+		}, []string{"endpoint"}),
+		endpointRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "beacon_endpoint_requests",
+			Help:      "number of requests sent to the beacon node",
+		}, []string{"endpoint", "result"}),
+		endpointFailovers: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "beacon_endpoint_failovers",
+			Help:      "number of requests that failed on the beacon node and were sent to another one",
+		}, []string{"endpoint"}),
+	}
+}
+
+func (m *metricsRecorder) RecordEndpointHealth(endpoint string, healthy bool, syncDistance uint64) {
+	if healthy {
+		m.endpointHealthy.WithLabelValues(endpoint).Set(1)
+	} else {
+		m.endpointHealthy.WithLabelValues(endpoint).Set(0)
+	}
+	m.endpointSyncDistance.WithLabelValues(endpoint).Set(float64(syncDistance))
+}
+
+func (m *metricsRecorder) RecordEndpointRequest(endpoint string, success bool) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	m.endpointRequests.WithLabelValues(endpoint, result).Inc()
+}
+
+func (m *metricsRecorder) RecordEndpointFailover(endpoint string) {
+	m.endpointFailovers.WithLabelValues(endpoint).Inc()
+}
+
+// NoopMetrics discards the beacon node and scheduler metrics.
+var NoopMetrics = noopMetrics{}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RecordEndpointHealth(string, bool, uint64) {}
+func (noopMetrics) RecordEndpointRequest(string, bool)        {}
+func (noopMetrics) RecordEndpointFailover(string)             {}
+func (noopMetrics) RecordBeaconRequestsInFlight(int)          {}
+func (noopMetrics) RecordBeaconRequestsQueued(string, int)    {}
+func (noopMetrics) RecordBeaconThrottled(float64)             {}
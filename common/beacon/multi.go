@@ -0,0 +1,312 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultHealthCheckInterval = 10 * time.Second
+
+// Node is one of the beacon nodes of a client.
+type Node struct {
+	// Name identifies the beacon node without exposing credentials in its URL.
+	Name   string
+	Client Client
+}
+
+// NodesProvider is implemented by beacon clients that send requests to several beacon nodes.
+type NodesProvider interface {
+	// Nodes returns the configured beacon nodes, in the configured order.
+	Nodes() []Node
+}
+
+// endpointClient is the beacon client of a single beacon node.
+type endpointClient interface {
+	Client
+	client.NodeSyncingProvider
+	client.GenesisProvider
+	client.SpecProvider
+	client.SignedBeaconBlockProvider
+	EventSubscriber
+}
+
+// endpoint is a beacon node of a multiClient, along with its state as of the latest health check.
+type endpoint struct {
+	address string
+	// name identifies the beacon node in logs and metrics without exposing credentials in its URL.
+	name   string
+	client endpointClient
+
+	// The following fields are protected by multiClient.mu
+	reachable    bool
+	syncing      bool
+	headSlot     uint64
+	syncDistance uint64
+}
+
+func newEndpoint(address string, c endpointClient) *endpoint {
+	name := address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	return &endpoint{
+		address: address,
+		name:    name,
+		client:  c,
+	}
+}
+
+// healthy returns whether the beacon node answered the latest health check and is not syncing. The caller must hold
+// multiClient.mu.
+func (e *endpoint) healthy() bool {
+	return e.reachable && !e.syncing
+}
+
+// rank orders beacon nodes by health: healthy nodes first, then syncing nodes, then unreachable nodes. The caller must
+// hold multiClient.mu.
+func (e *endpoint) rank() int {
+	switch {
+	case e.healthy():
+		return 0
+	case e.reachable:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// multiClient sends every request to the healthiest of several beacon nodes. If a request fails on a node, it is sent
+// to the next healthiest node, and the failed node is considered unreachable until its next successful health check.
+// Responses from a node, such as a block not being found, are returned as they are. Among equally healthy nodes, the
+// one with the lowest sync distance and then the highest head is preferred, and the configured order breaks ties.
+type multiClient struct {
+	log       log.Logger
+	metrics   Metrics
+	timeout   time.Duration
+	endpoints []*endpoint
+	mu        sync.Mutex
+}
+
+var (
+	_ endpointClient = (*multiClient)(nil)
+	_ NodesProvider  = (*multiClient)(nil)
+)
+
+func newMultiClient(endpoints []*endpoint, timeout time.Duration, l log.Logger, m Metrics) *multiClient {
+	return &multiClient{
+		log:       l,
+		metrics:   m,
+		timeout:   timeout,
+		endpoints: endpoints,
+	}
+}
+
+// runHealthChecks checks the health of the beacon nodes at the given interval, until the context is done.
+func (c *multiClient) runHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkHealth(ctx)
+		}
+	}
+}
+
+// checkHealth fetches the sync status of every beacon node concurrently. It returns whether any of them is reachable.
+func (c *multiClient) checkHealth(ctx context.Context) bool {
+	var wg sync.WaitGroup
+	for _, e := range c.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			c.checkEndpointHealth(ctx, e)
+		}(e)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.ContainsFunc(c.endpoints, func(e *endpoint) bool { return e.reachable })
+}
+
+// checkEndpointHealth fetches the sync status of a single beacon node.
+func (c *multiClient) checkEndpointHealth(ctx context.Context, e *endpoint) {
+	checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := e.client.NodeSyncing(checkCtx, &api.NodeSyncingOpts{})
+
+	c.mu.Lock()
+	wasHealthy := e.healthy()
+	if err != nil {
+		e.reachable = false
+	} else {
+		e.reachable = true
+		e.syncing = res.Data.IsSyncing
+		e.headSlot = uint64(res.Data.HeadSlot)
+		e.syncDistance = uint64(res.Data.SyncDistance)
+	}
+	healthy, syncDistance := e.healthy(), e.syncDistance
+	c.mu.Unlock()
+
+	c.metrics.RecordEndpointHealth(e.name, healthy, syncDistance)
+	if healthy == wasHealthy {
+		return
+	}
+	if healthy {
+		c.log.Info("beacon node is healthy", "endpoint", e.name, "headSlot", res.Data.HeadSlot)
+	} else if err != nil {
+		c.log.Warn("beacon node is unreachable", "endpoint", e.name, "err", err)
+	} else {
+		c.log.Warn("beacon node is syncing", "endpoint", e.name, "headSlot", res.Data.HeadSlot, "syncDistance", res.Data.SyncDistance)
+	}
+}
+
+// ranked returns the beacon nodes from the healthiest to the least healthy.
+func (c *multiClient) ranked() []*endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ranked := slices.Clone(c.endpoints)
+	slices.SortStableFunc(ranked, func(a, b *endpoint) int {
+		if a.rank() != b.rank() {
+			return a.rank() - b.rank()
+		}
+		if a.syncDistance != b.syncDistance {
+			if a.syncDistance < b.syncDistance {
+				return -1
+			}
+			return 1
+		}
+		if a.headSlot != b.headSlot {
+			if a.headSlot > b.headSlot {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	})
+	return ranked
+}
+
+// markUnreachable records that a request to the beacon node failed.
+func (c *multiClient) markUnreachable(e *endpoint) {
+	c.mu.Lock()
+	e.reachable = false
+	syncDistance := e.syncDistance
+	c.mu.Unlock()
+
+	c.metrics.RecordEndpointHealth(e.name, false, syncDistance)
+}
+
+// shouldFailover returns whether a request that failed with the given error should be sent to another beacon node. Any
+// response from the beacon node other than a server error is returned as it is.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// request calls the given function with the beacon nodes from the healthiest to the least healthy, until one of them
+// does not fail. Requests that fail because the context is done are not recorded, as they say nothing about the node.
+func request[T any](ctx context.Context, c *multiClient, call func(endpointClient) (T, error)) (T, error) {
+	var result T
+	var err error
+
+	endpoints := c.ranked()
+	for i, e := range endpoints {
+		result, err = call(e.client)
+		if err != nil && ctx.Err() != nil {
+			return result, err
+		}
+		if err == nil || !shouldFailover(ctx, err) {
+			c.metrics.RecordEndpointRequest(e.name, true)
+			return result, err
+		}
+
+		c.metrics.RecordEndpointRequest(e.name, false)
+		c.markUnreachable(e)
+		if i < len(endpoints)-1 {
+			c.metrics.RecordEndpointFailover(e.name)
+			c.log.Warn("beacon node request failed, failing over", "endpoint", e.name, "next", endpoints[i+1].name, "err", err)
+		}
+	}
+	return result, err
+}
+
+// BeaconBlockHeader implements client.BeaconBlockHeadersProvider.
+func (c *multiClient) BeaconBlockHeader(ctx context.Context, opts *api.BeaconBlockHeaderOpts) (*api.Response[*v1.BeaconBlockHeader], error) {
+	return request(ctx, c, func(e endpointClient) (*api.Response[*v1.BeaconBlockHeader], error) {
+		return e.BeaconBlockHeader(ctx, opts)
+	})
+}
+
+// BlobSidecars implements client.BlobSidecarsProvider.
+func (c *multiClient) BlobSidecars(ctx context.Context, opts *api.BlobSidecarsOpts) (*api.Response[[]*deneb.BlobSidecar], error) {
+	return request(ctx, c, func(e endpointClient) (*api.Response[[]*deneb.BlobSidecar], error) {
+		return e.BlobSidecars(ctx, opts)
+	})
+}
+
+// NodeSyncing implements client.NodeSyncingProvider, returning the sync status of the healthiest beacon node.
+func (c *multiClient) NodeSyncing(ctx context.Context, opts *api.NodeSyncingOpts) (*api.Response[*v1.SyncState], error) {
+	return request(ctx, c, func(e endpointClient) (*api.Response[*v1.SyncState], error) {
+		return e.NodeSyncing(ctx, opts)
+	})
+}
+
+// Genesis implements client.GenesisProvider.
+func (c *multiClient) Genesis(ctx context.Context, opts *api.GenesisOpts) (*api.Response[*v1.Genesis], error) {
+	return request(ctx, c, func(e endpointClient) (*api.Response[*v1.Genesis], error) {
+		return e.Genesis(ctx, opts)
+	})
+}
+
+// Spec implements client.SpecProvider.
+func (c *multiClient) Spec(ctx context.Context, opts *api.SpecOpts) (*api.Response[map[string]any], error) {
+	return request(ctx, c, func(e endpointClient) (*api.Response[map[string]any], error) {
+		return e.Spec(ctx, opts)
+	})
+}
+
+// SignedBeaconBlock implements client.SignedBeaconBlockProvider.
+func (c *multiClient) SignedBeaconBlock(ctx context.Context, opts *api.SignedBeaconBlockOpts) (*api.Response[*spec.VersionedSignedBeaconBlock], error) {
+	return request(ctx, c, func(e endpointClient) (*api.Response[*spec.VersionedSignedBeaconBlock], error) {
+		return e.SignedBeaconBlock(ctx, opts)
+	})
+}
+
+// SubscribeEvents implements EventSubscriber. It follows the event stream of the healthiest beacon node, so a
+// subscriber that reconnects after the stream is lost fails over to another node.
+func (c *multiClient) SubscribeEvents(ctx context.Context, topics []string, handler func(Event)) error {
+	return c.ranked()[0].client.SubscribeEvents(ctx, topics, handler)
+}
+
+// Nodes implements NodesProvider.
+func (c *multiClient) Nodes() []Node {
+	nodes := make([]Node, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		nodes = append(nodes, Node{Name: e.name, Client: e.client})
+	}
+	return nodes
+}
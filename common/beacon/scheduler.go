@@ -0,0 +1,289 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/base-org/blob-archiver/common/ratelimit"
+)
+
+const (
+	// minThrottleBackoff and maxThrottleBackoff bound how long requests are paused after the beacon node is overloaded.
+	minThrottleBackoff = 500 * time.Millisecond
+	maxThrottleBackoff = 30 * time.Second
+	// maxRateReduction bounds how far an overloaded beacon node reduces the rate, relative to the configured rate.
+	maxRateReduction = 16
+	// rateRecoverySteps is the number of successful requests it takes to recover the configured rate from zero.
+	rateRecoverySteps = 20
+)
+
+// Priority is the priority class of a beacon node request. Waiting requests of a higher priority are sent first.
+type Priority int
+
+const (
+	PriorityBackfill Priority = iota
+	PriorityRearchive
+	PriorityLive
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackfill:
+		return "backfill"
+	case PriorityRearchive:
+		return "rearchive"
+	case PriorityLive:
+		return "live"
+	default:
+		return "unknown"
+	}
+}
+
+var priorities = []Priority{PriorityBackfill, PriorityRearchive, PriorityLive}
+
+// SchedulerConfig limits the requests sent to the beacon node. A zero value means no limit.
+type SchedulerConfig struct {
+	RequestsPerSecond float64
+	MaxInFlight       int
+}
+
+// SchedulerMetrics records the state of a Scheduler.
+type SchedulerMetrics interface {
+	RecordBeaconRequestsInFlight(count int)
+	RecordBeaconRequestsQueued(priority string, count int)
+	RecordBeaconThrottled(requestsPerSecond float64)
+}
+
+// Scheduler coordinates the beacon node requests of several components. Requests are admitted under a shared token
+// bucket rate limit and a maximum number of requests in flight, and waiting requests are admitted by priority, then in
+// arrival order.
+//
+// When the beacon node responds with 429 Too Many Requests or 503 Service Unavailable, the scheduler pauses all
+// requests with an exponential backoff and halves its rate. Successful requests shorten the backoff and gradually
+// restore the configured rate.
+type Scheduler struct {
+	cfg     SchedulerConfig
+	metrics SchedulerMetrics
+	// bucket is nil without a rate limit.
+	bucket *ratelimit.TokenBucket
+
+	mu          sync.Mutex
+	queue       []*schedulerWaiter
+	inFlight    int
+	backoff     time.Duration
+	pausedUntil time.Time
+	timer       *time.Timer
+}
+
+// schedulerWaiter is a request waiting to be admitted. ready is closed once it is admitted.
+type schedulerWaiter struct {
+	priority Priority
+	ready    chan struct{}
+	admitted bool
+}
+
+// NewScheduler returns a scheduler with the given limits.
+func NewScheduler(cfg SchedulerConfig, m SchedulerMetrics) *Scheduler {
+	s := &Scheduler{
+		cfg:     cfg,
+		metrics: m,
+	}
+	if cfg.RequestsPerSecond > 0 {
+		s.bucket = ratelimit.NewTokenBucket(cfg.RequestsPerSecond, 1)
+	}
+	return s
+}
+
+// Client returns a client that sends every request through the scheduler with the given priority.
+func (s *Scheduler) Client(c Client, priority Priority) Client {
+	return &scheduledClient{
+		client:    c,
+		scheduler: s,
+		priority:  priority,
+	}
+}
+
+// acquire blocks until a request of the given priority is admitted, or the context is done. Every admitted request must
+// be released.
+func (s *Scheduler) acquire(ctx context.Context, priority Priority) error {
+	w := &schedulerWaiter{
+		priority: priority,
+		ready:    make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.queue = append(s.queue, w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.admitted {
+			// The request was admitted in the meantime, but is never sent
+			s.inFlight--
+		} else {
+			s.queue = slices.DeleteFunc(s.queue, func(queued *schedulerWaiter) bool { return queued == w })
+		}
+		s.dispatch()
+		return ctx.Err()
+	}
+}
+
+// release records the result of an admitted request, and admits the next waiting requests.
+func (s *Scheduler) release(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	if isThrottled(err) {
+		s.throttle()
+	} else if err == nil {
+		s.recoverRate()
+	}
+	s.dispatch()
+}
+
+// throttle pauses requests and halves the rate after the beacon node is overloaded. Responses to requests that were sent
+// before the pause do not extend it. The caller must hold mu.
+func (s *Scheduler) throttle() {
+	now := time.Now()
+	if now.Before(s.pausedUntil) {
+		return
+	}
+
+	s.backoff = min(max(s.backoff*2, minThrottleBackoff), maxThrottleBackoff)
+	s.pausedUntil = now.Add(s.backoff)
+
+	rate := float64(0)
+	if s.bucket != nil {
+		rate = max(s.bucket.Rate()/2, s.cfg.RequestsPerSecond/maxRateReduction)
+		s.bucket.SetRate(rate)
+	}
+	s.metrics.RecordBeaconThrottled(rate)
+}
+
+// recoverRate shortens the backoff and restores part of the configured rate after a successful request. The caller must
+// hold mu.
+func (s *Scheduler) recoverRate() {
+	s.backoff /= 2
+	if s.backoff < minThrottleBackoff {
+		s.backoff = 0
+	}
+
+	if s.bucket != nil && s.bucket.Rate() < s.cfg.RequestsPerSecond {
+		s.bucket.SetRate(min(s.bucket.Rate()+s.cfg.RequestsPerSecond/rateRecoverySteps, s.cfg.RequestsPerSecond))
+	}
+}
+
+// dispatch admits waiting requests, from the highest priority to the lowest, for as long as the limits allow. If a
+// request has to wait for the rate limit or a pause, a timer dispatches again once it is over. The caller must hold mu.
+func (s *Scheduler) dispatch() {
+	defer s.recordQueue()
+
+	for len(s.queue) > 0 {
+		if s.cfg.MaxInFlight > 0 && s.inFlight >= s.cfg.MaxInFlight {
+			// The next release dispatches again
+			return
+		}
+
+		if delay := time.Until(s.pausedUntil); delay > 0 {
+			s.dispatchAfter(delay)
+			return
+		}
+
+		if s.bucket != nil && !s.bucket.Allow() {
+			s.dispatchAfter(s.bucket.Delay())
+			return
+		}
+
+		next := 0
+		for i, w := range s.queue {
+			if w.priority > s.queue[next].priority {
+				next = i
+			}
+		}
+		w := s.queue[next]
+		s.queue = slices.Delete(s.queue, next, next+1)
+		w.admitted = true
+		s.inFlight++
+		close(w.ready)
+	}
+}
+
+// dispatchAfter dispatches again after the given delay, unless a dispatch is scheduled already. The caller must hold mu.
+func (s *Scheduler) dispatchAfter(delay time.Duration) {
+	if s.timer != nil {
+		return
+	}
+
+	s.timer = time.AfterFunc(max(delay, time.Millisecond), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.timer = nil
+		s.dispatch()
+	})
+}
+
+// recordQueue records the number of requests in flight and waiting. The caller must hold mu.
+func (s *Scheduler) recordQueue() {
+	s.metrics.RecordBeaconRequestsInFlight(s.inFlight)
+	for _, priority := range priorities {
+		queued := 0
+		for _, w := range s.queue {
+			if w.priority == priority {
+				queued++
+			}
+		}
+		s.metrics.RecordBeaconRequestsQueued(priority.String(), queued)
+	}
+}
+
+// isThrottled returns whether the beacon node responded that it is overloaded.
+func isThrottled(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable)
+}
+
+// scheduledClient sends every request through a Scheduler.
+type scheduledClient struct {
+	client    Client
+	scheduler *Scheduler
+	priority  Priority
+}
+
+// schedule calls the given function once the scheduler admits the request.
+func schedule[T any](ctx context.Context, c *scheduledClient, call func() (T, error)) (T, error) {
+	if err := c.scheduler.acquire(ctx, c.priority); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	result, err := call()
+	c.scheduler.release(err)
+	return result, err
+}
+
+// BeaconBlockHeader implements client.BeaconBlockHeadersProvider.
+func (c *scheduledClient) BeaconBlockHeader(ctx context.Context, opts *api.BeaconBlockHeaderOpts) (*api.Response[*v1.BeaconBlockHeader], error) {
+	return schedule(ctx, c, func() (*api.Response[*v1.BeaconBlockHeader], error) {
+		return c.client.BeaconBlockHeader(ctx, opts)
+	})
+}
+
+// BlobSidecars implements client.BlobSidecarsProvider.
+func (c *scheduledClient) BlobSidecars(ctx context.Context, opts *api.BlobSidecarsOpts) (*api.Response[[]*deneb.BlobSidecar], error) {
+	return schedule(ctx, c, func() (*api.Response[[]*deneb.BlobSidecar], error) {
+		return c.client.BlobSidecars(ctx, opts)
+	})
+}
@@ -0,0 +1,178 @@
+package beacon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingClient is a beacon client whose requests block until they are released, and record the order in which they
+// were sent.
+type blockingClient struct {
+	mu      sync.Mutex
+	sent    []string
+	started chan string
+	release chan error
+}
+
+func newBlockingClient() *blockingClient {
+	return &blockingClient{
+		started: make(chan string, 16),
+		release: make(chan error),
+	}
+}
+
+func (c *blockingClient) BeaconBlockHeader(_ context.Context, opts *api.BeaconBlockHeaderOpts) (*api.Response[*v1.BeaconBlockHeader], error) {
+	c.mu.Lock()
+	c.sent = append(c.sent, opts.Block)
+	c.mu.Unlock()
+
+	c.started <- opts.Block
+	if err := <-c.release; err != nil {
+		return nil, err
+	}
+	return &api.Response[*v1.BeaconBlockHeader]{}, nil
+}
+
+func (c *blockingClient) BlobSidecars(_ context.Context, _ *api.BlobSidecarsOpts) (*api.Response[[]*deneb.BlobSidecar], error) {
+	return &api.Response[[]*deneb.BlobSidecar]{}, nil
+}
+
+func (c *blockingClient) sentBlocks() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+// queued returns the number of requests waiting to be admitted.
+func (s *Scheduler) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+func requestHeader(c Client, block string, errs chan<- error) {
+	go func() {
+		_, err := c.BeaconBlockHeader(context.Background(), &api.BeaconBlockHeaderOpts{Block: block})
+		errs <- err
+	}()
+}
+
+func TestScheduler_Priority(t *testing.T) {
+	beaconClient := newBlockingClient()
+	s := NewScheduler(SchedulerConfig{MaxInFlight: 1}, NoopMetrics)
+	errs := make(chan error, 4)
+
+	requestHeader(s.Client(beaconClient, PriorityBackfill), "first", errs)
+	require.Equal(t, "first", <-beaconClient.started)
+
+	// Requests that arrive while the beacon node is busy are sent by priority, then in arrival order
+	requestHeader(s.Client(beaconClient, PriorityBackfill), "backfill", errs)
+	require.Eventually(t, func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+	requestHeader(s.Client(beaconClient, PriorityRearchive), "rearchive", errs)
+	require.Eventually(t, func() bool { return s.queued() == 2 }, time.Second, time.Millisecond)
+	requestHeader(s.Client(beaconClient, PriorityLive), "live", errs)
+	require.Eventually(t, func() bool { return s.queued() == 3 }, time.Second, time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		beaconClient.release <- nil
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, []string{"first", "live", "rearchive", "backfill"}, beaconClient.sentBlocks())
+}
+
+func TestScheduler_MaxInFlight(t *testing.T) {
+	beaconClient := newBlockingClient()
+	s := NewScheduler(SchedulerConfig{MaxInFlight: 2}, NoopMetrics)
+	c := s.Client(beaconClient, PriorityLive)
+	errs := make(chan error, 3)
+
+	requestHeader(c, "a", errs)
+	requestHeader(c, "b", errs)
+	<-beaconClient.started
+	<-beaconClient.started
+
+	requestHeader(c, "c", errs)
+	require.Eventually(t, func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+	require.Len(t, beaconClient.sentBlocks(), 2)
+
+	beaconClient.release <- nil
+	require.NoError(t, <-errs)
+	<-beaconClient.started
+	require.Len(t, beaconClient.sentBlocks(), 3)
+
+	beaconClient.release <- nil
+	beaconClient.release <- nil
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+}
+
+func TestScheduler_Throttle(t *testing.T) {
+	beaconClient := newBlockingClient()
+	s := NewScheduler(SchedulerConfig{RequestsPerSecond: 100}, NoopMetrics)
+	c := s.Client(beaconClient, PriorityLive)
+	errs := make(chan error, 2)
+
+	requestHeader(c, "a", errs)
+	<-beaconClient.started
+	beaconClient.release <- &api.Error{StatusCode: 429}
+	require.Error(t, <-errs)
+
+	// The beacon node is overloaded, so requests are paused and the rate is halved
+	require.Equal(t, float64(50), s.bucket.Rate())
+	start := time.Now()
+	requestHeader(c, "b", errs)
+	<-beaconClient.started
+	require.GreaterOrEqual(t, time.Since(start), minThrottleBackoff-50*time.Millisecond)
+	beaconClient.release <- nil
+	require.NoError(t, <-errs)
+
+	// Successful requests restore the rate and clear the backoff
+	require.Equal(t, float64(55), s.bucket.Rate())
+	s.mu.Lock()
+	require.Zero(t, s.backoff)
+	s.mu.Unlock()
+
+	// Other errors do not throttle
+	requestHeader(c, "c", errs)
+	<-beaconClient.started
+	beaconClient.release <- &api.Error{StatusCode: 404}
+	require.Error(t, <-errs)
+	require.Equal(t, float64(55), s.bucket.Rate())
+}
+
+func TestScheduler_CancelWhileQueued(t *testing.T) {
+	beaconClient := newBlockingClient()
+	s := NewScheduler(SchedulerConfig{MaxInFlight: 1}, NoopMetrics)
+	c := s.Client(beaconClient, PriorityLive)
+	errs := make(chan error, 2)
+
+	requestHeader(c, "a", errs)
+	<-beaconClient.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := c.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: "b"})
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	require.Zero(t, s.queued())
+
+	beaconClient.release <- nil
+	require.NoError(t, <-errs)
+
+	// The cancelled request does not hold a slot
+	requestHeader(c, "c", errs)
+	<-beaconClient.started
+	beaconClient.release <- nil
+	require.NoError(t, <-errs)
+	require.Equal(t, []string{"a", "c"}, beaconClient.sentBlocks())
+}
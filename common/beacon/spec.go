@@ -0,0 +1,43 @@
+package beacon
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// FormatSpec formats a spec returned by client.SpecProvider, whose values are parsed into Go types, back into the
+// strings returned by the /eth/v1/config/spec endpoint of the beacon API. Values that are not scalars are encoded as
+// JSON, and values that cannot be encoded are left out.
+func FormatSpec(spec map[string]any) map[string]string {
+	result := make(map[string]string, len(spec))
+	for k, v := range spec {
+		switch value := v.(type) {
+		case string:
+			result[k] = value
+		case uint64:
+			result[k] = strconv.FormatUint(value, 10)
+		case time.Duration:
+			result[k] = strconv.FormatInt(int64(value/time.Second), 10)
+		case time.Time:
+			result[k] = strconv.FormatInt(value.Unix(), 10)
+		case []byte:
+			result[k] = hexutil.Encode(value)
+		case phase0.Version:
+			result[k] = hexutil.Encode(value[:])
+		case phase0.DomainType:
+			result[k] = hexutil.Encode(value[:])
+		default:
+			// e.g. BLOB_SCHEDULE, a list of objects
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			result[k] = string(encoded)
+		}
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package beacon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSpec(t *testing.T) {
+	spec := map[string]any{
+		"CONFIG_NAME":               "mainnet",
+		"SLOTS_PER_EPOCH":           uint64(32),
+		"GENESIS_SLOT":              uint64(0),
+		"SECONDS_PER_SLOT":          12 * time.Second,
+		"MIN_GENESIS_TIME":          time.Unix(1606824000, 0),
+		"DEPOSIT_CONTRACT_ADDRESS":  []byte{0x00, 0x00, 0x00, 0x00, 0x21, 0x9a},
+		"DENEB_FORK_VERSION":        phase0.Version{0x04, 0x00, 0x00, 0x00},
+		"DOMAIN_BEACON_PROPOSER":    phase0.DomainType{0x00, 0x00, 0x00, 0x00},
+		"TERMINAL_TOTAL_DIFFICULTY": "58750000000000000000000",
+		"BLOB_SCHEDULE": []any{
+			map[string]any{"EPOCH": "269568", "MAX_BLOBS_PER_BLOCK": "6"},
+		},
+	}
+
+	require.Equal(t, map[string]string{
+		"CONFIG_NAME":               "mainnet",
+		"SLOTS_PER_EPOCH":           "32",
+		"GENESIS_SLOT":              "0",
+		"SECONDS_PER_SLOT":          "12",
+		"MIN_GENESIS_TIME":          "1606824000",
+		"DEPOSIT_CONTRACT_ADDRESS":  "0x00000000219a",
+		"DENEB_FORK_VERSION":        "0x04000000",
+		"DOMAIN_BEACON_PROPOSER":    "0x00000000",
+		"TERMINAL_TOTAL_DIFFICULTY": "58750000000000000000000",
+		"BLOB_SCHEDULE":             `[{"EPOCH":"269568","MAX_BLOBS_PER_BLOCK":"6"}]`,
+	}, FormatSpec(spec))
+}
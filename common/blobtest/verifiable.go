@@ -0,0 +1,95 @@
+package blobtest
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// commitmentListDepth is the depth of the blob_kzg_commitments list of a Deneb BeaconBlockBody.
+	commitmentListDepth = 12
+	// blobKzgCommitmentsIndex is the index of the blob_kzg_commitments field in a Deneb BeaconBlockBody.
+	blobKzgCommitmentsIndex = 11
+)
+
+// NewVerifiableBlobSidecars returns sidecars with valid KZG proofs and inclusion proofs, and the root of their block.
+func NewVerifiableBlobSidecars(t *testing.T, count int) (common.Hash, []*deneb.BlobSidecar) {
+	// The other fields of the block body, which are the same for every sidecar
+	var bodySiblings [4][32]byte
+	for i := range bodySiblings {
+		bodySiblings[i] = [32]byte(RandBytes(t, 32))
+	}
+
+	sidecars := make([]*deneb.BlobSidecar, count)
+	leaves := make([][32]byte, 1<<commitmentListDepth)
+	for i := range sidecars {
+		sidecar := NewBlobSidecar(t, uint(i))
+		// Every field element must be below the BLS modulus
+		for j := 0; j < len(sidecar.Blob); j += 32 {
+			sidecar.Blob[j] = 0
+		}
+
+		commitment, err := kzg4844.BlobToCommitment(kzg4844.Blob(sidecar.Blob))
+		require.NoError(t, err)
+		proof, err := kzg4844.ComputeBlobProof(kzg4844.Blob(sidecar.Blob), commitment)
+		require.NoError(t, err)
+		sidecar.KZGCommitment = deneb.KZGCommitment(commitment)
+		sidecar.KZGProof = deneb.KZGProof(proof)
+
+		var chunks [64]byte
+		copy(chunks[:], commitment[:])
+		leaves[i] = sha256.Sum256(chunks[:])
+		sidecars[i] = sidecar
+	}
+
+	// Merkleize the commitments list, recording the siblings of every commitment
+	layer := leaves
+	for depth := 0; depth < commitmentListDepth; depth++ {
+		for i, sidecar := range sidecars {
+			sidecar.KZGCommitmentInclusionProof[depth] = layer[(i>>depth)^1]
+		}
+		next := make([][32]byte, len(layer)/2)
+		for j := range next {
+			next[j] = sha256.Sum256(append(layer[2*j][:], layer[2*j+1][:]...))
+		}
+		layer = next
+	}
+
+	// Mix in the length of the list, and merkleize the body with the list at its field index
+	var length [32]byte
+	length[0] = byte(count)
+	bodyRoot := sha256.Sum256(append(layer[0][:], length[:]...))
+	for i, sibling := range bodySiblings {
+		if blobKzgCommitmentsIndex>>i&1 == 1 {
+			bodyRoot = sha256.Sum256(append(sibling[:], bodyRoot[:]...))
+		} else {
+			bodyRoot = sha256.Sum256(append(bodyRoot[:], sibling[:]...))
+		}
+	}
+
+	header := &phase0.BeaconBlockHeader{Slot: 100, BodyRoot: bodyRoot}
+	for _, sidecar := range sidecars {
+		sidecar.KZGCommitmentInclusionProof[commitmentListDepth] = length
+		copy(sidecar.KZGCommitmentInclusionProof[commitmentListDepth+1:], bodySiblings[:])
+		sidecar.SignedBlockHeader = &phase0.SignedBeaconBlockHeader{Message: header}
+	}
+
+	root, err := header.HashTreeRoot()
+	require.NoError(t, err)
+	return common.Hash(root), sidecars
+}
+
+// Commitments returns the KZG commitments of the given sidecars, as listed in the body of their block.
+func Commitments(sidecars []*deneb.BlobSidecar) []deneb.KZGCommitment {
+	commitments := make([]deneb.KZGCommitment, len(sidecars))
+	for i, sidecar := range sidecars {
+		commitments[i] = sidecar.KZGCommitment
+	}
+	return commitments
+}
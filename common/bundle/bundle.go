@@ -0,0 +1,260 @@
+// Package bundle reads and writes portable archive bundles, which hold the blobs of a range of slots so that they can
+// be moved between data stores.
+//
+// A bundle is a tar file. Its first entry is manifest.json, which describes the chain and the slot range of the bundle.
+// It is followed by one blobs/<block root>.ssz entry per block, holding the SSZ encoded blob sidecars of the block, and
+// ends with index.json, which lists every block of the bundle with the checksum of its entry.
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// Version is the version of the bundle format.
+	Version = 1
+
+	manifestName   = "manifest.json"
+	indexName      = "index.json"
+	blobsDirectory = "blobs"
+)
+
+var (
+	// ErrInvalidBundle is returned when a bundle is malformed or of an unsupported version.
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrChecksum is returned when the content of an entry does not match its checksum in the index.
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrChainMismatch is returned when a bundle is imported into a data store of another chain.
+	ErrChainMismatch = errors.New("bundle is of another chain")
+)
+
+// Chain identifies the chain the blobs of a bundle belong to.
+type Chain struct {
+	GenesisTime           int64         `json:"genesis_time"`
+	GenesisValidatorsRoot common.Hash   `json:"genesis_validators_root"`
+	GenesisForkVersion    hexutil.Bytes `json:"genesis_fork_version"`
+}
+
+// Manifest describes a bundle.
+type Manifest struct {
+	Version   int    `json:"version"`
+	CreatedAt int64  `json:"created_at"`
+	Chain     Chain  `json:"chain"`
+	FromSlot  uint64 `json:"from_slot"`
+	ToSlot    uint64 `json:"to_slot"`
+}
+
+// Entry is a block of a bundle.
+type Entry struct {
+	Slot      uint64      `json:"slot"`
+	BlockRoot common.Hash `json:"block_root"`
+	Path      string      `json:"path"`
+	Size      int64       `json:"size"`
+	// Checksum is the SHA-256 hash of the content of the entry.
+	Checksum common.Hash `json:"sha256"`
+}
+
+// Index lists the blocks of a bundle, in slot order.
+type Index struct {
+	Entries []Entry `json:"entries"`
+	// MissingSlots have a canonical block, but its blobs were not found in the data store that was exported.
+	MissingSlots []uint64 `json:"missing_slots"`
+}
+
+// Writer writes a bundle. Blocks are added in slot order, and the index is written when the writer is closed.
+type Writer struct {
+	tw    *tar.Writer
+	index Index
+}
+
+// NewWriter starts a bundle with the given manifest. The version and creation time of the manifest are set by the
+// writer.
+func NewWriter(w io.Writer, manifest Manifest) (*Writer, error) {
+	manifest.Version = Version
+	manifest.CreatedAt = time.Now().Unix()
+
+	bw := &Writer{
+		tw:    tar.NewWriter(w),
+		index: Index{Entries: []Entry{}, MissingSlots: []uint64{}},
+	}
+	if err := bw.writeJSON(manifestName, manifest); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+// Add writes the blobs of the block at the given slot.
+func (w *Writer) Add(slot uint64, data storage.BlobData) error {
+	content, err := data.BlobSidecars.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to encode blob sidecars of slot %d: %w", slot, err)
+	}
+
+	entry := Entry{
+		Slot:      slot,
+		BlockRoot: data.Header.BeaconBlockHash,
+		Path:      entryPath(data.Header.BeaconBlockHash),
+		Size:      int64(len(content)),
+		Checksum:  sha256.Sum256(content),
+	}
+	if err := w.writeFile(entry.Path, content); err != nil {
+		return err
+	}
+	w.index.Entries = append(w.index.Entries, entry)
+	return nil
+}
+
+// AddMissing records a slot whose blobs could not be exported.
+func (w *Writer) AddMissing(slot uint64) {
+	w.index.MissingSlots = append(w.index.MissingSlots, slot)
+}
+
+// Close writes the index and finishes the bundle. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.writeJSON(indexName, w.index); err != nil {
+		return err
+	}
+	return w.tw.Close()
+}
+
+func (w *Writer) writeJSON(name string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.writeFile(name, content)
+}
+
+func (w *Writer) writeFile(name string, content []byte) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.tw.Write(content)
+	return err
+}
+
+// Reader reads a bundle.
+type Reader struct {
+	r        io.ReadSeeker
+	Manifest Manifest
+	Index    Index
+}
+
+// NewReader reads the manifest and index of the given bundle.
+func NewReader(r io.ReadSeeker) (*Reader, error) {
+	br := &Reader{r: r}
+
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return nil, fmt.Errorf("%w: missing manifest", ErrInvalidBundle)
+	}
+	if err := json.NewDecoder(tr).Decode(&br.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode manifest: %v", ErrInvalidBundle, err)
+	}
+	if br.Manifest.Version != Version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, br.Manifest.Version)
+	}
+
+	// The index is the last entry
+	foundIndex := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if header.Name == indexName {
+			if err := json.NewDecoder(tr).Decode(&br.Index); err != nil {
+				return nil, fmt.Errorf("%w: failed to decode index: %v", ErrInvalidBundle, err)
+			}
+			foundIndex = true
+		}
+	}
+	if !foundIndex {
+		return nil, fmt.Errorf("%w: missing index", ErrInvalidBundle)
+	}
+	return br, nil
+}
+
+// ForEach calls fn with the blobs of every block of the bundle, in slot order. It returns ErrChecksum if an entry does
+// not match the index, and ErrInvalidBundle if an entry of the index is not in the bundle.
+func (r *Reader) ForEach(ctx context.Context, fn func(Entry, storage.BlobData) error) error {
+	entries := make(map[string]Entry, len(r.Index.Entries))
+	for _, entry := range r.Index.Entries {
+		entries[entry.Path] = entry
+	}
+
+	if _, err := r.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r.r)
+	seen := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		entry, ok := entries[header.Name]
+		if !ok {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if int64(len(content)) != entry.Size || common.Hash(sha256.Sum256(content)) != entry.Checksum {
+			return fmt.Errorf("%w: %s", ErrChecksum, entry.Path)
+		}
+
+		sidecars := api.BlobSidecars{}
+		if err := sidecars.UnmarshalSSZ(content); err != nil {
+			return fmt.Errorf("%w: failed to decode %s: %v", ErrInvalidBundle, entry.Path, err)
+		}
+		data := storage.BlobData{
+			Header:       storage.Header{BeaconBlockHash: entry.BlockRoot},
+			BlobSidecars: storage.BlobSidecars{Data: sidecars.Sidecars},
+		}
+		if err := fn(entry, data); err != nil {
+			return err
+		}
+		seen++
+	}
+
+	if seen != len(r.Index.Entries) {
+		return fmt.Errorf("%w: %d of %d indexed entries found", ErrInvalidBundle, seen, len(r.Index.Entries))
+	}
+	return nil
+}
+
+func entryPath(root common.Hash) string {
+	return blobsDirectory + "/" + root.String() + ".ssz"
+}
@@ -0,0 +1,221 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/storage/storagetest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+var genesisValidatorsRoot = phase0.Root{1, 2, 3}
+
+type stubGenesisBeaconClient struct {
+	*beacontest.StubBeaconClient
+}
+
+func (c *stubGenesisBeaconClient) Genesis(_ context.Context, _ *api.GenesisOpts) (*api.Response[*v1.Genesis], error) {
+	return &api.Response[*v1.Genesis]{Data: &v1.Genesis{
+		GenesisTime:           time.Unix(1606824023, 0),
+		GenesisValidatorsRoot: genesisValidatorsRoot,
+		GenesisForkVersion:    phase0.Version{0, 0, 0, 1},
+	}}, nil
+}
+
+// exportDefault exports the slots StartSlot to StartSlot+6 of a chain with a block with verifiable blobs at every slot
+// but the last one, from a data store holding every block but the one at StartSlot+4. It returns the roots of the blocks
+// by slot, starting at StartSlot.
+func exportDefault(t *testing.T) (*beacontest.StubBeaconClient, []common.Hash, []byte, ExportResult) {
+	l := testlog.Logger(t, log.LvlInfo)
+	beacon := beacontest.NewEmptyStubBeaconClient()
+	source := storagetest.NewTestFileStorage(t, l)
+	roots := make([]common.Hash, 6)
+	for i, count := range []int{1, 2, 0, 3, 1, 2} {
+		root, sidecars := blobtest.NewVerifiableBlobSidecars(t, count)
+		roots[i] = root
+		beacon.Headers[strconv.FormatUint(blobtest.StartSlot+uint64(i), 10)] = &v1.BeaconBlockHeader{Root: phase0.Root(root)}
+		beacon.Blobs[root.String()] = sidecars
+		if i != 4 {
+			source.WriteOrFail(t, storage.BlobData{
+				Header:       storage.Header{BeaconBlockHash: root},
+				BlobSidecars: storage.BlobSidecars{Data: sidecars},
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	result, err := Export(context.Background(), &stubGenesisBeaconClient{beacon}, source, blobtest.StartSlot, blobtest.StartSlot+6, &buf, l)
+	require.NoError(t, err)
+	return beacon, roots, buf.Bytes(), result
+}
+
+func TestExportImport(t *testing.T) {
+	l := testlog.Logger(t, log.LvlInfo)
+	beacon, roots, bundle, result := exportDefault(t)
+	require.Equal(t, ExportResult{Exported: 5, Missing: 1}, result)
+
+	r, err := NewReader(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	require.Equal(t, Version, r.Manifest.Version)
+	require.Equal(t, blobtest.StartSlot, r.Manifest.FromSlot)
+	require.Equal(t, blobtest.StartSlot+6, r.Manifest.ToSlot)
+	require.Equal(t, int64(1606824023), r.Manifest.Chain.GenesisTime)
+	require.Equal(t, genesisValidatorsRoot[:], r.Manifest.Chain.GenesisValidatorsRoot.Bytes())
+	require.Len(t, r.Index.Entries, 5)
+	require.Equal(t, roots[1], r.Index.Entries[1].BlockRoot)
+	require.Equal(t, blobtest.StartSlot+1, r.Index.Entries[1].Slot)
+	require.Equal(t, []uint64{blobtest.StartSlot + 4}, r.Index.MissingSlots)
+
+	target := storagetest.NewTestFileStorage(t, l)
+	// Existing blobs are not overwritten
+	existing := storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: roots[5]},
+		BlobSidecars: storage.BlobSidecars{Data: blobtest.NewBlobSidecars(t, 1)},
+	}
+	target.WriteOrFail(t, existing)
+
+	imported, err := Import(context.Background(), bytes.NewReader(bundle), target, beacon, common.Hash(genesisValidatorsRoot), l)
+	require.NoError(t, err)
+	require.Equal(t, ImportResult{Imported: 4, Skipped: 1}, imported)
+
+	for _, root := range roots[:4] {
+		data := target.ReadOrFail(t, root)
+		require.Len(t, data.BlobSidecars.Data, len(beacon.Blobs[root.String()]))
+		for i, sidecar := range data.BlobSidecars.Data {
+			require.Equal(t, beacon.Blobs[root.String()][i], sidecar)
+		}
+	}
+	target.CheckNotExistsOrFail(t, roots[4])
+	require.Equal(t, existing, target.ReadOrFail(t, roots[5]))
+
+	// Importing again skips everything
+	imported, err = Import(context.Background(), bytes.NewReader(bundle), target, beacon, common.Hash(genesisValidatorsRoot), l)
+	require.NoError(t, err)
+	require.Equal(t, ImportResult{Imported: 0, Skipped: 5}, imported)
+}
+
+func TestImportVerifiesChecksums(t *testing.T) {
+	l := testlog.Logger(t, log.LvlInfo)
+	beacon, roots, bundle, _ := exportDefault(t)
+
+	r, err := NewReader(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	// Corrupt a byte of the blob of the second block
+	entry := r.Index.Entries[1]
+	offset := bytes.Index(bundle, []byte(entry.Path)) + 512
+	bundle[offset+100] ^= 1
+
+	target := storagetest.NewTestFileStorage(t, l)
+	imported, err := Import(context.Background(), bytes.NewReader(bundle), target, beacon, common.Hash(genesisValidatorsRoot), l)
+	require.ErrorIs(t, err, ErrChecksum)
+	require.Equal(t, 1, imported.Imported)
+	target.CheckExistsOrFail(t, roots[0])
+	target.CheckNotExistsOrFail(t, roots[1])
+}
+
+func TestImportVerifiesChain(t *testing.T) {
+	l := testlog.Logger(t, log.LvlInfo)
+	beacon, roots, bundle, _ := exportDefault(t)
+
+	target := storagetest.NewTestFileStorage(t, l)
+	imported, err := Import(context.Background(), bytes.NewReader(bundle), target, beacon, common.Hash{4, 5, 6}, l)
+	require.ErrorIs(t, err, ErrChainMismatch)
+	require.Zero(t, imported.Imported)
+	target.CheckNotExistsOrFail(t, roots[0])
+}
+
+func TestImportVerifiesBlobs(t *testing.T) {
+	l := testlog.Logger(t, log.LvlInfo)
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 1)
+
+	// A bundle with valid checksums, but with blobs that do not belong to their block
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Manifest{Chain: Chain{GenesisValidatorsRoot: common.Hash(genesisValidatorsRoot)}})
+	require.NoError(t, err)
+	require.NoError(t, w.Add(blobtest.StartSlot, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: root},
+		BlobSidecars: storage.BlobSidecars{Data: sidecars},
+	}))
+	require.NoError(t, w.Add(blobtest.StartSlot+1, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: blobtest.One},
+		BlobSidecars: storage.BlobSidecars{Data: blobtest.NewBlobSidecars(t, 1)},
+	}))
+	require.NoError(t, w.Close())
+
+	beacon := beacontest.NewEmptyStubBeaconClient()
+	beacon.Blobs[root.String()] = sidecars
+	beacon.Commitments[blobtest.One.String()] = blobtest.Commitments(blobtest.NewBlobSidecars(t, 1))
+
+	target := storagetest.NewTestFileStorage(t, l)
+	imported, err := Import(context.Background(), bytes.NewReader(buf.Bytes()), target, beacon, common.Hash(genesisValidatorsRoot), l)
+	require.ErrorContains(t, err, fmt.Sprintf("invalid blobs of slot %d", blobtest.StartSlot+1))
+	require.Equal(t, 1, imported.Imported)
+	target.CheckExistsOrFail(t, root)
+	target.CheckNotExistsOrFail(t, blobtest.One)
+}
+
+func TestImportVerifiesCompleteness(t *testing.T) {
+	l := testlog.Logger(t, log.LvlInfo)
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 2)
+	unknown, unknownSidecars := blobtest.NewVerifiableBlobSidecars(t, 1)
+
+	// A bundle with valid blobs, but only some of the blobs of a block
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Manifest{Chain: Chain{GenesisValidatorsRoot: common.Hash(genesisValidatorsRoot)}})
+	require.NoError(t, err)
+	require.NoError(t, w.Add(blobtest.StartSlot, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: root},
+		BlobSidecars: storage.BlobSidecars{Data: sidecars[:1]},
+	}))
+	require.NoError(t, w.Close())
+
+	beacon := beacontest.NewEmptyStubBeaconClient()
+	beacon.Blobs[root.String()] = sidecars
+
+	target := storagetest.NewTestFileStorage(t, l)
+	imported, err := Import(context.Background(), bytes.NewReader(buf.Bytes()), target, beacon, common.Hash(genesisValidatorsRoot), l)
+	require.ErrorContains(t, err, fmt.Sprintf("invalid blobs of slot %d", blobtest.StartSlot))
+	require.Zero(t, imported.Imported)
+	target.CheckNotExistsOrFail(t, root)
+
+	// Blocks the beacon node does not know cannot be checked for completeness
+	buf.Reset()
+	w, err = NewWriter(&buf, Manifest{Chain: Chain{GenesisValidatorsRoot: common.Hash(genesisValidatorsRoot)}})
+	require.NoError(t, err)
+	require.NoError(t, w.Add(blobtest.StartSlot, storage.BlobData{
+		Header:       storage.Header{BeaconBlockHash: unknown},
+		BlobSidecars: storage.BlobSidecars{Data: unknownSidecars},
+	}))
+	require.NoError(t, w.Close())
+
+	imported, err = Import(context.Background(), bytes.NewReader(buf.Bytes()), target, beacon, common.Hash(genesisValidatorsRoot), l)
+	require.ErrorContains(t, err, fmt.Sprintf("failed to fetch block of slot %d", blobtest.StartSlot))
+	require.Zero(t, imported.Imported)
+	target.CheckNotExistsOrFail(t, unknown)
+}
+
+func TestNewReaderRejectsInvalidBundles(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("not a bundle")))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+
+	// A bundle without an index
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Manifest{})
+	require.NoError(t, err)
+	require.NoError(t, w.tw.Close())
+	_, err = NewReader(bytes.NewReader(buf.Bytes()))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+}
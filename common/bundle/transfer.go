@@ -0,0 +1,141 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/base-org/blob-archiver/common/storage"
+	"github.com/base-org/blob-archiver/common/verify"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BeaconClient resolves the canonical blocks of the exported slots, and the chain they belong to.
+type BeaconClient interface {
+	client.BeaconBlockHeadersProvider
+	client.GenesisProvider
+}
+
+// ExportResult summarizes an export.
+type ExportResult struct {
+	Exported int
+	Missing  int
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Imported int
+	Skipped  int
+}
+
+// Export writes the blobs of the canonical blocks from fromSlot to toSlot (inclusive) to a bundle. Blocks are resolved
+// through the beacon node, and their blobs are read from the data store. Slots without a block are skipped, and blocks
+// whose blobs are not in the data store are recorded as missing in the index.
+func Export(ctx context.Context, beaconClient BeaconClient, store storage.DataStoreReader, fromSlot, toSlot uint64, w io.Writer, l log.Logger) (ExportResult, error) {
+	var result ExportResult
+
+	genesis, err := beaconClient.Genesis(ctx, &api.GenesisOpts{})
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch genesis: %w", err)
+	}
+
+	bw, err := NewWriter(w, Manifest{
+		Chain: Chain{
+			GenesisTime:           genesis.Data.GenesisTime.Unix(),
+			GenesisValidatorsRoot: common.Hash(genesis.Data.GenesisValidatorsRoot),
+			GenesisForkVersion:    genesis.Data.GenesisForkVersion[:],
+		},
+		FromSlot: fromSlot,
+		ToSlot:   toSlot,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		header, err := beaconClient.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+			Block: strconv.FormatUint(slot, 10),
+		})
+		var apiErr *api.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to fetch header of slot %d: %w", slot, err)
+		}
+
+		root := common.Hash(header.Data.Root)
+		data, err := store.ReadBlob(ctx, root)
+		if errors.Is(err, storage.ErrNotFound) {
+			l.Warn("blobs not found in storage, recording as missing", "slot", slot, "hash", root.String())
+			bw.AddMissing(slot)
+			result.Missing++
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read blobs of slot %d: %w", slot, err)
+		}
+
+		if err := bw.Add(slot, data); err != nil {
+			return result, err
+		}
+		result.Exported++
+		l.Debug("exported blobs", "slot", slot, "hash", root.String(), "count", len(data.BlobSidecars.Data))
+	}
+
+	return result, bw.Close()
+}
+
+// Import writes the blobs of every block of the bundle to the data store, after verifying their checksum, their KZG
+// proofs, and that they are the complete set of blobs of the block as returned by the beacon node. Bundles of another
+// chain than the one with the given genesis validators root are rejected with ErrChainMismatch. Blocks that already
+// exist in the data store are skipped.
+func Import(ctx context.Context, r io.ReadSeeker, store storage.DataStore, blocks client.SignedBeaconBlockProvider, genesisValidatorsRoot common.Hash, l log.Logger) (ImportResult, error) {
+	var result ImportResult
+
+	br, err := NewReader(r)
+	if err != nil {
+		return result, err
+	}
+	if br.Manifest.Chain.GenesisValidatorsRoot != genesisValidatorsRoot {
+		return result, fmt.Errorf("%w: bundle has genesis validators root %s, expected %s", ErrChainMismatch, br.Manifest.Chain.GenesisValidatorsRoot, genesisValidatorsRoot)
+	}
+	l.Info("importing bundle",
+		"fromSlot", br.Manifest.FromSlot,
+		"toSlot", br.Manifest.ToSlot,
+		"entries", len(br.Index.Entries),
+		"missingSlots", len(br.Index.MissingSlots),
+		"genesisValidatorsRoot", br.Manifest.Chain.GenesisValidatorsRoot.String(),
+	)
+
+	err = br.ForEach(ctx, func(entry Entry, data storage.BlobData) error {
+		exists, err := store.Exists(ctx, entry.BlockRoot)
+		if err != nil {
+			return fmt.Errorf("failed to check if blobs of slot %d exist: %w", entry.Slot, err)
+		}
+		if exists {
+			result.Skipped++
+			l.Debug("blobs already exist, skipping", "slot", entry.Slot, "hash", entry.BlockRoot.String())
+			return nil
+		}
+
+		commitments, err := verify.BlockCommitments(ctx, blocks, entry.BlockRoot)
+		if err != nil {
+			return fmt.Errorf("failed to fetch block of slot %d: %w", entry.Slot, err)
+		}
+		if err := verify.CompleteBlobSidecars(entry.BlockRoot, commitments, data.BlobSidecars.Data); err != nil {
+			return fmt.Errorf("invalid blobs of slot %d: %w", entry.Slot, err)
+		}
+		if err := store.WriteBlob(ctx, data); err != nil {
+			return fmt.Errorf("failed to write blobs of slot %d: %w", entry.Slot, err)
+		}
+		result.Imported++
+		return nil
+	})
+	return result, err
+}
@@ -3,6 +3,7 @@ package flags
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -59,25 +60,50 @@ func (c S3Config) check() error {
 }
 
 type BeaconConfig struct {
+	// BeaconURL is the URL of the beacon node, or a comma-separated list of URLs of beacon nodes (see Endpoints).
 	BeaconURL           string
 	BeaconClientTimeout time.Duration
 	EnforceJSON         bool
+	// HealthCheckInterval is how often the health and sync status of the beacon nodes is checked.
+	HealthCheckInterval time.Duration
 }
 
 type StorageConfig struct {
 	DataStorageType      DataStorage
 	S3Config             S3Config
 	FileStorageDirectory string
+	// SegmentSlots is the number of slots per segment file, or 0 to store every block as a separate object.
+	SegmentSlots uint64
+}
+
+// TLSConfig configures TLS for the HTTP server of a service. TLS is disabled if no certificate is set.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the CAs that client certificates are verified against, or is empty to not verify clients.
+	ClientCAFile      string
+	RequireClientCert bool
 }
 
 func NewBeaconConfig(cliCtx *cli.Context) BeaconConfig {
 	timeout, _ := time.ParseDuration(cliCtx.String(BeaconHttpClientTimeoutFlagName))
+	healthCheckInterval, _ := time.ParseDuration(cliCtx.String(BeaconHealthCheckIntervalFlagName))
 
 	return BeaconConfig{
 		BeaconURL:           cliCtx.String(BeaconHttpFlagName),
 		BeaconClientTimeout: timeout,
 		EnforceJSON:         cliCtx.Bool(BeaconHttpEnforceJson),
+		HealthCheckInterval: healthCheckInterval,
+	}
+}
+
+// Endpoints returns the URLs of the configured beacon nodes, in order of preference.
+func (c BeaconConfig) Endpoints() []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(c.BeaconURL, ",") {
+		endpoints = append(endpoints, strings.TrimSpace(endpoint))
 	}
+	return endpoints
 }
 
 func NewStorageConfig(cliCtx *cli.Context) StorageConfig {
@@ -85,9 +111,24 @@ func NewStorageConfig(cliCtx *cli.Context) StorageConfig {
 		DataStorageType:      toDataStorage(cliCtx.String(DataStoreFlagName)),
 		S3Config:             readS3Config(cliCtx),
 		FileStorageDirectory: cliCtx.String(FileStorageDirectoryFlagName),
+		SegmentSlots:         cliCtx.Uint64(SegmentSlotsFlagName),
 	}
 }
 
+func NewTLSConfig(cliCtx *cli.Context) TLSConfig {
+	return TLSConfig{
+		CertFile:          cliCtx.String(TLSCertFileFlagName),
+		KeyFile:           cliCtx.String(TLSKeyFileFlagName),
+		ClientCAFile:      cliCtx.String(TLSClientCAFileFlagName),
+		RequireClientCert: cliCtx.Bool(TLSRequireClientCertFlagName),
+	}
+}
+
+// Enabled returns true if the server should serve HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
 func toDataStorage(s string) DataStorage {
 	if s == string(DataStorageS3) {
 		return DataStorageS3
@@ -127,6 +168,12 @@ func (c BeaconConfig) Check() error {
 		return errors.New("beacon url must be set")
 	}
 
+	for _, endpoint := range c.Endpoints() {
+		if endpoint == "" {
+			return fmt.Errorf("invalid beacon url list %q", c.BeaconURL)
+		}
+	}
+
 	if c.BeaconClientTimeout == 0 {
 		return errors.New("beacon client timeout must be set")
 	}
@@ -149,3 +196,19 @@ func (c StorageConfig) Check() error {
 
 	return nil
 }
+
+func (c TLSConfig) Check() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("tls cert file and key file must be set together")
+	}
+
+	if c.ClientCAFile != "" && !c.Enabled() {
+		return errors.New("tls client ca file requires a tls cert file")
+	}
+
+	if c.RequireClientCert && c.ClientCAFile == "" {
+		return errors.New("requiring a tls client cert requires a tls client ca file")
+	}
+
+	return nil
+}
@@ -6,29 +6,35 @@ import (
 )
 
 const (
-	BeaconHttpFlagName              = "l1-beacon-http"
-	BeaconHttpClientTimeoutFlagName = "l1-beacon-client-timeout"
-	BeaconHttpEnforceJson           = "l1-beacon-enforce-json"
-	DataStoreFlagName               = "data-store"
-	S3CredentialTypeFlagName        = "s3-credential-type"
-	S3EndpointFlagName              = "s3-endpoint"
-	S3EndpointHttpsFlagName         = "s3-endpoint-https"
-	S3CompressFlagName              = "s3-compress"
-	S3AccessKeyFlagName             = "s3-access-key"
-	S3SecretAccessKeyFlagName       = "s3-secret-access-key"
-	S3BucketFlagName                = "s3-bucket"
-	S3PathFlagName                  = "s3-path"
-	FileStorageDirectoryFlagName    = "file-directory"
+	BeaconHttpFlagName                = "l1-beacon-http"
+	BeaconHttpClientTimeoutFlagName   = "l1-beacon-client-timeout"
+	BeaconHttpEnforceJson             = "l1-beacon-enforce-json"
+	BeaconHealthCheckIntervalFlagName = "l1-beacon-health-check-interval"
+	DataStoreFlagName                 = "data-store"
+	S3CredentialTypeFlagName          = "s3-credential-type"
+	S3EndpointFlagName                = "s3-endpoint"
+	S3EndpointHttpsFlagName           = "s3-endpoint-https"
+	S3CompressFlagName                = "s3-compress"
+	S3AccessKeyFlagName               = "s3-access-key"
+	S3SecretAccessKeyFlagName         = "s3-secret-access-key"
+	S3BucketFlagName                  = "s3-bucket"
+	S3PathFlagName                    = "s3-path"
+	FileStorageDirectoryFlagName      = "file-directory"
+	SegmentSlotsFlagName              = "segment-slots"
+	TLSCertFileFlagName               = "tls-cert-file"
+	TLSKeyFileFlagName                = "tls-key-file"
+	TLSClientCAFileFlagName           = "tls-client-ca-file"
+	TLSRequireClientCertFlagName      = "tls-require-client-cert"
 )
 
 func CLIFlags(envPrefix string) []cli.Flag {
 	return []cli.Flag{
 		// Required Flags
+		// The beacon node is not marked as required, as not every subcommand needs it. Its config is checked instead.
 		&cli.StringFlag{
-			Name:     BeaconHttpFlagName,
-			Usage:    "HTTP provider URL for L1 Beacon-node API",
-			Required: true,
-			EnvVars:  opservice.PrefixEnvVar(envPrefix, "L1_BEACON_HTTP"),
+			Name:    BeaconHttpFlagName,
+			Usage:   "HTTP provider URL for L1 Beacon-node API. Several comma-separated URLs can be given, in which case requests go to the healthiest node",
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "L1_BEACON_HTTP"),
 		},
 		&cli.StringFlag{
 			Name:     DataStoreFlagName,
@@ -91,6 +97,12 @@ func CLIFlags(envPrefix string) []cli.Flag {
 			Usage:   "The path to the directory to use for storing blobs on the file system",
 			EnvVars: opservice.PrefixEnvVar(envPrefix, "FILE_DIRECTORY"),
 		},
+		&cli.Uint64Flag{
+			Name:    SegmentSlotsFlagName,
+			Usage:   "The number of slots per segment file that finalized blocks are packed into, 0 to store every block as a separate object. Must be the same for the archiver and the API",
+			Value:   0,
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "SEGMENT_SLOTS"),
+		},
 		// Beacon Client Settings
 		&cli.StringFlag{
 			Name:    BeaconHttpClientTimeoutFlagName,
@@ -104,5 +116,38 @@ func CLIFlags(envPrefix string) []cli.Flag {
 			Value:   false,
 			EnvVars: opservice.PrefixEnvVar(envPrefix, "L1_BEACON_CLIENT_ENFORCE_JSON"),
 		},
+		&cli.StringFlag{
+			Name:    BeaconHealthCheckIntervalFlagName,
+			Usage:   "The interval at which the health and sync status of the beacon nodes is checked",
+			Value:   "10s",
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "L1_BEACON_HEALTH_CHECK_INTERVAL"),
+		},
+	}
+}
+
+// TLSFlags returns the flags configuring TLS for the HTTP server of a service.
+func TLSFlags(envPrefix string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    TLSCertFileFlagName,
+			Usage:   "Path to the PEM encoded certificate to serve HTTPS with. The certificate and key are reloaded when they change",
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "TLS_CERT_FILE"),
+		},
+		&cli.StringFlag{
+			Name:    TLSKeyFileFlagName,
+			Usage:   "Path to the PEM encoded private key of the TLS certificate",
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "TLS_KEY_FILE"),
+		},
+		&cli.StringFlag{
+			Name:    TLSClientCAFileFlagName,
+			Usage:   "Path to the PEM encoded CA certificates that client certificates are verified against. Client certificates are only verified if set",
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "TLS_CLIENT_CA_FILE"),
+		},
+		&cli.BoolFlag{
+			Name:    TLSRequireClientCertFlagName,
+			Usage:   "Whether to reject clients without a valid client certificate (mutual TLS). Requires tls-client-ca-file",
+			Value:   false,
+			EnvVars: opservice.PrefixEnvVar(envPrefix, "TLS_REQUIRE_CLIENT_CERT"),
+		},
 	}
 }
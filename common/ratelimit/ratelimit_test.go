@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowUsesBurst(t *testing.T) {
+	b := NewTokenBucket(1, 3)
+
+	require.True(t, b.Allow())
+	require.True(t, b.Allow())
+	require.True(t, b.Allow())
+	require.False(t, b.Allow())
+}
+
+func TestWaitIsRateLimited(t *testing.T) {
+	b := NewTokenBucket(20, 1)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, b.Wait(context.Background()))
+	}
+
+	// The first token is available immediately, the other four are added at 20 per second
+	require.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestWaitReturnsOnCancel(t *testing.T) {
+	b := NewTokenBucket(0.1, 1)
+	require.True(t, b.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := b.Wait(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDelay(t *testing.T) {
+	b := NewTokenBucket(10, 1)
+	require.Zero(t, b.Delay())
+
+	require.True(t, b.Allow())
+	delay := b.Delay()
+	require.Greater(t, delay, 50*time.Millisecond)
+	require.LessOrEqual(t, delay, 100*time.Millisecond)
+}
+
+func TestSetRate(t *testing.T) {
+	b := NewTokenBucket(1, 1)
+	b.SetRate(5)
+	require.Equal(t, float64(5), b.Rate())
+}
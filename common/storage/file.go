@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"path"
 	"strconv"
@@ -40,6 +41,53 @@ func NewFileStorage(dir string, l log.Logger) *FileStorage {
 		}
 	}
 
+	_, err = storage.ReadControlState(context.Background())
+	if err == ErrNotFound {
+		storage.log.Info("creating empty control_state file")
+		err = storage.WriteControlState(context.Background(), ControlState{})
+		if err != nil {
+			storage.log.Crit("failed to create empty control_state file", "err", err)
+		}
+	}
+
+	_, err = storage.ReadRearchiveJobs(context.Background())
+	if err == ErrNotFound {
+		storage.log.Info("creating empty rearchive_jobs file")
+		err = storage.WriteRearchiveJobs(context.Background(), RearchiveJobs{})
+		if err != nil {
+			storage.log.Crit("failed to create empty rearchive_jobs file", "err", err)
+		}
+	}
+
+	_, err = storage.ReadChainState(context.Background())
+	if err == ErrNotFound {
+		storage.log.Info("creating empty chain_state file")
+		err = storage.WriteChainState(context.Background(), ChainState{})
+		if err != nil {
+			storage.log.Crit("failed to create empty chain_state file", "err", err)
+		}
+	}
+
+	err = os.MkdirAll(path.Join(dir, orphanedDirectory), 0755)
+	if err != nil {
+		storage.log.Crit("failed to create orphaned directory", "err", err)
+	}
+
+	err = os.MkdirAll(path.Join(dir, auditDirectory), 0755)
+	if err != nil {
+		storage.log.Crit("failed to create audit directory", "err", err)
+	}
+
+	err = os.MkdirAll(path.Join(dir, unfinalizedDirectory), 0755)
+	if err != nil {
+		storage.log.Crit("failed to create unfinalized directory", "err", err)
+	}
+
+	err = os.MkdirAll(path.Join(dir, ingestDirectory), 0755)
+	if err != nil {
+		storage.log.Crit("failed to create ingest directory", "err", err)
+	}
+
 	return storage
 }
 
@@ -55,7 +103,15 @@ func (s *FileStorage) Exists(_ context.Context, hash common.Hash) (bool, error)
 }
 
 func (s *FileStorage) ReadBlob(_ context.Context, hash common.Hash) (BlobData, error) {
-	data, err := os.ReadFile(s.fileName(hash))
+	return s.readBlob(s.fileName(hash), hash)
+}
+
+func (s *FileStorage) ReadOrphanedBlob(_ context.Context, hash common.Hash) (BlobData, error) {
+	return s.readBlob(s.orphanedFileName(hash), hash)
+}
+
+func (s *FileStorage) readBlob(fileName string, hash common.Hash) (BlobData, error) {
+	data, err := os.ReadFile(fileName)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return BlobData{}, ErrNotFound
@@ -146,6 +202,266 @@ func (s *FileStorage) WriteLockfile(_ context.Context, data Lockfile) error {
 	return nil
 }
 
+func (s *FileStorage) ReadControlState(_ context.Context) (ControlState, error) {
+	var result ControlState
+	err := s.readObject("control_state", &result)
+	return result, err
+}
+
+func (s *FileStorage) WriteControlState(_ context.Context, data ControlState) error {
+	err := s.writeObject("control_state", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote control_state", "liveTrackingPaused", data.LiveTrackingPaused, "pausedBackfills", len(data.PausedBackfills))
+	return nil
+}
+
+func (s *FileStorage) ReadRearchiveJobs(_ context.Context) (RearchiveJobs, error) {
+	result := RearchiveJobs{}
+	err := s.readObject("rearchive_jobs", &result)
+	if err != nil {
+		return RearchiveJobs{}, err
+	}
+	return result, nil
+}
+
+func (s *FileStorage) WriteRearchiveJobs(_ context.Context, data RearchiveJobs) error {
+	err := s.writeObject("rearchive_jobs", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote rearchive_jobs", "jobs", len(data))
+	return nil
+}
+
+func (s *FileStorage) ReadIngestQueue(_ context.Context) (IngestQueue, error) {
+	entries, err := os.ReadDir(path.Join(s.directory, ingestDirectory))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	result := make(IngestQueue, len(entries))
+	for _, entry := range entries {
+		var root common.Hash
+		if err := root.UnmarshalText([]byte(entry.Name())); err != nil {
+			continue
+		}
+
+		var block IngestBlock
+		if err := s.readObject(path.Join(ingestDirectory, entry.Name()), &block); err != nil {
+			return nil, err
+		}
+		result[root] = block
+	}
+	return result, nil
+}
+
+func (s *FileStorage) WriteIngestBlock(_ context.Context, root common.Hash, block IngestBlock) error {
+	err := s.writeObject(path.Join(ingestDirectory, root.String()), block)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote ingest block", "hash", root.String(), "peer", block.Peer, "attempts", block.Attempts)
+	return nil
+}
+
+func (s *FileStorage) DeleteIngestBlock(_ context.Context, root common.Hash) error {
+	err := os.Remove(path.Join(s.directory, ingestDirectory, root.String()))
+	if err != nil && !os.IsNotExist(err) {
+		s.log.Warn("error deleting ingest block", "err", err, "hash", root.String())
+		return err
+	}
+	return nil
+}
+
+func (s *FileStorage) ReadBeaconSnapshot(_ context.Context) (BeaconSnapshot, error) {
+	result := BeaconSnapshot{}
+	err := s.readObject("beacon_snapshot", &result)
+	if err != nil {
+		return BeaconSnapshot{}, err
+	}
+	return result, nil
+}
+
+func (s *FileStorage) WriteBeaconSnapshot(_ context.Context, data BeaconSnapshot) error {
+	err := s.writeObject("beacon_snapshot", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote beacon_snapshot", "spec", len(data.Spec), "capturedAt", data.CapturedAt)
+	return nil
+}
+
+func (s *FileStorage) ReadAuditLog(_ context.Context, day string) (AuditLog, error) {
+	entries, err := os.ReadDir(path.Join(s.directory, auditDirectory, day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return AuditLog{}, ErrNotFound
+		}
+		return AuditLog{}, err
+	}
+	if len(entries) == 0 {
+		return AuditLog{}, ErrNotFound
+	}
+
+	result := make(AuditLog, 0, len(entries))
+	for _, entry := range entries {
+		var record AuditRecord
+		if err := s.readObject(path.Join(auditDirectory, day, entry.Name()), &record); err != nil {
+			return AuditLog{}, err
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+func (s *FileStorage) WriteAuditRecord(_ context.Context, day string, record AuditRecord) error {
+	err := os.MkdirAll(path.Join(s.directory, auditDirectory, day), 0755)
+	if err != nil {
+		s.log.Warn("error creating audit log directory", "err", err, "day", day)
+		return err
+	}
+
+	err = s.writeObject(auditRecordName(day), record)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote audit record", "day", day, "action", record.Action)
+	return nil
+}
+
+func (s *FileStorage) ReadChainState(_ context.Context) (ChainState, error) {
+	result := ChainState{}
+	err := s.readObject("chain_state", &result)
+	if err != nil {
+		return ChainState{}, err
+	}
+	if result.Orphans == nil {
+		result.Orphans = make(map[common.Hash]OrphanedBlock)
+	}
+	if result.Parked == nil {
+		result.Parked = make(map[common.Hash]ParkedBlock)
+	}
+	return result, nil
+}
+
+func (s *FileStorage) WriteChainState(_ context.Context, data ChainState) error {
+	err := s.writeObject("chain_state", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("wrote chain_state", "finalizedSlot", data.FinalizedSlot, "orphans", len(data.Orphans), "parked", len(data.Parked))
+	return nil
+}
+
+func (s *FileStorage) ReadUnfinalizedEpochs(_ context.Context) (map[uint64]UnfinalizedEpoch, error) {
+	entries, err := os.ReadDir(path.Join(s.directory, unfinalizedDirectory))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	result := make(map[uint64]UnfinalizedEpoch, len(entries))
+	for _, entry := range entries {
+		epoch, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var data UnfinalizedEpoch
+		if err := s.readObject(path.Join(unfinalizedDirectory, entry.Name()), &data); err != nil {
+			return nil, err
+		}
+		result[epoch] = data
+	}
+	return result, nil
+}
+
+func (s *FileStorage) WriteUnfinalizedEpoch(_ context.Context, epoch uint64, data UnfinalizedEpoch) error {
+	name := path.Join(unfinalizedDirectory, strconv.FormatUint(epoch, 10))
+	if len(data) == 0 {
+		err := os.Remove(path.Join(s.directory, name))
+		if err != nil && !os.IsNotExist(err) {
+			s.log.Warn("error deleting unfinalized epoch", "err", err, "epoch", epoch)
+			return err
+		}
+		return nil
+	}
+
+	err := s.writeObject(name, data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("wrote unfinalized epoch", "epoch", epoch, "blocks", len(data))
+	return nil
+}
+
+func (s *FileStorage) OrphanBlob(_ context.Context, hash common.Hash) error {
+	err := os.Rename(s.fileName(hash), s.orphanedFileName(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+
+		s.log.Warn("error orphaning blob", "err", err, "hash", hash.String())
+		return err
+	}
+
+	s.log.Info("orphaned blob", "hash", hash.String())
+	return nil
+}
+
+func (s *FileStorage) DeleteOrphanedBlob(_ context.Context, hash common.Hash) error {
+	err := os.Remove(s.orphanedFileName(hash))
+	if err != nil && !os.IsNotExist(err) {
+		s.log.Warn("error deleting orphaned blob", "err", err, "hash", hash.String())
+		return err
+	}
+
+	s.log.Info("deleted orphaned blob", "hash", hash.String())
+	return nil
+}
+
+// readObject decodes the JSON object with the given name from the storage directory.
+func (s *FileStorage) readObject(name string, v any) error {
+	data, err := os.ReadFile(path.Join(s.directory, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		s.log.Warn("error decoding "+name, "err", err)
+		return ErrMarshaling
+	}
+	return nil
+}
+
+// writeObject encodes the given value as JSON and writes it to the storage directory under the given name.
+func (s *FileStorage) writeObject(name string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.log.Warn("error encoding "+name, "err", err)
+		return ErrMarshaling
+	}
+	err = os.WriteFile(path.Join(s.directory, name), b, 0644)
+	if err != nil {
+		s.log.Warn("error writing "+name, "err", err)
+		return err
+	}
+	return nil
+}
+
 func (s *FileStorage) WriteBlob(_ context.Context, data BlobData) error {
 	b, err := json.Marshal(data)
 	if err != nil {
@@ -162,6 +478,79 @@ func (s *FileStorage) WriteBlob(_ context.Context, data BlobData) error {
 	return nil
 }
 
+func (s *FileStorage) DeleteBlob(_ context.Context, hash common.Hash) error {
+	err := os.Remove(s.fileName(hash))
+	if err != nil && !os.IsNotExist(err) {
+		s.log.Warn("error deleting blob", "err", err, "hash", hash.String())
+		return err
+	}
+	return nil
+}
+
+func (s *FileStorage) ReadSegmentCatalog(_ context.Context) (SegmentCatalog, error) {
+	var result SegmentCatalog
+	err := s.readObject(segmentCatalogName, &result)
+	return result, err
+}
+
+func (s *FileStorage) WriteSegmentCatalog(_ context.Context, data SegmentCatalog) error {
+	err := s.writeObject(segmentCatalogName, data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote segment_catalog", "segments", len(data.Segments))
+	return nil
+}
+
+func (s *FileStorage) WriteSegment(_ context.Context, name string, r io.Reader, _ int64) error {
+	if err := os.MkdirAll(path.Join(s.directory, segmentsDirectory), 0755); err != nil {
+		return err
+	}
+
+	// The segment is written under a temporary name first, so that readers never see a partial segment
+	fileName := path.Join(s.directory, segmentsDirectory, name)
+	f, err := os.Create(fileName + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(fileName+".tmp", fileName)
+	}
+	if err != nil {
+		s.log.Warn("error writing segment", "err", err, "segment", name)
+		_ = os.Remove(fileName + ".tmp")
+		return err
+	}
+	return nil
+}
+
+func (s *FileStorage) ReadSegmentRange(_ context.Context, name string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(path.Join(s.directory, segmentsDirectory, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, offset); err != nil {
+		s.log.Warn("error reading segment", "err", err, "segment", name)
+		return nil, ErrStorage
+	}
+	return data, nil
+}
+
 func (s *FileStorage) fileName(hash common.Hash) string {
 	return path.Join(s.directory, hash.String())
 }
+
+func (s *FileStorage) orphanedFileName(hash common.Hash) string {
+	return path.Join(s.directory, orphanedDirectory, hash.String())
+}
@@ -2,10 +2,14 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"os"
 	"testing"
+	"time"
 
+	v1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum-optimism/optimism/op-service/testlog"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
@@ -76,6 +80,271 @@ func TestRead(t *testing.T) {
 	runTestRead(t, fs)
 }
 
+func runTestControlState(t *testing.T, s DataStore) {
+	state, err := s.ReadControlState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, ControlState{}, state)
+
+	expected := ControlState{
+		LiveTrackingPaused: true,
+		PausedBackfills:    []common.Hash{{1, 2, 3}},
+	}
+	err = s.WriteControlState(context.Background(), expected)
+	require.NoError(t, err)
+
+	state, err = s.ReadControlState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expected, state)
+}
+
+func TestControlState(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestControlState(t, fs)
+}
+
+func runTestRearchiveJobs(t *testing.T, s DataStore) {
+	jobs, err := s.ReadRearchiveJobs(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+
+	expected := RearchiveJobs{
+		"job": RearchiveJob{
+			Id:          "job",
+			Status:      JobStatusRunning,
+			From:        10,
+			To:          20,
+			NextSlot:    15,
+			Processed:   5,
+			FailedSlots: []uint64{12},
+		},
+	}
+	err = s.WriteRearchiveJobs(context.Background(), expected)
+	require.NoError(t, err)
+
+	jobs, err = s.ReadRearchiveJobs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expected, jobs)
+}
+
+func TestRearchiveJobs(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestRearchiveJobs(t, fs)
+}
+
+func runTestChainState(t *testing.T, s DataStore) {
+	state, err := s.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, state.Orphans)
+	require.Empty(t, state.Parked)
+	require.Nil(t, state.FinalizedCursor)
+
+	expected := ChainState{
+		FinalizedSlot:   10,
+		Orphans:         map[common.Hash]OrphanedBlock{{2}: {Slot: 11, OrphanedAt: 1000}},
+		Parked:          map[common.Hash]ParkedBlock{{4}: {Slot: 12, ParkedAt: 1000, Attempts: 2}},
+		FinalizedCursor: &FinalizedCursor{Root: common.Hash{3}, Slot: 10},
+	}
+	err = s.WriteChainState(context.Background(), expected)
+	require.NoError(t, err)
+
+	state, err = s.ReadChainState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expected, state)
+}
+
+func TestChainState(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestChainState(t, fs)
+}
+
+func runTestUnfinalizedEpochs(t *testing.T, s DataStore) {
+	epochs, err := s.ReadUnfinalizedEpochs(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, epochs)
+
+	require.NoError(t, s.WriteUnfinalizedEpoch(context.Background(), 1, UnfinalizedEpoch{{1}: 32, {2}: 33}))
+	require.NoError(t, s.WriteUnfinalizedEpoch(context.Background(), 2, UnfinalizedEpoch{{3}: 64}))
+
+	epochs, err = s.ReadUnfinalizedEpochs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]UnfinalizedEpoch{
+		1: {{1}: 32, {2}: 33},
+		2: {{3}: 64},
+	}, epochs)
+
+	// Writing an empty epoch deletes it, which also succeeds for an epoch that does not exist
+	require.NoError(t, s.WriteUnfinalizedEpoch(context.Background(), 1, UnfinalizedEpoch{}))
+	require.NoError(t, s.WriteUnfinalizedEpoch(context.Background(), 3, nil))
+
+	epochs, err = s.ReadUnfinalizedEpochs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]UnfinalizedEpoch{2: {{3}: 64}}, epochs)
+}
+
+func TestUnfinalizedEpochs(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestUnfinalizedEpochs(t, fs)
+}
+
+func runTestIngestQueue(t *testing.T, s DataStore) {
+	queue, err := s.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, queue)
+
+	// Every block is written on its own
+	expected := IngestQueue{
+		{1}: {Peer: "http://peer:8000", QueuedAt: 1000, Attempts: 1},
+		{2}: {Peer: "http://other:8000", QueuedAt: 1001},
+	}
+	for root, block := range expected {
+		err = s.WriteIngestBlock(context.Background(), root, block)
+		require.NoError(t, err)
+	}
+
+	queue, err = s.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expected, queue)
+
+	// Deleting a block leaves the others queued, and deleting it again succeeds
+	require.NoError(t, s.DeleteIngestBlock(context.Background(), common.Hash{1}))
+	require.NoError(t, s.DeleteIngestBlock(context.Background(), common.Hash{1}))
+
+	queue, err = s.ReadIngestQueue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, IngestQueue{{2}: expected[common.Hash{2}]}, queue)
+}
+
+func TestIngestQueue(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestIngestQueue(t, fs)
+}
+
+func runTestAuditLog(t *testing.T, s DataStore) {
+	_, err := s.ReadAuditLog(context.Background(), "2024-05-01")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	expected := AuditLog{{
+		Time:     1000,
+		Actor:    "operator",
+		Auth:     "token",
+		Action:   "POST /rearchive",
+		Params:   map[string]string{"from": "1", "to": "2"},
+		Status:   202,
+		Response: json.RawMessage(`{"jobId":"1"}`),
+	}, {
+		Time:   1001,
+		Actor:  "operator",
+		Auth:   "token",
+		Action: "DELETE /jobs/{id}",
+		Params: map[string]string{"id": "1"},
+		Status: 200,
+	}}
+	// Every record is written on its own, and read back in the order it was written in
+	for _, record := range expected {
+		err = s.WriteAuditRecord(context.Background(), "2024-05-01", record)
+		require.NoError(t, err)
+	}
+
+	auditLog, err := s.ReadAuditLog(context.Background(), "2024-05-01")
+	require.NoError(t, err)
+	require.Equal(t, expected, auditLog)
+
+	_, err = s.ReadAuditLog(context.Background(), "2024-05-02")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAuditLog(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestAuditLog(t, fs)
+}
+
+func runTestBeaconSnapshot(t *testing.T, s DataStore) {
+	_, err := s.ReadBeaconSnapshot(context.Background())
+	require.ErrorIs(t, err, ErrNotFound)
+
+	expected := BeaconSnapshot{
+		Genesis: &v1.Genesis{
+			GenesisTime:           time.Unix(1606824023, 0),
+			GenesisValidatorsRoot: phase0.Root{1, 2, 3},
+			GenesisForkVersion:    phase0.Version{0, 0, 0, 1},
+		},
+		Spec:       map[string]string{"SECONDS_PER_SLOT": "12", "DENEB_FORK_VERSION": "0x04000000"},
+		CapturedAt: 1000,
+	}
+	err = s.WriteBeaconSnapshot(context.Background(), expected)
+	require.NoError(t, err)
+
+	snapshot, err := s.ReadBeaconSnapshot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expected, snapshot)
+}
+
+func TestBeaconSnapshot(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestBeaconSnapshot(t, fs)
+}
+
+func runTestOrphanBlob(t *testing.T, s DataStore) {
+	id := common.Hash{4, 5, 6}
+
+	err := s.OrphanBlob(context.Background(), id)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = s.WriteBlob(context.Background(), BlobData{
+		Header: Header{
+			BeaconBlockHash: id,
+		},
+		BlobSidecars: BlobSidecars{},
+	})
+	require.NoError(t, err)
+
+	_, err = s.ReadOrphanedBlob(context.Background(), id)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = s.OrphanBlob(context.Background(), id)
+	require.NoError(t, err)
+
+	// The blob is only found in the orphan namespace
+	exists, err := s.Exists(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = s.ReadBlob(context.Background(), id)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	data, err := s.ReadOrphanedBlob(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, id, data.Header.BeaconBlockHash)
+
+	err = s.DeleteOrphanedBlob(context.Background(), id)
+	require.NoError(t, err)
+	_, err = s.ReadOrphanedBlob(context.Background(), id)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = s.DeleteOrphanedBlob(context.Background(), id)
+	require.NoError(t, err)
+}
+
+func TestOrphanBlob(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestOrphanBlob(t, fs)
+}
+
 func TestBrokenStorage(t *testing.T) {
 	fs, cleanup := setup(t)
 
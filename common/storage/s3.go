@@ -67,6 +67,33 @@ func NewS3Storage(cfg flags.S3Config, l log.Logger) (*S3Storage, error) {
 		}
 	}
 
+	_, err = storage.ReadControlState(context.Background())
+	if err == ErrNotFound {
+		storage.log.Info("creating empty control_state object")
+		err = storage.WriteControlState(context.Background(), ControlState{})
+		if err != nil {
+			log.Crit("failed to create control_state key")
+		}
+	}
+
+	_, err = storage.ReadRearchiveJobs(context.Background())
+	if err == ErrNotFound {
+		storage.log.Info("creating empty rearchive_jobs object")
+		err = storage.WriteRearchiveJobs(context.Background(), RearchiveJobs{})
+		if err != nil {
+			log.Crit("failed to create rearchive_jobs key")
+		}
+	}
+
+	_, err = storage.ReadChainState(context.Background())
+	if err == ErrNotFound {
+		storage.log.Info("creating empty chain_state object")
+		err = storage.WriteChainState(context.Background(), ChainState{})
+		if err != nil {
+			log.Crit("failed to create chain_state key")
+		}
+	}
+
 	return storage, nil
 }
 
@@ -85,7 +112,15 @@ func (s *S3Storage) Exists(ctx context.Context, hash common.Hash) (bool, error)
 }
 
 func (s *S3Storage) ReadBlob(ctx context.Context, hash common.Hash) (BlobData, error) {
-	res, err := s.s3.GetObject(ctx, s.bucket, path.Join(s.path, hash.String()), minio.GetObjectOptions{})
+	return s.readBlob(ctx, path.Join(s.path, hash.String()), hash)
+}
+
+func (s *S3Storage) ReadOrphanedBlob(ctx context.Context, hash common.Hash) (BlobData, error) {
+	return s.readBlob(ctx, s.orphanedKey(hash), hash)
+}
+
+func (s *S3Storage) readBlob(ctx context.Context, key string, hash common.Hash) (BlobData, error) {
+	res, err := s.s3.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
 	if err != nil {
 		s.log.Info("unexpected error fetching blob", "hash", hash.String(), "err", err)
 		return BlobData{}, ErrStorage
@@ -238,6 +273,298 @@ func (s *S3Storage) WriteLockfile(ctx context.Context, data Lockfile) error {
 	return nil
 }
 
+func (s *S3Storage) ReadControlState(ctx context.Context) (ControlState, error) {
+	var data ControlState
+	err := s.readObject(ctx, "control_state", &data)
+	return data, err
+}
+
+func (s *S3Storage) WriteControlState(ctx context.Context, data ControlState) error {
+	err := s.writeObject(ctx, "control_state", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote to control_state", "liveTrackingPaused", data.LiveTrackingPaused, "pausedBackfills", len(data.PausedBackfills))
+	return nil
+}
+
+func (s *S3Storage) ReadIngestQueue(ctx context.Context) (IngestQueue, error) {
+	result := IngestQueue{}
+	prefix := path.Join(s.path, ingestDirectory) + "/"
+	for object := range s.s3.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			s.log.Info("unexpected error listing ingest queue", "err", object.Err)
+			return nil, ErrStorage
+		}
+
+		name := path.Base(object.Key)
+		var root common.Hash
+		if err := root.UnmarshalText([]byte(name)); err != nil {
+			continue
+		}
+
+		var block IngestBlock
+		if err := s.readObject(ctx, path.Join(ingestDirectory, name), &block); err != nil {
+			return nil, err
+		}
+		result[root] = block
+	}
+	return result, nil
+}
+
+func (s *S3Storage) WriteIngestBlock(ctx context.Context, root common.Hash, block IngestBlock) error {
+	err := s.writeObject(ctx, path.Join(ingestDirectory, root.String()), block)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote to ingest queue", "hash", root.String(), "peer", block.Peer, "attempts", block.Attempts)
+	return nil
+}
+
+func (s *S3Storage) DeleteIngestBlock(ctx context.Context, root common.Hash) error {
+	err := s.s3.RemoveObject(ctx, s.bucket, path.Join(s.path, ingestDirectory, root.String()), minio.RemoveObjectOptions{})
+	if err != nil {
+		s.log.Warn("error deleting ingest block", "hash", root.String(), "err", err)
+		return ErrStorage
+	}
+	return nil
+}
+
+func (s *S3Storage) ReadBeaconSnapshot(ctx context.Context) (BeaconSnapshot, error) {
+	data := BeaconSnapshot{}
+	err := s.readObject(ctx, "beacon_snapshot", &data)
+	if err != nil {
+		return BeaconSnapshot{}, err
+	}
+	return data, nil
+}
+
+func (s *S3Storage) WriteBeaconSnapshot(ctx context.Context, data BeaconSnapshot) error {
+	err := s.writeObject(ctx, "beacon_snapshot", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote to beacon_snapshot", "spec", len(data.Spec), "capturedAt", data.CapturedAt)
+	return nil
+}
+
+func (s *S3Storage) ReadAuditLog(ctx context.Context, day string) (AuditLog, error) {
+	result := AuditLog{}
+	prefix := path.Join(s.path, auditDirectory, day) + "/"
+	for object := range s.s3.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			s.log.Info("unexpected error listing audit log", "err", object.Err, "day", day)
+			return AuditLog{}, ErrStorage
+		}
+
+		var record AuditRecord
+		if err := s.readObject(ctx, path.Join(auditDirectory, day, path.Base(object.Key)), &record); err != nil {
+			return AuditLog{}, err
+		}
+		result = append(result, record)
+	}
+	if len(result) == 0 {
+		return AuditLog{}, ErrNotFound
+	}
+	return result, nil
+}
+
+func (s *S3Storage) WriteAuditRecord(ctx context.Context, day string, record AuditRecord) error {
+	err := s.writeObject(ctx, auditRecordName(day), record)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote to audit log", "day", day, "action", record.Action)
+	return nil
+}
+
+func (s *S3Storage) ReadRearchiveJobs(ctx context.Context) (RearchiveJobs, error) {
+	data := RearchiveJobs{}
+	err := s.readObject(ctx, "rearchive_jobs", &data)
+	if err != nil {
+		return RearchiveJobs{}, err
+	}
+	return data, nil
+}
+
+func (s *S3Storage) WriteRearchiveJobs(ctx context.Context, data RearchiveJobs) error {
+	err := s.writeObject(ctx, "rearchive_jobs", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote to rearchive_jobs", "jobs", len(data))
+	return nil
+}
+
+func (s *S3Storage) ReadChainState(ctx context.Context) (ChainState, error) {
+	data := ChainState{}
+	err := s.readObject(ctx, "chain_state", &data)
+	if err != nil {
+		return ChainState{}, err
+	}
+	if data.Orphans == nil {
+		data.Orphans = make(map[common.Hash]OrphanedBlock)
+	}
+	if data.Parked == nil {
+		data.Parked = make(map[common.Hash]ParkedBlock)
+	}
+	return data, nil
+}
+
+func (s *S3Storage) WriteChainState(ctx context.Context, data ChainState) error {
+	err := s.writeObject(ctx, "chain_state", data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("wrote to chain_state", "finalizedSlot", data.FinalizedSlot, "orphans", len(data.Orphans), "parked", len(data.Parked))
+	return nil
+}
+
+func (s *S3Storage) ReadUnfinalizedEpochs(ctx context.Context) (map[uint64]UnfinalizedEpoch, error) {
+	result := make(map[uint64]UnfinalizedEpoch)
+	prefix := path.Join(s.path, unfinalizedDirectory) + "/"
+	for object := range s.s3.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			s.log.Info("unexpected error listing unfinalized epochs", "err", object.Err)
+			return nil, ErrStorage
+		}
+
+		name := path.Base(object.Key)
+		epoch, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var data UnfinalizedEpoch
+		if err := s.readObject(ctx, path.Join(unfinalizedDirectory, name), &data); err != nil {
+			return nil, err
+		}
+		result[epoch] = data
+	}
+	return result, nil
+}
+
+func (s *S3Storage) WriteUnfinalizedEpoch(ctx context.Context, epoch uint64, data UnfinalizedEpoch) error {
+	name := path.Join(unfinalizedDirectory, strconv.FormatUint(epoch, 10))
+	if len(data) == 0 {
+		err := s.s3.RemoveObject(ctx, s.bucket, path.Join(s.path, name), minio.RemoveObjectOptions{})
+		if err != nil {
+			s.log.Warn("error deleting unfinalized epoch", "err", err, "epoch", epoch)
+			return ErrStorage
+		}
+		return nil
+	}
+
+	err := s.writeObject(ctx, name, data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("wrote to unfinalized epoch", "epoch", epoch, "blocks", len(data))
+	return nil
+}
+
+func (s *S3Storage) OrphanBlob(ctx context.Context, hash common.Hash) error {
+	// The copy keeps the metadata of the object, so that compressed blobs can still be read
+	_, err := s.s3.CopyObject(ctx, minio.CopyDestOptions{
+		Bucket: s.bucket,
+		Object: s.orphanedKey(hash),
+	}, minio.CopySrcOptions{
+		Bucket: s.bucket,
+		Object: path.Join(s.path, hash.String()),
+	})
+	if err != nil {
+		errResponse := minio.ToErrorResponse(err)
+		if errResponse.Code == "NoSuchKey" {
+			return ErrNotFound
+		}
+
+		s.log.Warn("error orphaning blob", "hash", hash.String(), "err", err)
+		return ErrStorage
+	}
+
+	err = s.s3.RemoveObject(ctx, s.bucket, path.Join(s.path, hash.String()), minio.RemoveObjectOptions{})
+	if err != nil {
+		s.log.Warn("error removing orphaned blob", "hash", hash.String(), "err", err)
+		return ErrStorage
+	}
+
+	s.log.Info("orphaned blob", "hash", hash.String())
+	return nil
+}
+
+func (s *S3Storage) DeleteOrphanedBlob(ctx context.Context, hash common.Hash) error {
+	err := s.s3.RemoveObject(ctx, s.bucket, s.orphanedKey(hash), minio.RemoveObjectOptions{})
+	if err != nil {
+		s.log.Warn("error deleting orphaned blob", "hash", hash.String(), "err", err)
+		return ErrStorage
+	}
+
+	s.log.Info("deleted orphaned blob", "hash", hash.String())
+	return nil
+}
+
+func (s *S3Storage) orphanedKey(hash common.Hash) string {
+	return path.Join(s.path, orphanedDirectory, hash.String())
+}
+
+// readObject decodes the JSON object with the given key, relative to the storage path.
+func (s *S3Storage) readObject(ctx context.Context, key string, v any) error {
+	res, err := s.s3.GetObject(ctx, s.bucket, path.Join(s.path, key), minio.GetObjectOptions{})
+	if err != nil {
+		s.log.Info("unexpected error fetching "+key, "err", err)
+		return ErrStorage
+	}
+	defer res.Close()
+	_, err = res.Stat()
+	if err != nil {
+		errResponse := minio.ToErrorResponse(err)
+		if errResponse.Code == "NoSuchKey" {
+			s.log.Info("unable to find " + key + " key")
+			return ErrNotFound
+		} else {
+			s.log.Info("unexpected error fetching "+key, "err", err)
+			return ErrStorage
+		}
+	}
+
+	err = json.NewDecoder(res).Decode(v)
+	if err != nil {
+		s.log.Warn("error decoding "+key, "err", err)
+		return ErrMarshaling
+	}
+
+	return nil
+}
+
+// writeObject encodes the given value as JSON and writes it under the given key, relative to the storage path.
+func (s *S3Storage) writeObject(ctx context.Context, key string, v any) error {
+	d, err := json.Marshal(v)
+	if err != nil {
+		s.log.Warn("error encoding "+key, "err", err)
+		return ErrMarshaling
+	}
+
+	options := minio.PutObjectOptions{
+		ContentType: "application/json",
+	}
+	reader := bytes.NewReader(d)
+
+	_, err = s.s3.PutObject(ctx, s.bucket, path.Join(s.path, key), reader, int64(len(d)), options)
+	if err != nil {
+		s.log.Warn("error writing to "+key, "err", err)
+		return ErrStorage
+	}
+
+	return nil
+}
+
 func (s *S3Storage) WriteBlob(ctx context.Context, data BlobData) error {
 	b, err := json.Marshal(data)
 	if err != nil {
@@ -271,6 +598,73 @@ func (s *S3Storage) WriteBlob(ctx context.Context, data BlobData) error {
 	return nil
 }
 
+func (s *S3Storage) DeleteBlob(ctx context.Context, hash common.Hash) error {
+	err := s.s3.RemoveObject(ctx, s.bucket, path.Join(s.path, hash.String()), minio.RemoveObjectOptions{})
+	if err != nil {
+		s.log.Warn("error deleting blob", "hash", hash.String(), "err", err)
+		return ErrStorage
+	}
+	return nil
+}
+
+func (s *S3Storage) ReadSegmentCatalog(ctx context.Context) (SegmentCatalog, error) {
+	var data SegmentCatalog
+	err := s.readObject(ctx, segmentCatalogName, &data)
+	return data, err
+}
+
+func (s *S3Storage) WriteSegmentCatalog(ctx context.Context, data SegmentCatalog) error {
+	err := s.writeObject(ctx, segmentCatalogName, data)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("wrote to segment_catalog", "segments", len(data.Segments))
+	return nil
+}
+
+func (s *S3Storage) WriteSegment(ctx context.Context, name string, r io.Reader, size int64) error {
+	options := minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	}
+
+	_, err := s.s3.PutObject(ctx, s.bucket, path.Join(s.path, segmentsDirectory, name), r, size, options)
+	if err != nil {
+		s.log.Warn("error writing segment", "segment", name, "err", err)
+		return ErrStorage
+	}
+	return nil
+}
+
+func (s *S3Storage) ReadSegmentRange(ctx context.Context, name string, offset, length int64) ([]byte, error) {
+	options := minio.GetObjectOptions{}
+	if err := options.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+
+	res, err := s.s3.GetObject(ctx, s.bucket, path.Join(s.path, segmentsDirectory, name), options)
+	if err != nil {
+		s.log.Info("unexpected error fetching segment", "segment", name, "err", err)
+		return nil, ErrStorage
+	}
+	defer res.Close()
+
+	data, err := io.ReadAll(res)
+	if err != nil {
+		errResponse := minio.ToErrorResponse(err)
+		if errResponse.Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		s.log.Info("unexpected error fetching segment", "segment", name, "err", err)
+		return nil, ErrStorage
+	}
+	if int64(len(data)) != length {
+		s.log.Warn("short segment read", "segment", name, "offset", offset, "length", length, "read", len(data))
+		return nil, ErrStorage
+	}
+	return data, nil
+}
+
 func compress(in []byte) ([]byte, error) {
 	var buf bytes.Buffer
 	gz := gzip.NewWriter(&buf)
@@ -23,22 +23,25 @@ func setupS3(t *testing.T) *S3Storage {
 
 	l := testlog.Logger(t, log.LvlInfo)
 
-	s3, err := NewS3Storage(flags.S3Config{
+	cfg := flags.S3Config{
 		Endpoint:         "localhost:9000",
 		AccessKey:        "admin",
 		SecretAccessKey:  "password",
 		UseHttps:         false,
 		Bucket:           "blobs",
 		S3CredentialType: flags.S3CredentialStatic,
-	}, l)
+	}
+	s3, err := NewS3Storage(cfg, l)
 
 	require.NoError(t, err)
 
-	for object := range s3.s3.ListObjects(context.Background(), "blobs", minio.ListObjectsOptions{}) {
+	for object := range s3.s3.ListObjects(context.Background(), "blobs", minio.ListObjectsOptions{Recursive: true}) {
 		err = s3.s3.RemoveObject(context.Background(), "blobs", object.Key, minio.RemoveObjectOptions{})
 		require.NoError(t, err)
 	}
 
+	// Recreate the empty objects that were removed
+	s3, err = NewS3Storage(cfg, l)
 	require.NoError(t, err)
 	return s3
 }
@@ -54,3 +57,57 @@ func TestS3Read(t *testing.T) {
 
 	runTestRead(t, s3)
 }
+
+func TestS3ControlState(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestControlState(t, s3)
+}
+
+func TestS3RearchiveJobs(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestRearchiveJobs(t, s3)
+}
+
+func TestS3ChainState(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestChainState(t, s3)
+}
+
+func TestS3UnfinalizedEpochs(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestUnfinalizedEpochs(t, s3)
+}
+
+func TestS3IngestQueue(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestIngestQueue(t, s3)
+}
+
+func TestS3AuditLog(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestAuditLog(t, s3)
+}
+
+func TestS3BeaconSnapshot(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestBeaconSnapshot(t, s3)
+}
+
+func TestS3OrphanBlob(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestOrphanBlob(t, s3)
+}
+
+func TestS3Segments(t *testing.T) {
+	s3 := setupS3(t)
+
+	runTestSegments(t, s3)
+}
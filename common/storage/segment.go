@@ -0,0 +1,403 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// segmentsDirectory is the namespace of the segment files, relative to the storage directory or path.
+	segmentsDirectory  = "segments"
+	segmentCatalogName = "segment_catalog"
+
+	// segmentMagic ends every segment file, after the footer.
+	segmentMagic = "BLOBSEG1"
+	// segmentIndexEntrySize is the size of an index entry: block root, slot, offset and length of the entry.
+	segmentIndexEntrySize = 32 + 8 + 8 + 8
+	// segmentFooterSize is the size of the footer: number of blocks, first slot, last slot and magic.
+	segmentFooterSize = 8 + 8 + 8 + 8
+
+	// segmentRefreshInterval is the minimum time between two reloads of the catalog when a block is not found, so that
+	// segments sealed by another process are picked up.
+	segmentRefreshInterval = 30 * time.Second
+)
+
+// ErrSegmentCorrupted is returned when a segment file does not match its catalog entry.
+var ErrSegmentCorrupted = errors.New("segment corrupted")
+
+// SegmentBackend is a data store that can also hold segment files.
+type SegmentBackend interface {
+	DataStore
+	// DeleteBlob deletes the per-block blob data of the given beacon block hash. Deleting blob data that does not exist
+	// succeeds.
+	DeleteBlob(ctx context.Context, hash common.Hash) error
+	// ReadSegmentCatalog returns ErrNotFound if no segment was sealed yet.
+	ReadSegmentCatalog(ctx context.Context) (SegmentCatalog, error)
+	WriteSegmentCatalog(ctx context.Context, data SegmentCatalog) error
+	WriteSegment(ctx context.Context, name string, r io.Reader, size int64) error
+	// ReadSegmentRange reads length bytes at the given offset of a segment file.
+	ReadSegmentRange(ctx context.Context, name string, offset, length int64) ([]byte, error)
+}
+
+// SegmentBlock is a block to seal into a segment.
+type SegmentBlock struct {
+	Slot uint64
+	Root common.Hash
+}
+
+// segmentLocation locates a block in a segment. Only a prefix of the block root is kept in memory, and the full root
+// stored with the entry is checked when it is read.
+type segmentLocation struct {
+	prefix  uint64
+	segment uint64
+	offset  int64
+	length  int64
+}
+
+// SegmentedStorage packs finalized blocks into large immutable segment files, one per SegmentSlots slots, so that cold
+// data is not stored as one object per block. Recent blocks are stored per block as usual, and reads fall back to the
+// segments for blocks that are not stored per block.
+//
+// A segment file holds, for every block, its root followed by its SSZ encoded blob sidecars. It ends with an index of
+// the blocks sorted by root, and a footer. A single block is read with a range read of its entry. The sealed segments
+// are listed in a catalog, and the indexes of all sealed segments are kept in memory.
+type SegmentedStorage struct {
+	SegmentBackend
+	log          log.Logger
+	segmentSlots uint64
+
+	// refreshMu serializes reloads of the catalog
+	refreshMu   sync.Mutex
+	mu          sync.RWMutex
+	catalog     SegmentCatalog
+	locations   []segmentLocation
+	refreshedAt time.Time
+}
+
+// NewSegmentedStorage loads the catalog and the segment indexes of the given backend.
+func NewSegmentedStorage(ctx context.Context, backend SegmentBackend, segmentSlots uint64, l log.Logger) (*SegmentedStorage, error) {
+	s := &SegmentedStorage{
+		SegmentBackend: backend,
+		log:            l,
+		segmentSlots:   segmentSlots,
+		catalog:        SegmentCatalog{SegmentSlots: segmentSlots},
+	}
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if s.catalog.SegmentSlots != segmentSlots {
+		return nil, fmt.Errorf("segments were sealed with %d slots per segment, not %d", s.catalog.SegmentSlots, segmentSlots)
+	}
+	return s, nil
+}
+
+// SegmentSlots returns the number of slots per segment.
+func (s *SegmentedStorage) SegmentSlots() uint64 {
+	return s.segmentSlots
+}
+
+// Catalog returns the sealed segments.
+func (s *SegmentedStorage) Catalog() SegmentCatalog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	catalog := s.catalog
+	catalog.Segments = append([]SegmentInfo(nil), s.catalog.Segments...)
+	return catalog
+}
+
+// refresh reloads the catalog, and loads the indexes of the segments that were sealed since the last refresh.
+func (s *SegmentedStorage) refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	catalog, err := s.SegmentBackend.ReadSegmentCatalog(ctx)
+	if errors.Is(err, ErrNotFound) {
+		catalog = SegmentCatalog{SegmentSlots: s.segmentSlots}
+	} else if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	loaded := len(s.catalog.Segments)
+	s.mu.RUnlock()
+
+	var locations []segmentLocation
+	for _, info := range catalog.Segments[min(loaded, len(catalog.Segments)):] {
+		index, err := s.readIndex(ctx, info)
+		if err != nil {
+			return err
+		}
+		for _, entry := range index {
+			locations = append(locations, segmentLocation{
+				prefix:  rootPrefix(entry.Root),
+				segment: info.Number,
+				offset:  entry.offset,
+				length:  entry.length,
+			})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalog = catalog
+	s.refreshedAt = time.Now()
+	if len(locations) > 0 {
+		s.locations = append(s.locations, locations...)
+		sort.Slice(s.locations, func(i, j int) bool { return s.locations[i].prefix < s.locations[j].prefix })
+	}
+	return nil
+}
+
+// segmentIndexEntry is an entry of the index embedded in a segment file.
+type segmentIndexEntry struct {
+	SegmentBlock
+	offset int64
+	length int64
+}
+
+// readIndex reads and validates the embedded index of a segment.
+func (s *SegmentedStorage) readIndex(ctx context.Context, info SegmentInfo) ([]segmentIndexEntry, error) {
+	data, err := s.SegmentBackend.ReadSegmentRange(ctx, segmentName(info.Number), info.IndexOffset, info.Size-info.IndexOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index of segment %d: %w", info.Number, err)
+	}
+
+	if len(data) != info.Blocks*segmentIndexEntrySize+segmentFooterSize {
+		return nil, fmt.Errorf("%w: segment %d has an index of %d bytes", ErrSegmentCorrupted, info.Number, len(data))
+	}
+	footer := data[len(data)-segmentFooterSize:]
+	if string(footer[24:]) != segmentMagic || binary.BigEndian.Uint64(footer) != uint64(info.Blocks) {
+		return nil, fmt.Errorf("%w: segment %d has an invalid footer", ErrSegmentCorrupted, info.Number)
+	}
+
+	index := make([]segmentIndexEntry, info.Blocks)
+	for i := range index {
+		entry := data[i*segmentIndexEntrySize:]
+		index[i] = segmentIndexEntry{
+			SegmentBlock: SegmentBlock{
+				Root: common.BytesToHash(entry[:32]),
+				Slot: binary.BigEndian.Uint64(entry[32:]),
+			},
+			offset: int64(binary.BigEndian.Uint64(entry[40:])),
+			length: int64(binary.BigEndian.Uint64(entry[48:])),
+		}
+	}
+	return index, nil
+}
+
+// locate returns the candidate locations of a block in the sealed segments.
+func (s *SegmentedStorage) locate(hash common.Hash) []segmentLocation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix := rootPrefix(hash)
+	i := sort.Search(len(s.locations), func(i int) bool { return s.locations[i].prefix >= prefix })
+	var candidates []segmentLocation
+	for ; i < len(s.locations) && s.locations[i].prefix == prefix; i++ {
+		candidates = append(candidates, s.locations[i])
+	}
+	return candidates
+}
+
+// readSealedBlob reads the blob data of a block from the sealed segments. If the block is not found, the catalog is
+// reloaded at most every segmentRefreshInterval, in case another process sealed it.
+func (s *SegmentedStorage) readSealedBlob(ctx context.Context, hash common.Hash) (BlobData, error) {
+	data, err := s.readLocatedBlob(ctx, hash)
+	if !errors.Is(err, ErrNotFound) {
+		return data, err
+	}
+
+	s.mu.RLock()
+	stale := time.Since(s.refreshedAt) > segmentRefreshInterval
+	s.mu.RUnlock()
+	if !stale {
+		return BlobData{}, ErrNotFound
+	}
+	if err := s.refresh(ctx); err != nil {
+		s.log.Warn("failed to refresh segment catalog", "err", err)
+		return BlobData{}, ErrStorage
+	}
+	return s.readLocatedBlob(ctx, hash)
+}
+
+func (s *SegmentedStorage) readLocatedBlob(ctx context.Context, hash common.Hash) (BlobData, error) {
+	for _, location := range s.locate(hash) {
+		entry, err := s.SegmentBackend.ReadSegmentRange(ctx, segmentName(location.segment), location.offset, location.length)
+		if err != nil {
+			return BlobData{}, err
+		}
+		if len(entry) < 32 || common.BytesToHash(entry[:32]) != hash {
+			continue
+		}
+
+		sidecars := api.BlobSidecars{}
+		if err := sidecars.UnmarshalSSZ(entry[32:]); err != nil {
+			s.log.Warn("error decoding sealed blob", "hash", hash.String(), "segment", location.segment, "err", err)
+			return BlobData{}, ErrMarshaling
+		}
+		return BlobData{
+			Header:       Header{BeaconBlockHash: hash},
+			BlobSidecars: BlobSidecars{Data: sidecars.Sidecars},
+		}, nil
+	}
+	return BlobData{}, ErrNotFound
+}
+
+// Exists returns true if the block is stored per block or in a sealed segment.
+func (s *SegmentedStorage) Exists(ctx context.Context, hash common.Hash) (bool, error) {
+	exists, err := s.SegmentBackend.Exists(ctx, hash)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	_, err = s.readSealedBlob(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReadBlob reads the blob data of a block stored per block, or from a sealed segment otherwise. A block that was
+// rewritten after it was sealed, e.g. by a rearchive job, is therefore read from its newer per-block object.
+func (s *SegmentedStorage) ReadBlob(ctx context.Context, hash common.Hash) (BlobData, error) {
+	data, err := s.SegmentBackend.ReadBlob(ctx, hash)
+	if !errors.Is(err, ErrNotFound) {
+		return data, err
+	}
+	return s.readSealedBlob(ctx, hash)
+}
+
+// Seal packs the given blocks, which must be stored per block, into the segment with the given number, and adds it to
+// the catalog. The per-block objects are kept until PruneSealed, so that readers that have not reloaded the catalog yet
+// can still find them. It returns ErrNotFound if one of the blocks is not stored.
+func (s *SegmentedStorage) Seal(ctx context.Context, number uint64, blocks []SegmentBlock) error {
+	f, err := os.CreateTemp("", "segment-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	index := make([]segmentIndexEntry, 0, len(blocks))
+	var offset int64
+	for _, block := range blocks {
+		data, err := s.SegmentBackend.ReadBlob(ctx, block.Root)
+		if err != nil {
+			return fmt.Errorf("failed to read blob data of slot %d: %w", block.Slot, err)
+		}
+		encoded, err := data.BlobSidecars.MarshalSSZ()
+		if err != nil {
+			return ErrMarshaling
+		}
+
+		entry := append(block.Root.Bytes(), encoded...)
+		if _, err := f.Write(entry); err != nil {
+			return err
+		}
+		index = append(index, segmentIndexEntry{SegmentBlock: block, offset: offset, length: int64(len(entry))})
+		offset += int64(len(entry))
+	}
+
+	// The index is sorted by root, and followed by the footer
+	sort.Slice(index, func(i, j int) bool { return bytes.Compare(index[i].Root[:], index[j].Root[:]) < 0 })
+	var tail bytes.Buffer
+	for _, entry := range index {
+		tail.Write(entry.Root[:])
+		tail.Write(binary.BigEndian.AppendUint64(nil, entry.Slot))
+		tail.Write(binary.BigEndian.AppendUint64(nil, uint64(entry.offset)))
+		tail.Write(binary.BigEndian.AppendUint64(nil, uint64(entry.length)))
+	}
+	firstSlot, lastSlot := number*s.segmentSlots, (number+1)*s.segmentSlots-1
+	tail.Write(binary.BigEndian.AppendUint64(nil, uint64(len(index))))
+	tail.Write(binary.BigEndian.AppendUint64(nil, firstSlot))
+	tail.Write(binary.BigEndian.AppendUint64(nil, lastSlot))
+	tail.WriteString(segmentMagic)
+	if _, err := f.Write(tail.Bytes()); err != nil {
+		return err
+	}
+
+	size := offset + int64(tail.Len())
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := s.SegmentBackend.WriteSegment(ctx, segmentName(number), f, size); err != nil {
+		return err
+	}
+
+	catalog := s.Catalog()
+	catalog.SegmentSlots = s.segmentSlots
+	catalog.Segments = append(catalog.Segments, SegmentInfo{
+		Number:      number,
+		FirstSlot:   firstSlot,
+		LastSlot:    lastSlot,
+		Blocks:      len(index),
+		Size:        size,
+		IndexOffset: offset,
+		SealedAt:    time.Now().Unix(),
+	})
+	if err := s.SegmentBackend.WriteSegmentCatalog(ctx, catalog); err != nil {
+		return err
+	}
+
+	s.log.Info("sealed segment", "segment", number, "firstSlot", firstSlot, "lastSlot", lastSlot, "blocks", len(index), "size", size)
+	return s.refresh(ctx)
+}
+
+// PruneSealed deletes the per-block objects of the blocks of every segment sealed more than the given delay ago.
+func (s *SegmentedStorage) PruneSealed(ctx context.Context, delay time.Duration) error {
+	catalog := s.Catalog()
+	cutoff := time.Now().Add(-delay).Unix()
+
+	pruned := false
+	for i, info := range catalog.Segments {
+		if info.Pruned || info.SealedAt > cutoff {
+			continue
+		}
+
+		index, err := s.readIndex(ctx, info)
+		if err != nil {
+			return err
+		}
+		for _, entry := range index {
+			if err := s.SegmentBackend.DeleteBlob(ctx, entry.Root); err != nil {
+				return err
+			}
+		}
+		catalog.Segments[i].Pruned = true
+		pruned = true
+		s.log.Info("pruned blocks of sealed segment", "segment", info.Number, "blocks", len(index))
+	}
+
+	if !pruned {
+		return nil
+	}
+	if err := s.SegmentBackend.WriteSegmentCatalog(ctx, catalog); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalog = catalog
+	return nil
+}
+
+func segmentName(number uint64) string {
+	return fmt.Sprintf("%010d.seg", number)
+}
+
+func rootPrefix(hash common.Hash) uint64 {
+	return binary.BigEndian.Uint64(hash[:8])
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func runTestSegments(t *testing.T, backend SegmentBackend) {
+	ctx := context.Background()
+	l := testlog.Logger(t, log.LvlInfo)
+
+	s, err := NewSegmentedStorage(ctx, backend, 4, l)
+	require.NoError(t, err)
+	require.Empty(t, s.Catalog().Segments)
+
+	blocks := []SegmentBlock{
+		{Slot: 4, Root: common.Hash{4}},
+		{Slot: 5, Root: common.Hash{5}},
+		{Slot: 7, Root: common.Hash{7}},
+	}
+	stored := make(map[common.Hash]BlobData)
+	for i, block := range blocks {
+		data := BlobData{
+			Header:       Header{BeaconBlockHash: block.Root},
+			BlobSidecars: BlobSidecars{Data: blobtest.NewBlobSidecars(t, uint(i))},
+		}
+		require.NoError(t, s.WriteBlob(ctx, data))
+		stored[block.Root] = data
+	}
+
+	// Every block of a segment must be stored
+	err = s.Seal(ctx, 1, append(blocks, SegmentBlock{Slot: 6, Root: common.Hash{6}}))
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Empty(t, s.Catalog().Segments)
+
+	require.NoError(t, s.Seal(ctx, 1, blocks))
+	catalog := s.Catalog()
+	require.Len(t, catalog.Segments, 1)
+	require.Equal(t, uint64(4), catalog.Segments[0].FirstSlot)
+	require.Equal(t, uint64(7), catalog.Segments[0].LastSlot)
+	require.Equal(t, 3, catalog.Segments[0].Blocks)
+
+	// The per-block objects are kept until the segment is pruned
+	require.NoError(t, s.PruneSealed(ctx, segmentRefreshInterval))
+	exists, err := backend.Exists(ctx, common.Hash{4})
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	require.NoError(t, s.PruneSealed(ctx, 0))
+	require.True(t, s.Catalog().Segments[0].Pruned)
+	for _, block := range blocks {
+		exists, err := backend.Exists(ctx, block.Root)
+		require.NoError(t, err)
+		require.False(t, exists)
+	}
+
+	// Sealed blocks are read from the segment, also by another instance
+	other, err := NewSegmentedStorage(ctx, backend, 4, l)
+	require.NoError(t, err)
+	for _, reader := range []*SegmentedStorage{s, other} {
+		for root, expected := range stored {
+			exists, err := reader.Exists(ctx, root)
+			require.NoError(t, err)
+			require.True(t, exists)
+
+			data, err := reader.ReadBlob(ctx, root)
+			require.NoError(t, err)
+			require.Equal(t, expected.Header, data.Header)
+			require.Len(t, data.BlobSidecars.Data, len(expected.BlobSidecars.Data))
+			for i := range expected.BlobSidecars.Data {
+				require.Equal(t, expected.BlobSidecars.Data[i], data.BlobSidecars.Data[i])
+			}
+		}
+
+		exists, err := reader.Exists(ctx, common.Hash{6})
+		require.NoError(t, err)
+		require.False(t, exists)
+		_, err = reader.ReadBlob(ctx, common.Hash{6})
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+
+	// A block that is rewritten after it was sealed is read from its newer per-block object
+	rewritten := BlobData{
+		Header:       Header{BeaconBlockHash: common.Hash{4}},
+		BlobSidecars: BlobSidecars{Data: blobtest.NewBlobSidecars(t, 1)},
+	}
+	require.NoError(t, s.WriteBlob(ctx, rewritten))
+	data, err := s.ReadBlob(ctx, common.Hash{4})
+	require.NoError(t, err)
+	require.Equal(t, rewritten, data)
+
+	// The number of slots per segment cannot change
+	_, err = NewSegmentedStorage(ctx, backend, 8, l)
+	require.Error(t, err)
+}
+
+func TestSegments(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+
+	runTestSegments(t, fs)
+}
+
+func TestSegmentCorrupted(t *testing.T) {
+	fs, cleanup := setup(t)
+	defer cleanup()
+	ctx := context.Background()
+	l := testlog.Logger(t, log.LvlInfo)
+
+	s, err := NewSegmentedStorage(ctx, fs, 4, l)
+	require.NoError(t, err)
+	require.NoError(t, fs.WriteBlob(ctx, BlobData{Header: Header{BeaconBlockHash: common.Hash{1}}}))
+	require.NoError(t, s.Seal(ctx, 0, []SegmentBlock{{Slot: 1, Root: common.Hash{1}}}))
+
+	fileName := path.Join(fs.directory, segmentsDirectory, segmentName(0))
+	segment, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	segment[len(segment)-1] ^= 1
+	require.NoError(t, os.WriteFile(fileName, segment, 0644))
+
+	_, err = NewSegmentedStorage(ctx, fs, 4, l)
+	require.ErrorIs(t, err, ErrSegmentCorrupted)
+}
@@ -2,8 +2,14 @@ package storage
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"path"
 	"sync"
+	"time"
 
 	v1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
@@ -14,6 +20,18 @@ import (
 
 const (
 	blobSidecarSize = 131928
+	// orphanedDirectory is the namespace of the blob data of orphaned blocks, relative to the storage directory or path.
+	orphanedDirectory = "orphaned"
+	// auditDirectory is the namespace of the audit logs, relative to the storage directory or path. Every record is
+	// stored as an object of its own under the day it was recorded on, see auditRecordName.
+	auditDirectory = "audit"
+	// unfinalizedDirectory is the namespace of the blocks that are not finalized yet, relative to the storage directory or
+	// path. They are stored as an object per epoch, so that archiving a block only rewrites the object of its epoch.
+	unfinalizedDirectory = "unfinalized"
+	// ingestDirectory is the namespace of the ingest queue, relative to the storage directory or path. Every queued block
+	// is stored as an object of its own, named by its root, so that the API and the archiver never overwrite each
+	// other's changes.
+	ingestDirectory = "ingest"
 )
 
 var (
@@ -69,11 +87,254 @@ var BackfillMu sync.Mutex
 type BackfillProcess struct {
 	Start   v1.BeaconBlockHeader `json:"start_block"`
 	Current v1.BeaconBlockHeader `json:"current_block"`
+	// Chunks is only set for processes that are filled by the parallel backfill.
+	Chunks []BackfillChunk `json:"chunks,omitempty"`
 }
 
+// BackfillChunk is a slot range of a backfill process that is filled independently of the other chunks. Slots are
+// processed from StartSlot down to EndSlot, and CurrentSlot is the next slot to process.
+type BackfillChunk struct {
+	StartSlot   uint64 `json:"start_slot"`
+	EndSlot     uint64 `json:"end_slot"`
+	CurrentSlot uint64 `json:"current_slot"`
+	Done        bool   `json:"done"`
+}
+
+// Lockfile is held by the archiver that is currently allowed to write to storage. The holder also publishes the
+// latest head it has archived, so that a standby archiver knows where to catch up from when it takes over.
 type Lockfile struct {
-	ArchiverId string `json:"archiver_id"`
-	Timestamp  int64  `json:"timestamp"`
+	ArchiverId string      `json:"archiver_id"`
+	Timestamp  int64       `json:"timestamp"`
+	HeadRoot   common.Hash `json:"head_root"`
+	HeadSlot   uint64      `json:"head_slot"`
+}
+
+// ControlState holds the runtime controls that were set through the archiver admin API, so that they survive restarts.
+type ControlState struct {
+	LiveTrackingPaused bool `json:"live_tracking_paused"`
+	// PausedBackfills holds the start block hashes of the backfill processes that are paused.
+	PausedBackfills []common.Hash `json:"paused_backfills"`
+}
+
+// JobStatus is the state of a RearchiveJob.
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	// JobStatusPartial and JobStatusFailed are jobs that finished, but could not rearchive some or all of their slots.
+	JobStatusPartial  JobStatus = "partial"
+	JobStatusFailed   JobStatus = "failed"
+	JobStatusCanceled JobStatus = "canceled"
+)
+
+// RearchiveJob is a request to rearchive the blobs of a range of slots, which the archiver processes in the
+// background. Slots that could not be rearchived are recorded in FailedSlots and do not stop the job.
+type RearchiveJob struct {
+	Id     string    `json:"id"`
+	Status JobStatus `json:"status"`
+	From   uint64    `json:"from"`
+	To     uint64    `json:"to"`
+	// NextSlot is the next slot to rearchive, so that an interrupted job resumes where it left off.
+	NextSlot    uint64   `json:"next_slot"`
+	Processed   uint64   `json:"processed"`
+	FailedSlots []uint64 `json:"failed_slots"`
+	// Items are only set for jobs that rearchive explicit blocks instead of a range of slots. From and To are then the
+	// slots that are searched for the blocks of the versioned hashes among the items.
+	Items    []RearchiveItem `json:"items,omitempty"`
+	NextItem int             `json:"next_item,omitempty"`
+	// DryRun jobs only compare the stored blobs with the beacon node, without overwriting them.
+	DryRun    bool            `json:"dry_run"`
+	Report    RearchiveReport `json:"report"`
+	CreatedAt int64           `json:"created_at"`
+	UpdatedAt int64           `json:"updated_at"`
+}
+
+// RearchiveReport compares the blobs that were stored before a rearchive job with the blobs served by the beacon node.
+// Slots without a block are not part of the report.
+type RearchiveReport struct {
+	IdenticalSlots []uint64         `json:"identical_slots"`
+	DifferentSlots []SlotDifference `json:"different_slots"`
+	// MissingSlots have a block on the beacon node, but no blobs in storage.
+	MissingSlots []uint64 `json:"missing_slots"`
+}
+
+// SlotDifference lists how the stored blobs for a block differ from the blobs served by the beacon node.
+type SlotDifference struct {
+	Slot        uint64      `json:"slot"`
+	BlockRoot   common.Hash `json:"block_root"`
+	Differences []string    `json:"differences"`
+}
+
+// ItemStatus is the result of rearchiving a RearchiveItem.
+type ItemStatus string
+
+const (
+	ItemStatusPending    ItemStatus = "pending"
+	ItemStatusRearchived ItemStatus = "rearchived"
+	// ItemStatusCompared is the result of a dry run.
+	ItemStatusCompared ItemStatus = "compared"
+	ItemStatusNotFound ItemStatus = "not_found"
+	ItemStatusFailed   ItemStatus = "failed"
+)
+
+// RearchiveItem is a block to rearchive, identified by exactly one of its root, its slot or the versioned hash of one
+// of its blobs, together with the result of rearchiving it.
+type RearchiveItem struct {
+	BlockRoot     *common.Hash `json:"block_root,omitempty"`
+	Slot          *uint64      `json:"slot,omitempty"`
+	VersionedHash *common.Hash `json:"versioned_hash,omitempty"`
+
+	Status ItemStatus `json:"status"`
+	// ResolvedRoot, ResolvedSlot and Canonical describe the block that was found on the beacon node.
+	ResolvedRoot *common.Hash `json:"resolved_root,omitempty"`
+	ResolvedSlot *uint64      `json:"resolved_slot,omitempty"`
+	Canonical    *bool        `json:"canonical,omitempty"`
+	// Comparison is one of identical, different or missing, see RearchiveReport.
+	Comparison  string   `json:"comparison,omitempty"`
+	Differences []string `json:"differences,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Finished returns true if the job will not make any more progress.
+func (j RearchiveJob) Finished() bool {
+	switch j.Status {
+	case JobStatusCompleted, JobStatusPartial, JobStatusFailed, JobStatusCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// FinalStatus returns the status of a job that processed all of its slots or items: completed if nothing failed, failed
+// if everything failed, and partial otherwise.
+func (j RearchiveJob) FinalStatus() JobStatus {
+	failed := uint64(len(j.FailedSlots))
+	for _, item := range j.Items {
+		if item.Status == ItemStatusFailed {
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return JobStatusCompleted
+	case failed >= j.Processed:
+		return JobStatusFailed
+	default:
+		return JobStatusPartial
+	}
+}
+
+// OrphanedBlock is an archived block that is no longer part of the canonical chain. Its blobs are kept in a separate
+// namespace of the storage until the orphan retention expires.
+type OrphanedBlock struct {
+	Slot       uint64 `json:"slot"`
+	OrphanedAt int64  `json:"orphaned_at"`
+}
+
+// FinalizedCursor is the latest finalized block archived in finalized-only mode. Every block between the origin block
+// and the cursor is archived.
+type FinalizedCursor struct {
+	Root common.Hash `json:"root"`
+	Slot uint64      `json:"slot"`
+}
+
+// ParkedBlock is a block whose blob sidecars were disputed by the beacon nodes, so they were not stored. Parked blocks are
+// retried until enough beacon nodes agree.
+type ParkedBlock struct {
+	Slot     uint64 `json:"slot"`
+	ParkedAt int64  `json:"parked_at"`
+	Attempts int    `json:"attempts"`
+}
+
+// SegmentInfo describes a sealed segment, see SegmentedStorage.
+type SegmentInfo struct {
+	Number    uint64 `json:"number"`
+	FirstSlot uint64 `json:"first_slot"`
+	LastSlot  uint64 `json:"last_slot"`
+	Blocks    int    `json:"blocks"`
+	Size      int64  `json:"size"`
+	// IndexOffset is the offset of the embedded index in the segment file.
+	IndexOffset int64 `json:"index_offset"`
+	SealedAt    int64 `json:"sealed_at"`
+	// Pruned is set once the per-block objects of the segment's blocks have been deleted.
+	Pruned bool `json:"pruned"`
+}
+
+// SegmentCatalog lists the sealed segments, in slot order.
+type SegmentCatalog struct {
+	SegmentSlots uint64        `json:"segment_slots"`
+	Segments     []SegmentInfo `json:"segments"`
+}
+
+// ChainState tracks whether archived blocks are canonical. The blocks that are not finalized yet are stored per epoch,
+// see UnfinalizedEpoch.
+type ChainState struct {
+	// FinalizedSlot is the slot of the latest finalized checkpoint seen by the archiver.
+	FinalizedSlot uint64 `json:"finalized_slot"`
+	// Orphans maps the roots of orphaned blocks --> OrphanedBlock.
+	Orphans map[common.Hash]OrphanedBlock `json:"orphans"`
+	// Parked maps the roots of blocks that are waiting for the beacon nodes to agree --> ParkedBlock.
+	Parked map[common.Hash]ParkedBlock `json:"parked,omitempty"`
+	// FinalizedCursor is only set in finalized-only mode.
+	FinalizedCursor *FinalizedCursor `json:"finalized_cursor,omitempty"`
+}
+
+// UnfinalizedEpoch maps the roots of the blocks of an epoch that were archived by live tracking after the finalized slot
+// --> slot. Once they are finalized, they are either confirmed as canonical or orphaned.
+type UnfinalizedEpoch map[common.Hash]uint64
+
+// RearchiveJobs maps job id --> RearchiveJob.
+type RearchiveJobs map[string]RearchiveJob
+
+// IngestBlock is a block that the API served from a peer blob archiver because it was missing from storage. It is
+// queued for the archiver to fetch it from the same peer and store it.
+type IngestBlock struct {
+	Peer     string `json:"peer"`
+	QueuedAt int64  `json:"queued_at"`
+	Attempts int    `json:"attempts"`
+}
+
+// IngestQueue maps block root --> IngestBlock.
+type IngestQueue map[common.Hash]IngestBlock
+
+// AuditRecord is an admin action taken through the archiver API.
+type AuditRecord struct {
+	Time int64 `json:"time"`
+	// Actor is the name of the bearer token or the common name of the client certificate that authenticated the request,
+	// and Auth is how it was authenticated: token, client_cert or none.
+	Actor  string `json:"actor"`
+	Auth   string `json:"auth"`
+	Action string `json:"action"`
+	// Params holds the URL and query parameters of the request, and Body its JSON body, if any.
+	Params map[string]string `json:"params,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+	// Status is the HTTP status of the response, and Response its JSON body, if any.
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// AuditLog holds the audit records of a single day (UTC), in the order they were recorded.
+type AuditLog []AuditRecord
+
+// auditRecordName returns a new name for an audit record of the given day, relative to the storage directory or path.
+// Names sort in the order they were created in, and a random suffix keeps the records of archivers that share the
+// storage from overwriting each other.
+func auditRecordName(day string) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return path.Join(auditDirectory, day, fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(suffix)))
+}
+
+// BeaconSnapshot is the chain configuration of the beacon node, captured by the archiver so the API can serve it without
+// a beacon node.
+type BeaconSnapshot struct {
+	Genesis *v1.Genesis `json:"genesis"`
+	// Spec holds the values of /eth/v1/config/spec, formatted as returned by the beacon API.
+	Spec       map[string]string `json:"spec"`
+	CapturedAt int64             `json:"captured_at"`
 }
 
 // BackfillProcesses maps backfill start block hash --> BackfillProcess. This allows us to track
@@ -95,8 +356,23 @@ type DataStoreReader interface {
 	// - ErrStorage: there was an error accessing the data store.
 	// - ErrMarshaling: there was an error decoding the blob data.
 	ReadBlob(ctx context.Context, hash common.Hash) (BlobData, error)
+	// ReadOrphanedBlob reads the blob data of an orphaned block, see OrphanBlob. It returns the same errors as ReadBlob.
+	ReadOrphanedBlob(ctx context.Context, hash common.Hash) (BlobData, error)
 	ReadBackfillProcesses(ctx context.Context) (BackfillProcesses, error)
 	ReadLockfile(ctx context.Context) (Lockfile, error)
+	ReadControlState(ctx context.Context) (ControlState, error)
+	ReadRearchiveJobs(ctx context.Context) (RearchiveJobs, error)
+	ReadChainState(ctx context.Context) (ChainState, error)
+	// ReadUnfinalizedEpochs reads the unfinalized blocks of every epoch, keyed by epoch.
+	ReadUnfinalizedEpochs(ctx context.Context) (map[uint64]UnfinalizedEpoch, error)
+	// ReadIngestQueue reads every queued block.
+	ReadIngestQueue(ctx context.Context) (IngestQueue, error)
+	// ReadAuditLog reads the audit log of the given day, formatted as YYYY-MM-DD. It returns ErrNotFound if nothing was
+	// recorded on that day.
+	ReadAuditLog(ctx context.Context, day string) (AuditLog, error)
+	// ReadBeaconSnapshot reads the chain configuration captured by the archiver. It returns ErrNotFound if none was
+	// captured yet.
+	ReadBeaconSnapshot(ctx context.Context) (BeaconSnapshot, error)
 }
 
 // DataStoreWriter is the interface for writing to a data store.
@@ -108,6 +384,26 @@ type DataStoreWriter interface {
 	WriteBlob(ctx context.Context, data BlobData) error
 	WriteBackfillProcesses(ctx context.Context, data BackfillProcesses) error
 	WriteLockfile(ctx context.Context, data Lockfile) error
+	WriteControlState(ctx context.Context, data ControlState) error
+	WriteRearchiveJobs(ctx context.Context, data RearchiveJobs) error
+	WriteChainState(ctx context.Context, data ChainState) error
+	// WriteUnfinalizedEpoch writes the unfinalized blocks of an epoch. Writing an empty epoch deletes it.
+	WriteUnfinalizedEpoch(ctx context.Context, epoch uint64, data UnfinalizedEpoch) error
+	// WriteIngestBlock adds a block to the ingest queue, or updates it if it is queued already.
+	WriteIngestBlock(ctx context.Context, root common.Hash, block IngestBlock) error
+	// DeleteIngestBlock removes a block from the ingest queue. Deleting a block that is not queued succeeds.
+	DeleteIngestBlock(ctx context.Context, root common.Hash) error
+	// WriteAuditRecord adds the record to the audit log of the given day, formatted as YYYY-MM-DD.
+	WriteAuditRecord(ctx context.Context, day string, record AuditRecord) error
+	WriteBeaconSnapshot(ctx context.Context, data BeaconSnapshot) error
+	// OrphanBlob moves the blob data for the given beacon block hash to the orphan namespace, so that it is no longer
+	// returned by ReadBlob. It should return one of the following errors:
+	// - nil: the blob data was moved.
+	// - ErrNotFound: the blob data was not found in the data store.
+	// - ErrStorage: there was an error accessing the data store.
+	OrphanBlob(ctx context.Context, hash common.Hash) error
+	// DeleteOrphanedBlob deletes the blob data of an orphaned block. Deleting blob data that does not exist succeeds.
+	DeleteOrphanedBlob(ctx context.Context, hash common.Hash) error
 }
 
 // DataStore is the interface for a data store that can be both written to and read from.
@@ -117,9 +413,19 @@ type DataStore interface {
 }
 
 func NewStorage(cfg flags.StorageConfig, l log.Logger) (DataStore, error) {
+	var backend SegmentBackend
 	if cfg.DataStorageType == flags.DataStorageS3 {
-		return NewS3Storage(cfg.S3Config, l)
+		s3, err := NewS3Storage(cfg.S3Config, l)
+		if err != nil {
+			return nil, err
+		}
+		backend = s3
 	} else {
-		return NewFileStorage(cfg.FileStorageDirectory, l), nil
+		backend = NewFileStorage(cfg.FileStorageDirectory, l)
+	}
+
+	if cfg.SegmentSlots > 0 {
+		return NewSegmentedStorage(context.Background(), backend, cfg.SegmentSlots, l)
 	}
+	return backend, nil
 }
@@ -0,0 +1,161 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/base-org/blob-archiver/common/flags"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// reloadInterval limits how often the certificate files are checked for changes.
+const reloadInterval = 10 * time.Second
+
+// reloader holds the server certificate and the client CAs loaded from files. The files are checked for changes at most
+// once per reloadInterval when a client connects, and reloaded when any of them changed. If the files cannot be loaded,
+// the previous certificates are kept.
+type reloader struct {
+	cfg flags.TLSConfig
+	log log.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  []time.Time
+	checkedAt time.Time
+}
+
+// NewServerConfig returns the TLS config of an HTTP server, or nil if TLS is disabled. The certificate and client CAs
+// are reloaded when their files change, so they can be rotated without a restart.
+func NewServerConfig(cfg flags.TLSConfig, l log.Logger) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	r, err := newReloader(cfg, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetCertificate:     r.getCertificate,
+		GetConfigForClient: r.getConfigForClient,
+	}, nil
+}
+
+func newReloader(cfg flags.TLSConfig, l log.Logger) (*reloader, error) {
+	r := &reloader{cfg: cfg, log: l, checkedAt: time.Now()}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// refresh reloads the files if they may have changed.
+func (r *reloader) refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) < reloadInterval {
+		return
+	}
+	r.checkedAt = time.Now()
+	if err := r.reload(); err != nil {
+		r.log.Error("unable to reload TLS certificates, keeping the previous certificates", "err", err)
+	}
+}
+
+// reload loads the files if any of them changed since they were last loaded. The caller must hold the lock, if any.
+func (r *reloader) reload() error {
+	files := r.files()
+	modTimes := make([]time.Time, len(files))
+	changed := r.cert == nil
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+		changed = changed || !modTimes[i].Equal(r.modTimes[i])
+	}
+	if !changed {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load tls certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("unable to read tls client ca file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in tls client ca file")
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.log.Info("loaded TLS certificates", "cert", r.cfg.CertFile, "clientCA", r.cfg.ClientCAFile)
+	return nil
+}
+
+func (r *reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.refresh()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// getConfigForClient returns the config for a new connection, with the current certificate and client CAs.
+func (r *reloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.refresh()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.clientCAs != nil {
+		config.ClientCAs = r.clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.cfg.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return config, nil
+}
+
+// ClientName returns the common name of the verified client certificate of a request, and false if the client did not
+// present a certificate that was verified against the configured client CAs.
+func ClientName(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName, true
+}
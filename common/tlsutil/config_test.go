@@ -0,0 +1,205 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/base-org/blob-archiver/common/flags"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate with the given common name, signed by the parent, or self-signed if it is nil.
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM(), c.keyPEM(t))
+	require.NoError(t, err)
+	return cert
+}
+
+// writeFiles writes the certificate and key to the files of the config with the given modification time, so that a
+// rewrite is detected regardless of the timestamp resolution of the file system.
+func (c *testCert) writeFiles(t *testing.T, cfg flags.TLSConfig, modTime time.Time) {
+	require.NoError(t, os.WriteFile(cfg.CertFile, c.certPEM(), 0600))
+	require.NoError(t, os.WriteFile(cfg.KeyFile, c.keyPEM(t), 0600))
+	require.NoError(t, os.Chtimes(cfg.CertFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(cfg.KeyFile, modTime, modTime))
+}
+
+func setupTLS(t *testing.T, requireClientCert bool) (flags.TLSConfig, *testCert) {
+	dir := t.TempDir()
+	cfg := flags.TLSConfig{
+		CertFile:          path.Join(dir, "tls.crt"),
+		KeyFile:           path.Join(dir, "tls.key"),
+		ClientCAFile:      path.Join(dir, "ca.crt"),
+		RequireClientCert: requireClientCert,
+	}
+
+	ca := newTestCert(t, "ca", nil)
+	require.NoError(t, os.WriteFile(cfg.ClientCAFile, ca.certPEM(), 0600))
+	newTestCert(t, "server", ca).writeFiles(t, cfg, time.Now())
+	return cfg, ca
+}
+
+func startTestServer(t *testing.T, cfg flags.TLSConfig) string {
+	tlsConfig, err := NewServerConfig(cfg, testlog.Logger(t, log.LvlInfo))
+	require.NoError(t, err)
+
+	srv, err := StartHTTPServer("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := ClientName(r)
+		_, _ = fmt.Fprintf(w, "%s %t", name, ok)
+	}), tlsConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+	})
+	return fmt.Sprintf("https://localhost:%d", srv.Addr().(*net.TCPAddr).Port)
+}
+
+func get(url string, ca *testCert, clientCert *tls.Certificate) (string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: roots}
+	if clientCert != nil {
+		config.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestServerConfig(t *testing.T) {
+	cfg, ca := setupTLS(t, false)
+	url := startTestServer(t, cfg)
+
+	// Client certificates are verified if they are given
+	body, err := get(url, ca, nil)
+	require.NoError(t, err)
+	require.Equal(t, " false", body)
+
+	clientCert := newTestCert(t, "operator", ca).tlsCertificate(t)
+	body, err = get(url, ca, &clientCert)
+	require.NoError(t, err)
+	require.Equal(t, "operator true", body)
+
+	// Certificates of other CAs are not accepted
+	otherCert := newTestCert(t, "operator", newTestCert(t, "other", nil)).tlsCertificate(t)
+	body, err = get(url, ca, &otherCert)
+	require.NoError(t, err)
+	require.Equal(t, " false", body)
+}
+
+func TestServerConfig_RequireClientCert(t *testing.T) {
+	cfg, ca := setupTLS(t, true)
+	url := startTestServer(t, cfg)
+
+	_, err := get(url, ca, nil)
+	require.Error(t, err)
+
+	clientCert := newTestCert(t, "operator", ca).tlsCertificate(t)
+	body, err := get(url, ca, &clientCert)
+	require.NoError(t, err)
+	require.Equal(t, "operator true", body)
+}
+
+func TestServerConfig_Invalid(t *testing.T) {
+	cfg, _ := setupTLS(t, false)
+	require.NoError(t, os.WriteFile(cfg.ClientCAFile, []byte("invalid"), 0600))
+
+	_, err := NewServerConfig(cfg, testlog.Logger(t, log.LvlInfo))
+	require.ErrorContains(t, err, "no certificates found in tls client ca file")
+
+	_, err = NewServerConfig(flags.TLSConfig{}, testlog.Logger(t, log.LvlInfo))
+	require.NoError(t, err)
+}
+
+func TestReloader(t *testing.T) {
+	cfg, ca := setupTLS(t, false)
+	r, err := newReloader(cfg, testlog.Logger(t, log.LvlInfo))
+	require.NoError(t, err)
+
+	serverCert := func() string {
+		config, err := r.getConfigForClient(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal(t, "server", serverCert())
+
+	// Changes are only picked up once the reload interval passed
+	newTestCert(t, "rotated", ca).writeFiles(t, cfg, time.Now().Add(time.Minute))
+	require.Equal(t, "server", serverCert())
+
+	r.checkedAt = time.Time{}
+	require.Equal(t, "rotated", serverCert())
+
+	// Invalid files are ignored
+	require.NoError(t, os.WriteFile(cfg.KeyFile, []byte("invalid"), 0600))
+	r.checkedAt = time.Time{}
+	require.Equal(t, "rotated", serverCert())
+}
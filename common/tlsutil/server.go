@@ -0,0 +1,56 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ethereum-optimism/optimism/op-service/httputil"
+)
+
+// HTTPServer is an HTTP server that serves HTTPS if it is given a TLS config, see httputil.HTTPServer.
+type HTTPServer struct {
+	listener net.Listener
+	srv      *http.Server
+}
+
+// StartHTTPServer starts serving the handler on the given address, with TLS if tlsConfig is not nil.
+func StartHTTPServer(addr string, handler http.Handler, tlsConfig *tls.Config) (*HTTPServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind to address %q: %w", addr, err)
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       httputil.DefaultTimeouts.ReadTimeout,
+		ReadHeaderTimeout: httputil.DefaultTimeouts.ReadHeaderTimeout,
+		WriteTimeout:      httputil.DefaultTimeouts.WriteTimeout,
+		IdleTimeout:       httputil.DefaultTimeouts.IdleTimeout,
+	}
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = srv.ServeTLS(listener, "", "")
+		} else {
+			err = srv.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			panic(fmt.Errorf("unexpected serve error: %w", err))
+		}
+	}()
+	return &HTTPServer{listener: listener, srv: srv}, nil
+}
+
+func (s *HTTPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Shutdown stops the server, allowing active connections to close gracefully until the context is done.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,45 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BlockCommitments returns the blob KZG commitments in the body of the block with the given root, as returned by the
+// beacon node. Blocks before Deneb have no commitments.
+func BlockCommitments(ctx context.Context, c client.SignedBeaconBlockProvider, root common.Hash) ([]deneb.KZGCommitment, error) {
+	res, err := c.SignedBeaconBlock(ctx, &api.SignedBeaconBlockOpts{Block: root.String()})
+	if err != nil {
+		return nil, err
+	}
+	if res.Data == nil {
+		return nil, fmt.Errorf("no block returned for %s", root)
+	}
+
+	switch res.Data.Version {
+	case spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
+		return nil, nil
+	}
+	return res.Data.BlobKZGCommitments()
+}
+
+// CompleteBlobSidecars checks that the given sidecars are the complete set of blobs of the block with the given root and
+// commitments, in addition to the checks of BlobSidecars. A truncated set, e.g. from a peer that only returns some of
+// the blobs of a block, is rejected.
+func CompleteBlobSidecars(root common.Hash, commitments []deneb.KZGCommitment, sidecars []*deneb.BlobSidecar) error {
+	if len(sidecars) != len(commitments) {
+		return fmt.Errorf("expected %d sidecars, got %d", len(commitments), len(sidecars))
+	}
+	for i, sidecar := range sidecars {
+		if sidecar.KZGCommitment != commitments[i] {
+			return fmt.Errorf("sidecar %d: kzg commitment does not match the block", i)
+		}
+	}
+	return BlobSidecars(root, sidecars)
+}
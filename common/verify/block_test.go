@@ -0,0 +1,34 @@
+package verify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/base-org/blob-archiver/common/beacon/beacontest"
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteBlobSidecars(t *testing.T) {
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 3)
+	beacon := beacontest.NewEmptyStubBeaconClient()
+	beacon.Commitments[root.String()] = blobtest.Commitments(sidecars)
+
+	commitments, err := BlockCommitments(context.Background(), beacon, root)
+	require.NoError(t, err)
+	require.Len(t, commitments, 3)
+	require.NoError(t, CompleteBlobSidecars(root, commitments, sidecars))
+
+	// A truncated set passes BlobSidecars, but is not complete
+	require.NoError(t, BlobSidecars(root, sidecars[:2]))
+	require.ErrorContains(t, CompleteBlobSidecars(root, commitments, sidecars[:2]), "expected 3 sidecars, got 2")
+	require.ErrorContains(t, CompleteBlobSidecars(root, commitments, nil), "expected 3 sidecars, got 0")
+
+	// Commitments of another block
+	_, other := blobtest.NewVerifiableBlobSidecars(t, 3)
+	require.ErrorContains(t, CompleteBlobSidecars(root, blobtest.Commitments(other), sidecars), "sidecar 0: kzg commitment does not match")
+
+	// Unknown blocks cannot be checked
+	_, err = BlockCommitments(context.Background(), beacon, blobtest.One)
+	require.Error(t, err)
+}
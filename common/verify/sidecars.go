@@ -0,0 +1,75 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+const (
+	// blobKzgCommitmentsIndex is the index of the blob_kzg_commitments field in the Deneb BeaconBlockBody.
+	blobKzgCommitmentsIndex = 11
+	// CommitmentListDepth is the depth of the blob_kzg_commitments list, MAX_BLOB_COMMITMENTS_PER_BLOCK being 4096.
+	CommitmentListDepth = 12
+)
+
+// BlobSidecars checks that the given sidecars belong to the block with the given root, that their commitments are
+// included in its body, and that every blob matches its commitment. This allows serving sidecars from an untrusted
+// source, e.g. another blob archiver.
+func BlobSidecars(root common.Hash, sidecars []*deneb.BlobSidecar) error {
+	for i, sidecar := range sidecars {
+		if sidecar.Index != deneb.BlobIndex(i) {
+			return fmt.Errorf("sidecar %d: unexpected index %d", i, sidecar.Index)
+		}
+		if sidecar.SignedBlockHeader == nil || sidecar.SignedBlockHeader.Message == nil {
+			return fmt.Errorf("sidecar %d: missing block header", i)
+		}
+
+		headerRoot, err := sidecar.SignedBlockHeader.Message.HashTreeRoot()
+		if err != nil {
+			return fmt.Errorf("sidecar %d: failed to hash block header: %w", i, err)
+		}
+		if common.Hash(headerRoot) != root {
+			return fmt.Errorf("sidecar %d: block header root %s does not match", i, common.Hash(headerRoot))
+		}
+
+		if !verifyCommitmentInclusion(sidecar) {
+			return fmt.Errorf("sidecar %d: invalid kzg commitment inclusion proof", i)
+		}
+
+		if err := kzg4844.VerifyBlobProof(kzg4844.Blob(sidecar.Blob), kzg4844.Commitment(sidecar.KZGCommitment), kzg4844.Proof(sidecar.KZGProof)); err != nil {
+			return fmt.Errorf("sidecar %d: invalid kzg proof: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// verifyCommitmentInclusion checks the merkle proof of the sidecar's commitment against the body root of its block, as
+// in verify_blob_sidecar_inclusion_proof of the Deneb p2p specification.
+func verifyCommitmentInclusion(sidecar *deneb.BlobSidecar) bool {
+	root := CommitmentInclusionRoot(sidecar)
+	return bytes.Equal(root[:], sidecar.SignedBlockHeader.Message.BodyRoot[:])
+}
+
+// CommitmentInclusionRoot returns the body root that the merkle proof of the sidecar's commitment leads to.
+func CommitmentInclusionRoot(sidecar *deneb.BlobSidecar) [32]byte {
+	var chunks [64]byte
+	copy(chunks[:], sidecar.KZGCommitment[:])
+	value := sha256.Sum256(chunks[:])
+
+	// The commitment is at its index in the list, the list is the left child of its length mix-in, and the list is at
+	// its field index in the body
+	index := uint64(sidecar.Index) | uint64(blobKzgCommitmentsIndex)<<(CommitmentListDepth+1)
+	for i, sibling := range sidecar.KZGCommitmentInclusionProof {
+		if index>>i&1 == 1 {
+			value = sha256.Sum256(append(sibling[:], value[:]...))
+		} else {
+			value = sha256.Sum256(append(value[:], sibling[:]...))
+		}
+	}
+	return value
+}
@@ -0,0 +1,32 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/base-org/blob-archiver/common/blobtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobSidecars(t *testing.T) {
+	root, sidecars := blobtest.NewVerifiableBlobSidecars(t, 3)
+	require.NoError(t, BlobSidecars(root, sidecars))
+	for _, sidecar := range sidecars {
+		require.Equal(t, [32]byte(sidecar.SignedBlockHeader.Message.BodyRoot), CommitmentInclusionRoot(sidecar))
+	}
+
+	// Sidecars of another block
+	require.ErrorContains(t, BlobSidecars(blobtest.One, sidecars), "does not match")
+
+	// A blob that does not match its commitment
+	blob := sidecars[1].Blob
+	sidecars[1].Blob[1] ^= 1
+	require.ErrorContains(t, BlobSidecars(root, sidecars), "sidecar 1: invalid kzg proof")
+	sidecars[1].Blob = blob
+
+	// A commitment that is not part of the block
+	sidecars[2].KZGCommitment = sidecars[0].KZGCommitment
+	require.ErrorContains(t, BlobSidecars(root, sidecars), "sidecar 2: invalid kzg commitment inclusion proof")
+
+	// Missing or reordered sidecars
+	require.ErrorContains(t, BlobSidecars(root, sidecars[1:]), "unexpected index")
+}
@@ -51,7 +51,7 @@ func Main() cliapp.LifecycleAction {
 		oplog.SetGlobalLogHandler(l.Handler())
 		opservice.ValidateEnvVars(flags.EnvVarPrefix, flags.Flags, l)
 
-		headerClient, err := beacon.NewBeaconClient(cliCtx.Context, cfg.BeaconConfig)
+		headerClient, err := beacon.NewBeaconClient(cliCtx.Context, cfg.BeaconConfig, l, beacon.NoopMetrics)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create beacon client: %w", err)
 		}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/attestantio/go-eth2-client/api"
 	"github.com/base-org/blob-archiver/common/storage"
@@ -17,6 +19,10 @@ const (
 	FormatJson Format = "application/json"
 	// FormatSSZ instructs the client to request the response in SSZ format
 	FormatSSZ Format = "application/octet-stream"
+
+	// clientTimeout bounds a request to the blob sidecar API, including reading the response, so that a hung server
+	// cannot block the caller.
+	clientTimeout = 30 * time.Second
 )
 
 // BlobSidecarClient is a minimal client for fetching sidecars from the blob service. This client is used instead of an
@@ -25,8 +31,8 @@ const (
 // 2) Exposes implementation details, e.g. status code, as well as allowing us to specify the format
 type BlobSidecarClient interface {
 	// FetchSidecars fetches the sidecars for a given slot from the blob sidecar API. It returns the HTTP status code and
-	// the sidecars.
-	FetchSidecars(id string, format Format) (int, storage.BlobSidecars, error)
+	// the sidecars. The request is aborted once the context is done.
+	FetchSidecars(ctx context.Context, id string, format Format) (int, storage.BlobSidecars, error)
 }
 
 type httpBlobSidecarClient struct {
@@ -38,13 +44,13 @@ type httpBlobSidecarClient struct {
 func NewBlobSidecarClient(url string) BlobSidecarClient {
 	return &httpBlobSidecarClient{
 		url:    url,
-		client: &http.Client{},
+		client: &http.Client{Timeout: clientTimeout},
 	}
 }
 
-func (c *httpBlobSidecarClient) FetchSidecars(id string, format Format) (int, storage.BlobSidecars, error) {
+func (c *httpBlobSidecarClient) FetchSidecars(ctx context.Context, id string, format Format) (int, storage.BlobSidecars, error) {
 	url := fmt.Sprintf("%s/eth/v1/beacon/blob_sidecars/%s", c.url, id)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return http.StatusInternalServerError, storage.BlobSidecars{}, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -55,13 +61,14 @@ func (c *httpBlobSidecarClient) FetchSidecars(id string, format Format) (int, st
 	if err != nil {
 		return http.StatusInternalServerError, storage.BlobSidecars{}, fmt.Errorf("failed to fetch sidecars: %w", err)
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
+		// Drain the body so that the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
 		return response.StatusCode, storage.BlobSidecars{}, nil
 	}
 
-	defer response.Body.Close()
-
 	var sidecars storage.BlobSidecars
 	if format == FormatJson {
 		if err := json.NewDecoder(response.Body).Decode(&sidecars); err != nil {
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSidecars(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/eth/v1/beacon/blob_sidecars/hung" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":404,"message":"Block not found"}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := NewBlobSidecarClient(server.URL)
+
+	status, sidecars, err := c.FetchSidecars(context.Background(), "0x01", FormatSSZ)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, status)
+	require.Empty(t, sidecars.Data)
+
+	// A hung server does not outlive the request context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err = c.FetchSidecars(ctx, "hung", FormatSSZ)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -118,7 +118,7 @@ func shouldRetry(status int) bool {
 // fetchWithRetries fetches the sidecar and handles retryable error cases (5xx status codes + 429 + connection errors)
 func fetchWithRetries(ctx context.Context, endpoint BlobSidecarClient, id string, format Format) (int, storage.BlobSidecars, error) {
 	return retry.Do2(ctx, retryAttempts, retry.Exponential(), func() (int, storage.BlobSidecars, error) {
-		status, resp, err := endpoint.FetchSidecars(id, format)
+		status, resp, err := endpoint.FetchSidecars(ctx, id, format)
 
 		if err == nil && status != http.StatusOK && shouldRetry(status) {
 			err = fmt.Errorf("retryable status code: %d", status)
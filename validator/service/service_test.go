@@ -52,7 +52,7 @@ func (s *stubBlobSidecarClient) setResponse(id string, statusCode int, data stor
 	}
 }
 
-func (s *stubBlobSidecarClient) FetchSidecars(id string, format Format) (int, storage.BlobSidecars, error) {
+func (s *stubBlobSidecarClient) FetchSidecars(_ context.Context, id string, format Format) (int, storage.BlobSidecars, error) {
 	response, ok := s.data[id]
 	if !ok {
 		return 0, storage.BlobSidecars{}, fmt.Errorf("not found")